@@ -3,7 +3,18 @@
 // Usage:
 //
 //	medialab play <url> [--screen N]
-//	medialab search <query> [--play] [--screen N]
+//	medialab play <url> --screen speaker
+//	medialab play --pick <N> | --id <video-id> [--screen N]
+//	medialab play --station <name> [--screen N]
+//	medialab stations
+//	medialab play <playlist-url> [--start N] [--limit N|--all] [--shuffle] [--screen N]
+//	medialab playlist show|play <url> [--start N] [--limit N|--all] [--shuffle]
+//	medialab play <url|query> --quality 1080p|720p:vp9|audio|<ytdl-format>
+//	medialab formats <url>
+//	medialab download <url>... [--quality Q]
+//	medialab download list|rm <url>
+//	medialab search <query> [--provider NAME] [--max-duration D] [--min-duration D]
+//	                [--no-live] [--sort ORDER] [--full] [--play] [--screen N]
 //	medialab pause [--screen N]
 //	medialab play [--screen N]
 //	medialab toggle [--screen N]
@@ -14,15 +25,41 @@
 //	medialab seek <seconds> [--relative] [--screen N]
 //	medialab info [--screen N]
 //	medialab list
+//	medialab speed <x> [--no-pitch] [--screen N]
+//	medialab step|backstep [--screen N]
+//	medialab loop file|playlist [N|inf|off] [--screen N]
+//	medialab loop ab <a> <b> [--screen N]
+//	medialab loop off [--screen N]
+//	medialab library scan|watch|stats
+//	medialab library search <query>
+//	medialab clip <start> <end> [url] [--out FILE] [--screen N]
+//	medialab clip --last <seconds> [--out FILE] [--screen N]
+//	medialab cache [stats|clear]
+//	medialab macro list
+//	medialab macro run <name> [--parallel] [--stop-on-error]
+//	medialab scene save|load|show|delete <name>
+//	medialab scene list
+//	medialab schedule add "<cron>"|--at TIME play <url|query>|stop|scene <name>|macro <name>
+//	                [--screen N|all] [--until HH:MM] [--name NAME] [--allow-overlap]
+//	medialab schedule list|conflicts|run
+//	medialab schedule rm|enable|disable <id>
+//	medialab timer <duration>|end [stop|pause] [--fade D] [--screen N]
+//	medialab doctor [--json]
+//	medialab audit tail [-n N] [--follow] [--actor A] [--source S] [--screen N] [--action X]
 //	medialab setup  # Generate mpv config and shell scripts
 package main
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
+	"os/signal"
+	"os/user"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -33,16 +70,32 @@ import (
 func main() {
 	if len(os.Args) < 2 {
 		printUsage()
-		os.Exit(1)
+		exit(1)
 	}
 
-	lab := medialab.New(nil)
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
 	cmd := os.Args[1]
 	args := os.Args[2:]
 
+	config := medialab.DefaultConfig()
+	if hasFlag(args, "--no-cache") {
+		config.CacheTTL = 0 // neither read nor write cached results
+		args = removeFlag(args, "--no-cache")
+	}
+
+	if level := os.Getenv("MEDIALAB_LOG"); level != "" {
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid MEDIALAB_LOG: %s (use debug, info, warn or error)\n", level)
+			exit(1)
+		}
+		config.Logger = slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: l}))
+	}
+
+	lab := medialab.New(config)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	startAudit(lab, cmd, args)
+
 	switch cmd {
 	case "play":
 		cmdPlay(ctx, lab, args)
@@ -70,6 +123,40 @@ func main() {
 		cmdInfo(lab, args)
 	case "list", "ls":
 		cmdList(lab)
+	case "speed":
+		cmdSpeed(lab, args)
+	case "step", "frame-step":
+		cmdControl(lab, "frame-step", args)
+	case "backstep", "frame-back-step":
+		cmdControl(lab, "frame-back-step", args)
+	case "loop":
+		cmdLoop(lab, args)
+	case "clip":
+		cmdClip(lab, args)
+	case "playlist", "pl":
+		cmdPlaylist(ctx, lab, args)
+	case "formats":
+		cmdFormats(ctx, lab, args)
+	case "stations", "radio":
+		cmdStations(lab)
+	case "download", "dl":
+		cmdDownload(lab, args)
+	case "library", "lib":
+		cmdLibrary(lab, args)
+	case "cache":
+		cmdCache(lab, args)
+	case "macro":
+		cmdMacro(lab, args)
+	case "scene":
+		cmdScene(lab, args)
+	case "schedule":
+		cmdSchedule(lab, args)
+	case "timer", "sleep":
+		cmdTimer(lab, args)
+	case "doctor":
+		cmdDoctor(ctx, lab, args)
+	case "audit":
+		cmdAudit(lab, args)
 	case "setup":
 		cmdSetup()
 	case "help", "--help", "-h":
@@ -77,8 +164,125 @@ func main() {
 	default:
 		fmt.Fprintf(os.Stderr, "unknown command: %s\n", cmd)
 		printUsage()
-		os.Exit(1)
+		exit(1)
+	}
+	finishAudit(0)
+}
+
+// commandAliases maps command aliases to the names the audit log uses
+var commandAliases = map[string]string{
+	"resume": "play", "playpause": "toggle", "quit": "stop", "previous": "prev",
+	"fs": "fullscreen", "vol": "volume", "status": "info", "ls": "list",
+	"frame-step": "step", "frame-back-step": "backstep", "pl": "playlist",
+	"radio": "stations", "dl": "download", "lib": "library", "sleep": "timer",
+}
+
+// readOnlyCommands only show or check things, so they are not audited.
+// Scheduler runs and fired timers are audited as they act.
+var readOnlyCommands = map[string]bool{
+	"search": true, "info": true, "list": true, "formats": true, "stations": true,
+	"playlist show": true, "download list": true, "download ls": true,
+	"library search": true, "library stats": true, "library watch": true,
+	"cache": true, "cache stats": true, "macro list": true, "scene list": true, "scene show": true,
+	"schedule list": true, "schedule conflicts": true, "schedule run": true,
+	"doctor": true, "audit": true, "audit tail": true, "setup": true,
+	"help": true, "--help": true, "-h": true,
+}
+
+// subcommandCommands take a subcommand, which the audit action includes
+var subcommandCommands = map[string]bool{
+	"playlist": true, "download": true, "library": true, "cache": true,
+	"macro": true, "scene": true, "schedule": true, "audit": true,
+}
+
+// screenCommands act on one screen, screen 1 without --screen
+var screenCommands = map[string]bool{
+	"play": true, "pause": true, "toggle": true, "stop": true, "next": true, "prev": true,
+	"fullscreen": true, "volume": true, "seek": true, "speed": true, "step": true,
+	"backstep": true, "loop": true, "clip": true, "timer": true, "search": true, "playlist play": true,
+}
+
+// screenLabel is a screen as the audit log names it: 1-4 or speaker
+func screenLabel(screen medialab.Screen) string {
+	if screen == medialab.ScreenSpeaker {
+		return "speaker"
+	}
+	return strconv.Itoa(int(screen) + 1)
+}
+
+// pending is the audit entry of the running command, recorded by
+// finishAudit or exit
+var pending struct {
+	lab   *medialab.MediaLab
+	entry *medialab.AuditEntry
+	start time.Time
+}
+
+// startAudit prepares the audit entry of a command that acts
+func startAudit(lab *medialab.MediaLab, cmd string, args []string) {
+	action := cmd
+	if canonical, ok := commandAliases[cmd]; ok {
+		action = canonical
 	}
+	if subcommandCommands[action] && len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		if action != "download" || args[0] == "list" || args[0] == "ls" || args[0] == "rm" {
+			action += " " + args[0]
+		}
+	}
+	if readOnlyCommands[action] && !(action == "search" && hasFlag(args, "--play", "-p")) {
+		return
+	}
+
+	entry := &medialab.AuditEntry{Actor: auditActor(), Source: "cli", Action: action}
+	if data, err := json.Marshal(args); err == nil && len(args) > 0 {
+		entry.Args = data
+	}
+	if value, _ := flagValue(args, "--screen", "-s"); value != "" {
+		if screen, err := medialab.ParseScreen(value); err == nil {
+			entry.Screen = screenLabel(screen)
+		}
+	} else if screenCommands[action] {
+		entry.Screen = screenLabel(medialab.Screen1) // parseScreen's default
+	}
+	pending.lab, pending.entry, pending.start = lab, entry, time.Now()
+}
+
+// startPickAudit audits a search result picked at the prompt as the
+// play it starts, since search alone is not audited
+func startPickAudit(lab *medialab.MediaLab, url string, screen medialab.Screen) {
+	entry := &medialab.AuditEntry{Actor: auditActor(), Source: "cli", Action: "play", Screen: screenLabel(screen)}
+	if data, err := json.Marshal([]string{url}); err == nil {
+		entry.Args = data
+	}
+	pending.lab, pending.entry, pending.start = lab, entry, time.Now()
+}
+
+// auditActor is the local user running the command
+func auditActor() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// finishAudit records the running command with its exit code
+func finishAudit(code int) {
+	if pending.entry == nil {
+		return
+	}
+	e := *pending.entry
+	pending.entry = nil
+	e.DurationMS = time.Since(pending.start).Milliseconds()
+	if code != 0 {
+		e.Result = "error"
+	}
+	pending.lab.Audit(e)
+}
+
+// exit records the running command in the audit log and exits
+func exit(code int) {
+	finishAudit(code)
+	os.Exit(code)
 }
 
 func printUsage() {
@@ -89,7 +293,14 @@ USAGE:
 
 COMMANDS:
     play <url>              Play URL/file (YouTube URLs work directly)
-    search <query>          Search YouTube
+    playlist show <url>     List playlist/channel entries without playing
+    playlist play <url>     Expand and play a playlist (any yt-dlp URL)
+    formats <url>           List formats yt-dlp offers (for --quality)
+    stations                List radio stations playable with --station
+    download <url>...       Download for offline playback (play uses it)
+    download list           Show the offline cache
+    download rm <url>       Remove a file from the offline cache
+    search <query>          Search (YouTube by default; prompts to play in a terminal)
     pause                   Pause playback
     resume                  Resume playback
     toggle                  Toggle play/pause
@@ -101,20 +312,106 @@ COMMANDS:
     seek <seconds>          Seek to position
     info                    Show playback info
     list                    List active players
+    speed <x>               Set playback speed (e.g. 0.5, 1.25)
+    step                    Step one frame forward (pauses)
+    backstep                Step one frame back (pauses)
+    loop file [N|inf|off]   Loop current file (default: inf)
+    loop playlist [N|inf]   Loop playlist (default: inf)
+    loop ab <a> <b>         Loop between two timestamps
+    loop off                Disable all looping
+    clip <start> <end>      Export a time range to a file
+    library scan            Index local media (Config.LibraryDirs)
+    library watch           Rescan periodically (--interval, default 5m)
+    library search <query>  Fuzzy search the local library
+    library stats           Show library index stats
+    cache [stats|clear]     Show or clear the search cache
+    macro list              List macros (Config.Macros, macros.json)
+    macro run <name>        Run a macro's steps
+    scene save <name>       Save every screen's media, position and volume
+    scene load <name>       Restore a scene (stops screens not in it)
+    scene list              List saved scenes
+    scene show <name>       Show what a scene restores
+    scene delete <name>     Delete a scene
+    schedule add <cron> ... Schedule play, stop, scene or macro (see examples)
+    schedule list           List entries with their next run
+    schedule rm <id>        Delete an entry (also enable/disable <id>)
+    schedule conflicts      Show entries overlapping on a screen
+    schedule run            Run due entries until interrupted
+    timer <duration> [stop] Stop (or pause) after a delay; waits in the foreground
+    timer end [pause]       Stop (or pause) when the current media ends
+    doctor                  Check binaries, sockets, display, profiles and audio
+    audit tail              Show recent media actions (who did what, where)
     setup                   Generate mpv config and scripts
 
 OPTIONS:
-    --screen N, -s N        Target screen (1-4, default: 1)
+    --screen N, -s N        Target screen (1-4 or speaker, default: 1)
     --play, -p              Play first search result
+    --pick N                Play result N of the last search
+    --id ID                 Play a YouTube video by ID
+    --station NAME          Play a configured radio station
+    --quality Q, -q Q       Format: best, audio, 1080p, vp9, 1080p:av1 or a
+                            ytdl-format (default: Config.ScreenQuality/Quality)
+    --start N               First playlist entry (1-based)
+    --limit N               Maximum playlist entries (default 200)
+    --all                   Every playlist entry, ignoring --limit
+    --shuffle               Shuffle playlist entries
+    --provider NAME, -P     Search provider: youtube, soundcloud, archive,
+                            local or a yt-dlp prefix (e.g. bilisearch)
+    --max-duration D        Skip results longer than D (10m, 4:30, 600)
+    --min-duration D        Skip results shorter than D
+    --no-live               Skip live and upcoming streams
+    --sort ORDER            relevance, date, views or duration
+    --full                  Fetch full metadata (upload date; slower)
     --relative, -r          Seek relative to current position
+    --no-pitch              Disable pitch correction when changing speed
+    --last N                Clip the last N seconds of the current media
+    --out FILE, -o FILE     Clip output file
+    --no-cache              Bypass the search/resolution cache
+    --parallel              Run macro steps at once instead of in order
+    --stop-on-error         Skip remaining macro steps after a failure
+    --at TIME               One-off schedule entry (2006-01-02T15:04)
+    --until HH:MM           Stop the entry's screens at this time
+    --allow-overlap         Accept an entry that overlaps another
+    --fade D                Fade the volume out over D before a timer acts
+    --json                  Print the doctor report as JSON
+    -n N                    Audit entries to show (default: 20)
+    --follow, -f            Keep printing audit entries as they are recorded
+    --actor A               Only audit entries by A (client, agent or user)
 
 EXAMPLES:
     medialab play "https://youtube.com/watch?v=..."
     medialab play "lofi hip hop" --screen 2
+    medialab play "https://youtube.com/@channel" --shuffle --limit 20
+    medialab playlist show "https://youtube.com/playlist?list=..."
+    medialab play "https://youtube.com/watch?v=..." --quality 720p:vp9
+    medialab play "lofi hip hop radio" --quality audio
+    medialab play "lofi hip hop radio" --screen speaker
+    medialab play --station "groove salad" --screen speaker
+    medialab download "https://youtube.com/watch?v=..." --quality 720p
     medialab search "synthwave mix" --play
+    medialab search "synthwave mix" && medialab play --pick 3
+    medialab search "field recordings" --provider archive
+    medialab search "bohemian rhapsody" --max-duration 10m --no-live
+    medialab search "lofi hip hop" --no-cache
     medialab volume 50 --screen 1
     medialab seek -30 --relative
-    medialab toggle --screen 2`)
+    medialab toggle --screen 2
+    medialab speed 0.5 --screen 2
+    medialab loop playlist inf --screen 3
+    medialab loop ab 1:10 1:25
+    medialab clip --last 30 --screen 2
+    medialab library scan && medialab library search "coltrane naima"
+    medialab macro run movie-night --stop-on-error
+    medialab scene save standup && medialab scene load "incident dashboard"
+    medialab schedule add "0 9 * * mon-fri" play "https://..." --until 09:30
+    medialab schedule add "0 22 * * *" stop --screen all
+    medialab schedule add --at 2026-12-31T23:55 scene countdown
+    medialab timer 45m stop --fade 30s --screen 2
+    medialab timer end pause --screen speaker
+    medialab doctor
+    medialab audit tail -n 50 --screen 2
+    MEDIALAB_LOG=debug medialab play "lofi hip hop"
+    medialab clip 1:00 1:45 "https://youtube.com/watch?v=..." -o intro.mkv`)
 }
 
 func parseScreen(args []string) (medialab.Screen, []string) {
@@ -125,9 +422,8 @@ func parseScreen(args []string) (medialab.Screen, []string) {
 		arg := args[i]
 		if arg == "--screen" || arg == "-s" {
 			if i+1 < len(args) {
-				n, err := strconv.Atoi(args[i+1])
-				if err == nil && n >= 1 && n <= 4 {
-					screen = medialab.Screen(n - 1)
+				if s, err := medialab.ParseScreen(args[i+1]); err == nil {
+					screen = s
 				}
 				i++
 			}
@@ -138,6 +434,32 @@ func parseScreen(args []string) (medialab.Screen, []string) {
 	return screen, remaining
 }
 
+// flagValue extracts the value of a "--flag VALUE" option, returning the
+// remaining arguments with the flag and its value removed
+func flagValue(args []string, flags ...string) (string, []string) {
+	value := ""
+	remaining := make([]string, 0, len(args))
+
+	for i := 0; i < len(args); i++ {
+		matched := false
+		for _, flag := range flags {
+			if args[i] == flag {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			if i+1 < len(args) {
+				value = args[i+1]
+				i++
+			}
+		} else {
+			remaining = append(remaining, args[i])
+		}
+	}
+	return value, remaining
+}
+
 func hasFlag(args []string, flags ...string) bool {
 	for _, arg := range args {
 		for _, flag := range flags {
@@ -149,48 +471,832 @@ func hasFlag(args []string, flags ...string) bool {
 	return false
 }
 
+func removeFlag(args []string, flag string) []string {
+	remaining := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg != flag {
+			remaining = append(remaining, arg)
+		}
+	}
+	return remaining
+}
+
 func cmdPlay(ctx context.Context, lab *medialab.MediaLab, args []string) {
 	screen, remaining := parseScreen(args)
+	pick, remaining := flagValue(remaining, "--pick")
+	videoID, remaining := flagValue(remaining, "--id")
+	station, remaining := flagValue(remaining, "--station")
+	quality, remaining := flagValue(remaining, "--quality", "-q")
+	plOpts, remaining := parsePlaylistOptions(remaining)
+	asPlaylist := hasFlag(remaining, "--playlist")
+	remaining = removeFlag(remaining, "--playlist")
 
-	if len(remaining) == 0 {
+	var opts medialab.PlayOptions
+	if quality != "" {
+		q, err := medialab.ParseQuality(quality)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			exit(1)
+		}
+		opts.Quality = &q
+		plOpts.Quality = &q
+	}
+
+	url := strings.Join(remaining, " ")
+	var title string
+	var err error
+
+	switch {
+	case pick != "":
+		var result *medialab.SearchResult
+		if result, err = lab.ResultAt(countFlag("--pick", pick)); err == nil {
+			url, title = result.URL, result.Title
+		}
+	case videoID != "":
+		url, err = medialab.VideoURL(videoID)
+	case station != "":
+		var st medialab.Station
+		if st, err = lab.Station(station); err == nil {
+			url, title = st.URL, st.Name
+		}
+	case url == "":
 		// No URL = resume
 		if err := lab.Resume(screen); err != nil {
 			fmt.Fprintf(os.Stderr, "resume failed: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
-		fmt.Printf("Resumed on screen %d\n", int(screen)+1)
+		fmt.Printf("Resumed on %s\n", screen)
 		return
+	case !strings.Contains(url, "://") && !strings.HasPrefix(url, "/") && !strings.HasPrefix(url, "."):
+		// Not a URL: resolve via the local library or YouTube
+		url, err = lab.ResolveQuery(ctx, url)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "play failed: %v\n", err)
+		exit(1)
 	}
 
-	url := strings.Join(remaining, " ")
-
-	// If it doesn't look like a URL, treat as YouTube search
-	if !strings.Contains(url, "://") && !strings.HasPrefix(url, "/") && !strings.HasPrefix(url, ".") {
-		instance, err := lab.PlayYouTubeSearch(ctx, url, screen)
+	if asPlaylist || medialab.IsPlaylistURL(url) {
+		instance, playlist, err := lab.PlayPlaylist(ctx, url, plOpts, screen)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "play failed: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
-		fmt.Printf("Playing on screen %d (PID %d): %s\n", int(screen)+1, instance.PID, instance.URL)
+		fmt.Printf("Playing %d of %d entries on %s (PID %d): %s\n",
+			len(playlist.Entries), playlist.Count, screen, instance.PID, playlist.Title)
+		printPlaylistEntries(playlist, 10)
 		return
 	}
 
-	instance, err := lab.Play(ctx, url, screen)
+	instance, err := lab.PlayWith(ctx, url, screen, opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "play failed: %v\n", err)
-		os.Exit(1)
+		exit(1)
+	}
+	if title != "" {
+		fmt.Printf("Playing on %s (PID %d): %s\n  %s\n", screen, instance.PID, title, url)
+		return
+	}
+	fmt.Printf("Playing on %s (PID %d): %s\n", screen, instance.PID, url)
+}
+
+// parsePlaylistOptions extracts --start, --limit, --all and --shuffle
+func parsePlaylistOptions(args []string) (medialab.PlaylistOptions, []string) {
+	var opts medialab.PlaylistOptions
+	start, remaining := flagValue(args, "--start")
+	limit, remaining := flagValue(remaining, "--limit")
+	opts.Start = countFlag("--start", start)
+	opts.Limit = countFlag("--limit", limit)
+	opts.All = hasFlag(remaining, "--all")
+	opts.Shuffle = hasFlag(remaining, "--shuffle")
+	return opts, removeFlag(removeFlag(remaining, "--all"), "--shuffle")
+}
+
+// countFlag parses a non-negative integer flag value ("" = 0)
+func countFlag(flag, value string) int {
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		fmt.Fprintf(os.Stderr, "invalid %s: %s\n", flag, value)
+		exit(1)
+	}
+	return n
+}
+
+// printPlaylistEntries lists up to max entries (0 = all)
+func printPlaylistEntries(playlist *medialab.Playlist, max int) {
+	for i, e := range playlist.Entries {
+		if max > 0 && i == max {
+			fmt.Printf("  ... and %d more\n", len(playlist.Entries)-max)
+			break
+		}
+		fmt.Printf("%4d. %s [%s]\n", e.Index, e.Title, e.Duration)
+	}
+}
+
+func cmdPlaylist(ctx context.Context, lab *medialab.MediaLab, args []string) {
+	if len(args) < 2 || (args[0] != "show" && args[0] != "play") {
+		fmt.Fprintln(os.Stderr, "usage: medialab playlist show|play <url> [--start N] [--limit N|--all] [--shuffle]")
+		exit(1)
+	}
+	if args[0] == "play" {
+		cmdPlay(ctx, lab, append(args[1:], "--playlist"))
+		return
+	}
+
+	opts, remaining := parsePlaylistOptions(args[1:])
+	if len(remaining) != 1 {
+		fmt.Fprintln(os.Stderr, "playlist URL required")
+		exit(1)
+	}
+	playlist, err := lab.ExpandPlaylist(ctx, remaining[0], opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "playlist failed: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("%s", playlist.Title)
+	if playlist.Channel != "" {
+		fmt.Printf(" (%s)", playlist.Channel)
+	}
+	fmt.Printf(" - %d entries\n\n", playlist.Count)
+	printPlaylistEntries(playlist, 0)
+}
+
+func cmdFormats(ctx context.Context, lab *medialab.MediaLab, args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: medialab formats <url>")
+		exit(1)
+	}
+	formats, err := lab.ListFormats(ctx, args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "formats failed: %v\n", err)
+		exit(1)
+	}
+
+	fmt.Printf("%-12s %-5s %-11s %-5s %-14s %-12s %9s  %s\n", "ID", "EXT", "RESOLUTION", "FPS", "VCODEC", "ACODEC", "SIZE", "NOTE")
+	for _, f := range formats {
+		fps, size := "", ""
+		if f.FPS > 0 {
+			fps = strconv.FormatFloat(f.FPS, 'f', -1, 64)
+		}
+		if f.Filesize > 0 {
+			size = formatBytes(f.Filesize)
+		}
+		fmt.Printf("%-12s %-5s %-11s %-5s %-14s %-12s %9s  %s\n",
+			f.ID, f.Ext, f.Resolution, fps, f.VideoCodec, f.AudioCodec, size, f.Note)
+	}
+}
+
+func cmdStations(lab *medialab.MediaLab) {
+	stations := lab.Stations()
+	if len(stations) == 0 {
+		fmt.Println("No stations configured (Config.Stations)")
+		return
+	}
+	for _, st := range stations {
+		fmt.Printf("  %-20s %-10s %s\n", st.Name, st.Genre, st.URL)
+	}
+}
+
+func cmdMacro(lab *medialab.MediaLab, args []string) {
+	if len(args) == 0 || (args[0] == "run" && len(args) < 2) {
+		fmt.Fprintln(os.Stderr, "usage: medialab macro list | run <name> [--parallel] [--stop-on-error]")
+		exit(1)
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		macros, err := lab.Macros()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "macros failed: %v\n", err)
+			exit(1)
+		}
+		if len(macros) == 0 {
+			fmt.Println("No macros configured (Config.Macros or ~/.config/medialab/macros.json)")
+			return
+		}
+		names := make([]string, 0, len(macros))
+		for name := range macros {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			actions := make([]string, len(macros[name]))
+			for i, step := range macros[name] {
+				actions[i] = step.Action
+			}
+			fmt.Printf("  %-20s %s\n", name, strings.Join(actions, ", "))
+		}
+	case "run":
+		opts := medialab.BatchOptions{
+			Parallel:    hasFlag(args, "--parallel"),
+			StopOnError: hasFlag(args, "--stop-on-error"),
+		}
+		// Macros may wait or resolve several queries, so they get the
+		// same two minutes as the batch tool rather than the 30s default
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+		defer cancel()
+		result, err := lab.RunMacro(ctx, args[1], opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "macro failed: %v\n", err)
+			exit(1)
+		}
+		for _, step := range result.Steps {
+			status := "ok"
+			switch {
+			case step.Skipped:
+				status = "skipped"
+			case !step.Success:
+				status = "FAILED: " + step.Error
+			}
+			fmt.Printf("  %2d. %-14s %s\n", step.Index, step.Action, status)
+		}
+		if !result.Success {
+			fmt.Fprintf(os.Stderr, "%d steps failed, %d skipped\n", result.Failed, result.Skipped)
+			exit(1)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown macro command: %s\n", args[0])
+		exit(1)
+	}
+}
+
+func cmdScene(lab *medialab.MediaLab, args []string) {
+	if len(args) == 0 || (args[0] != "list" && args[0] != "ls" && len(args) < 2) {
+		fmt.Fprintln(os.Stderr, "usage: medialab scene save|load|show|delete <name> | list")
+		exit(1)
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		scenes, err := lab.Scenes()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "scenes failed: %v\n", err)
+			exit(1)
+		}
+		if len(scenes) == 0 {
+			fmt.Println("No scenes saved (medialab scene save <name>)")
+			return
+		}
+		for _, scene := range scenes {
+			fmt.Printf("  %-24s %d screens  saved %s\n", scene.Name, len(scene.Screens), scene.SavedAt.Local().Format("2006-01-02 15:04"))
+		}
+	case "save":
+		scene, err := lab.SaveScene(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "scene save failed: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("Saved scene %q (%d screens)\n", scene.Name, len(scene.Screens))
+		printScene(scene)
+	case "load":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		ctx, cancel := context.WithTimeout(ctx, time.Minute)
+		defer cancel()
+		scene, err := lab.LoadScene(ctx, args[1])
+		if scene != nil {
+			printScene(scene)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "scene load failed: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("Loaded scene %q\n", scene.Name)
+	case "show":
+		scene, err := lab.GetScene(args[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "scene failed: %v\n", err)
+			exit(1)
+		}
+		printScene(scene)
+	case "delete", "rm":
+		if err := lab.DeleteScene(args[1]); err != nil {
+			fmt.Fprintf(os.Stderr, "scene delete failed: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("Deleted scene %q\n", args[1])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown scene command: %s\n", args[0])
+		exit(1)
+	}
+}
+
+func printScene(scene *medialab.Scene) {
+	for _, sc := range scene.Screens {
+		state := "playing"
+		if sc.Paused {
+			state = "paused"
+		}
+		entry := sc.Playlist[0]
+		if sc.PlaylistPos > 0 && sc.PlaylistPos <= len(sc.Playlist) {
+			entry = sc.Playlist[sc.PlaylistPos-1]
+		}
+		fmt.Printf("  %-9s %-7s %s at %s, vol %.0f", sc.Screen, state, entry, time.Duration(sc.Position*float64(time.Second)).Round(time.Second), sc.Volume)
+		if len(sc.Playlist) > 1 {
+			fmt.Printf(" [%d/%d]", sc.PlaylistPos, len(sc.Playlist))
+		}
+		fmt.Println()
+	}
+}
+
+func cmdSchedule(lab *medialab.MediaLab, args []string) {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+	scheduler := lab.Scheduler()
+
+	switch args[0] {
+	case "list", "ls":
+		entries, err := scheduler.Entries()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "schedule failed: %v\n", err)
+			exit(1)
+		}
+		if len(entries) == 0 {
+			fmt.Println("No schedule entries (medialab schedule add ...)")
+			return
+		}
+		next, _ := scheduler.NextRuns(time.Now())
+		nextRun := make(map[string]time.Time, len(next))
+		for _, run := range next {
+			nextRun[run.ID] = run.Start
+		}
+		for _, e := range entries {
+			printScheduleEntry(e, nextRun[e.ID])
+		}
+	case "add":
+		entry, err := parseScheduleEntry(lab, args[1:])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			fmt.Fprintln(os.Stderr, `usage: medialab schedule add "<cron>"|--at TIME play <url|query>|stop|scene <name>|macro <name> [--screen N|all] [--until HH:MM] [--name NAME] [--allow-overlap]`)
+			exit(1)
+		}
+		entry, err = scheduler.Add(entry)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "schedule add failed: %v\n", err)
+			exit(1)
+		}
+		next, _ := scheduler.NextRuns(time.Now())
+		for _, run := range next {
+			if run.ID == entry.ID {
+				printScheduleEntry(entry, run.Start)
+			}
+		}
+		fmt.Printf("Added schedule entry %s\n", entry.ID)
+	case "rm", "remove", "delete", "enable", "disable":
+		if len(args) < 2 {
+			fmt.Fprintf(os.Stderr, "usage: medialab schedule %s <id>\n", args[0])
+			exit(1)
+		}
+		var err error
+		done := "Removed"
+		if args[0] == "enable" || args[0] == "disable" {
+			_, err = scheduler.SetDisabled(args[1], args[0] == "disable")
+			done = strings.ToUpper(args[0][:1]) + args[0][1:] + "d"
+		} else {
+			err = scheduler.Remove(args[1])
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "schedule %s failed: %v\n", args[0], err)
+			exit(1)
+		}
+		fmt.Printf("%s schedule entry %s\n", done, args[1])
+	case "conflicts":
+		conflicts, err := scheduler.Conflicts()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "schedule failed: %v\n", err)
+			exit(1)
+		}
+		if len(conflicts) == 0 {
+			fmt.Println("No conflicts in the next two weeks")
+			return
+		}
+		for _, c := range conflicts {
+			fmt.Printf("  %s\n", c)
+		}
+	case "run":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		fmt.Println("Running schedule (Ctrl-C to stop)")
+		scheduler.Run(ctx, func(e medialab.ScheduleEntry, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "[%s] entry %s failed: %v\n", time.Now().Format("15:04:05"), e.ID, err)
+				return
+			}
+			fmt.Printf("[%s] ran entry %s %s\n", time.Now().Format("15:04:05"), e.ID, e.Name)
+		})
+	default:
+		fmt.Fprintf(os.Stderr, "unknown schedule command: %s\n", args[0])
+		exit(1)
 	}
-	fmt.Printf("Playing on screen %d (PID %d): %s\n", int(screen)+1, instance.PID, url)
+}
+
+// parseScheduleEntry parses the arguments of "schedule add"
+func parseScheduleEntry(lab *medialab.MediaLab, args []string) (medialab.ScheduleEntry, error) {
+	var e medialab.ScheduleEntry
+	screenArg, args := flagValue(args, "--screen", "-s")
+	at, args := flagValue(args, "--at")
+	e.Until, args = flagValue(args, "--until")
+	e.Name, args = flagValue(args, "--name")
+	station, args := flagValue(args, "--station")
+	e.AllowOverlap = hasFlag(args, "--allow-overlap")
+	args = removeFlag(args, "--allow-overlap")
+
+	if at != "" {
+		t, err := time.ParseInLocation("2006-01-02T15:04", at, time.Local)
+		if err != nil {
+			return e, fmt.Errorf("invalid --at (want 2006-01-02T15:04): %s", at)
+		}
+		e.At = &t
+	} else if len(args) > 0 {
+		e.Cron, args = args[0], args[1:]
+	}
+	if len(args) == 0 {
+		return e, fmt.Errorf("action required: play, stop, scene or macro")
+	}
+
+	step := medialab.BatchStep{Action: args[0]}
+	switch {
+	case screenArg == "all":
+		step.AllScreens = true
+	case screenArg != "":
+		screen, err := medialab.ParseScreen(screenArg)
+		if err != nil {
+			return e, err
+		}
+		step.Screens = []medialab.Screen{screen}
+	}
+
+	target := strings.Join(args[1:], " ")
+	switch args[0] {
+	case "play":
+		switch {
+		case station != "":
+			step.Station = station
+		case target == "":
+			return e, fmt.Errorf("play needs a url, query or --station")
+		case strings.Contains(target, "://") || strings.HasPrefix(target, "/"):
+			step.URL = target
+		default:
+			step.Query = target
+		}
+		e.Steps = []medialab.BatchStep{step}
+	case "stop", "pause", "resume":
+		e.Steps = []medialab.BatchStep{step}
+	case "scene":
+		e.Scene = target
+	case "macro":
+		steps, err := lab.Macro(target)
+		if err != nil {
+			return e, err
+		}
+		e.Steps = steps
+	default:
+		return e, fmt.Errorf("unknown schedule action: %s", args[0])
+	}
+	return e, nil
+}
+
+func printScheduleEntry(e medialab.ScheduleEntry, next time.Time) {
+	when := e.Cron
+	if e.At != nil {
+		when = e.At.Local().Format("2006-01-02 15:04")
+	}
+	if e.Until != "" {
+		when += " until " + e.Until
+	}
+	what := "scene " + e.Scene
+	if e.Scene == "" {
+		actions := make([]string, len(e.Steps))
+		for i, step := range e.Steps {
+			actions[i] = step.Action
+		}
+		what = strings.Join(actions, ", ")
+	}
+	fmt.Printf("  %-4s %-32s %-20s %s", e.ID, when, what, e.Name)
+	switch {
+	case e.Disabled:
+		fmt.Print("  (disabled)")
+	case !next.IsZero():
+		fmt.Printf("  next %s", next.Format("Mon 01-02 15:04"))
+	}
+	if e.LastError != "" {
+		fmt.Printf("  last run failed: %s", e.LastError)
+	}
+	fmt.Println()
+}
+
+func cmdTimer(lab *medialab.MediaLab, args []string) {
+	screen, remaining := parseScreen(args)
+	fadeStr, remaining := flagValue(remaining, "--fade")
+	if len(remaining) == 0 || len(remaining) > 2 {
+		fmt.Fprintln(os.Stderr, "usage: medialab timer <duration>|end [stop|pause] [--fade D] [--screen N]")
+		exit(1)
+	}
+
+	opts := medialab.TimerOptions{Screen: screen, Action: "stop"}
+	if len(remaining) == 2 {
+		opts.Action = remaining[1]
+	}
+	if remaining[0] == "end" {
+		opts.AtEnd = true
+	} else {
+		seconds, err := parseDurationArg(remaining[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid duration: %s\n", remaining[0])
+			exit(1)
+		}
+		opts.After = time.Duration(seconds * float64(time.Second))
+	}
+	if fadeStr != "" {
+		seconds, err := parseDurationArg(fadeStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --fade: %s\n", fadeStr)
+			exit(1)
+		}
+		opts.Fade = time.Duration(seconds * float64(time.Second))
+	}
+
+	timer, err := lab.StartTimer(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "timer failed: %v\n", err)
+		exit(1)
+	}
+	when := "when the current media ends"
+	if !opts.AtEnd {
+		when = "at " + timer.Due.Format("15:04:05")
+	}
+	fmt.Printf("Will %s %s %s (Ctrl-C to cancel)\n", opts.Action, screen, when)
+
+	// Timers live in this process, so wait for it here
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	done, err := lab.WaitTimer(ctx, timer.ID)
+	if err != nil {
+		lab.CancelTimer(timer.ID)
+		fmt.Println("\nTimer cancelled")
+		return
+	}
+	if done.State == medialab.TimerFailed {
+		fmt.Fprintf(os.Stderr, "timer failed: %s\n", done.Error)
+		exit(1)
+	}
+	if done.Action == "pause" {
+		fmt.Printf("Paused %s\n", screen)
+	} else {
+		fmt.Printf("Stopped %s\n", screen)
+	}
+}
+
+func cmdDoctor(ctx context.Context, lab *medialab.MediaLab, args []string) {
+	report := lab.CheckHealth(ctx)
+	if hasFlag(args, "--json") {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(data))
+	} else {
+		for _, check := range report.Checks {
+			fmt.Printf("[%-4s] %-10s %s\n", check.Status, check.Name, check.Message)
+			if check.Version != "" {
+				fmt.Printf("       %-10s %s\n", "", check.Version)
+			}
+		}
+		fmt.Printf("\nStatus: %s\n", report.Status)
+	}
+	if !report.Ready {
+		exit(1)
+	}
+}
+
+func cmdAudit(lab *medialab.MediaLab, args []string) {
+	if len(args) == 0 || args[0] != "tail" {
+		fmt.Fprintln(os.Stderr, "usage: medialab audit tail [-n N] [--follow] [--actor A] [--source S] [--screen N] [--action X]")
+		exit(1)
+	}
+	args = args[1:]
+	follow := hasFlag(args, "--follow", "-f")
+	args = removeFlag(removeFlag(args, "--follow"), "-f")
+
+	var filter medialab.AuditFilter
+	var screenStr, limitStr string
+	filter.Actor, args = flagValue(args, "--actor")
+	filter.Source, args = flagValue(args, "--source")
+	filter.Action, args = flagValue(args, "--action")
+	screenStr, args = flagValue(args, "--screen", "-s")
+	limitStr, args = flagValue(args, "-n")
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected argument: %s\n", args[0])
+		exit(1)
+	}
+	if screenStr != "" {
+		screen, err := medialab.ParseScreen(screenStr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			exit(1)
+		}
+		filter.Screen = screenLabel(screen)
+	}
+	filter.Limit = 20
+	if limitStr != "" {
+		n, err := strconv.Atoi(limitStr)
+		if err != nil || n <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid -n: %s\n", limitStr)
+			exit(1)
+		}
+		filter.Limit = n
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	for {
+		entries, err := lab.AuditLog(filter)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "audit log: %v\n", err)
+			exit(1)
+		}
+		for _, e := range entries {
+			printAuditEntry(e)
+			filter.Since = e.Time
+		}
+		if !follow {
+			return
+		}
+		filter.Limit = 0
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func printAuditEntry(e medialab.AuditEntry) {
+	via := e.Endpoint + e.Tool
+	if via == "" {
+		via = e.Source
+	}
+	screen := "-"
+	if e.Screen != "" {
+		screen = e.Screen
+	}
+	fmt.Printf("%s  %-12s %-28s %-7s %-14s %s", e.Time.Local().Format("2006-01-02 15:04:05"), e.Actor, via, screen, e.Action, e.Result)
+	if e.Error != "" {
+		fmt.Printf(" (%s)", e.Error)
+	}
+	if len(e.Args) > 0 {
+		fmt.Printf("  %s", e.Args)
+	}
+	fmt.Println()
+}
+
+func cmdDownload(lab *medialab.MediaLab, args []string) {
+	downloads := lab.Downloads()
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: medialab download <url>... [--quality Q] | list | rm <url>")
+		exit(1)
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		stats := downloads.Stats()
+		quota := "unlimited"
+		if stats.Quota > 0 {
+			quota = formatBytes(stats.Quota)
+		}
+		fmt.Printf("%s: %d files, %s of %s\n", stats.Dir, stats.Files, formatBytes(stats.Usage), quota)
+		for _, c := range downloads.Cached() {
+			fmt.Printf("  %9s  %s  %-8s %s\n", formatBytes(c.Size), c.LastUsed.Format("2006-01-02 15:04"), c.Quality, c.URL)
+		}
+		return
+	case "rm", "remove":
+		for _, url := range args[1:] {
+			if err := downloads.Remove(url); err != nil {
+				fmt.Fprintf(os.Stderr, "remove failed: %v\n", err)
+				exit(1)
+			}
+			fmt.Printf("Removed %s\n", url)
+		}
+		return
+	}
+
+	quality, urls := flagValue(args, "--quality", "-q")
+	var q *medialab.Quality
+	if quality != "" {
+		parsed, err := medialab.ParseQuality(quality)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			exit(1)
+		}
+		q = &parsed
+	}
+
+	// Ctrl-C cancels the downloads so partial files are cleaned up
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var ids []string
+	for _, url := range urls {
+		job, err := downloads.Start(url, q)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "download failed: %v\n", err)
+			exit(1)
+		}
+		ids = append(ids, job.ID)
+	}
+
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	failed := false
+	for _, id := range ids {
+		for {
+			job, _ := downloads.Job(id)
+			if job.State != medialab.DownloadQueued && job.State != medialab.DownloadDownloading {
+				fmt.Print("\r\033[K")
+				switch job.State {
+				case medialab.DownloadDone:
+					fmt.Printf("Downloaded %s -> %s (%s)\n", job.URL, job.Path, formatBytes(job.Total))
+				default:
+					failed = true
+					fmt.Fprintf(os.Stderr, "%s %s: %s\n", job.URL, job.State, job.Error)
+				}
+				break
+			}
+			fmt.Printf("\r\033[K[%s] %5.1f%% %s/%s %s/s ETA %ds", job.State, job.Progress,
+				formatBytes(job.Downloaded), formatBytes(job.Total), formatBytes(int64(job.Speed)), job.ETA)
+
+			select {
+			case <-ctx.Done():
+				for _, id := range ids {
+					downloads.Cancel(id)
+				}
+				for _, id := range ids {
+					downloads.Wait(context.Background(), id)
+				}
+				fmt.Println("\nCancelled")
+				exit(1)
+			case <-ticker.C:
+			}
+		}
+	}
+	if failed {
+		exit(1)
+	}
+}
+
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fGiB", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMiB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKiB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
 }
 
 func cmdSearch(ctx context.Context, lab *medialab.MediaLab, args []string) {
 	screen, remaining := parseScreen(args)
+	provider, remaining := flagValue(remaining, "--provider", "-P")
+	minStr, remaining := flagValue(remaining, "--min-duration")
+	maxStr, remaining := flagValue(remaining, "--max-duration")
+	sortOrder, remaining := flagValue(remaining, "--sort")
 	playFirst := hasFlag(args, "--play", "-p")
+	if provider == "" {
+		provider = medialab.DefaultSearchProvider
+	}
+
+	opts := medialab.SearchOptions{
+		MaxResults:  10,
+		ExcludeLive: hasFlag(args, "--no-live"),
+		Sort:        sortOrder,
+		Full:        hasFlag(args, "--full"),
+		Remember:    true,
+	}
+	var err error
+	if minStr != "" {
+		if opts.MinDuration, err = parseDurationArg(minStr); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --min-duration: %s\n", minStr)
+			exit(1)
+		}
+	}
+	if maxStr != "" {
+		if opts.MaxDuration, err = parseDurationArg(maxStr); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --max-duration: %s\n", maxStr)
+			exit(1)
+		}
+	}
 
 	// Remove flags from remaining
 	query := ""
 	for _, arg := range remaining {
-		if arg != "--play" && arg != "-p" {
+		switch arg {
+		case "--play", "-p", "--no-live", "--full":
+		default:
 			query += arg + " "
 		}
 	}
@@ -198,13 +1304,20 @@ func cmdSearch(ctx context.Context, lab *medialab.MediaLab, args []string) {
 
 	if query == "" {
 		fmt.Fprintln(os.Stderr, "search query required")
-		os.Exit(1)
+		exit(1)
 	}
 
-	results, err := lab.SearchYouTube(ctx, query, 10)
+	if opts.Full {
+		// Full metadata fetches each video individually
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Minute)
+		defer cancel()
+	}
+
+	results, err := lab.Search(ctx, provider, query, opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "search failed: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if len(results) == 0 {
@@ -216,20 +1329,89 @@ func cmdSearch(ctx context.Context, lab *medialab.MediaLab, args []string) {
 		instance, err := lab.Play(ctx, results[0].URL, screen)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "play failed: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
-		fmt.Printf("Playing on screen %d: %s\n", int(screen)+1, results[0].Title)
+		fmt.Printf("Playing on %s: %s\n", screen, results[0].Title)
 		fmt.Printf("  Channel: %s | Duration: %s\n", results[0].Channel, results[0].Duration)
 		fmt.Printf("  PID: %d\n", instance.PID)
 		return
 	}
 
-	fmt.Printf("YouTube search: %s\n\n", query)
+	fmt.Printf("Search (%s): %s\n\n", provider, query)
 	for i, r := range results {
 		fmt.Printf("%2d. %s\n", i+1, r.Title)
-		fmt.Printf("    %s | %s\n", r.Channel, r.Duration)
+		details := []string{r.Channel, r.Duration}
+		if r.Live {
+			details[1] = "LIVE"
+		}
+		if r.ViewCount > 0 {
+			details = append(details, fmt.Sprintf("%d views", r.ViewCount))
+		}
+		if r.UploadDate != "" {
+			details = append(details, r.UploadDate)
+		}
+		fmt.Printf("    %s\n", strings.Join(details, " | "))
 		fmt.Printf("    %s\n\n", r.URL)
 	}
+
+	if isTerminal(os.Stdin) && isTerminal(os.Stdout) {
+		if n := promptPick(len(results)); n > 0 {
+			// The search deadline may have passed while waiting for input
+			playCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if pending.entry == nil {
+				if r, err := lab.ResultAt(n); err == nil {
+					startPickAudit(lab, r.URL, screen)
+				}
+			}
+			playResult(playCtx, lab, n, screen)
+		}
+	}
+}
+
+// playResult plays the nth result of the last search
+func playResult(ctx context.Context, lab *medialab.MediaLab, n int, screen medialab.Screen) {
+	instance, result, err := lab.PlayResult(ctx, n, screen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "play failed: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("Playing on %s: %s\n", screen, result.Title)
+	fmt.Printf("  Channel: %s | Duration: %s\n", result.Channel, result.Duration)
+	fmt.Printf("  PID: %d\n", instance.PID)
+}
+
+// promptPick asks for a result number, returning 0 if none was chosen
+func promptPick(count int) int {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Printf("Play [1-%d, Enter to skip]: ", count)
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSpace(line)
+		if line == "" || line == "q" {
+			return 0
+		}
+		if n, convErr := strconv.Atoi(line); convErr == nil && n >= 1 && n <= count {
+			return n
+		}
+		if err != nil {
+			return 0
+		}
+		fmt.Printf("Enter a number from 1 to %d\n", count)
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// parseDurationArg accepts Go durations ("10m"), timestamps ("4:30") or seconds
+func parseDurationArg(s string) (float64, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return d.Seconds(), nil
+	}
+	return medialab.ParseTimestamp(s)
 }
 
 func cmdControl(lab *medialab.MediaLab, action string, args []string) {
@@ -251,13 +1433,17 @@ func cmdControl(lab *medialab.MediaLab, action string, args []string) {
 		err = lab.Prev(screen)
 	case "fullscreen":
 		err = lab.Fullscreen(screen)
+	case "frame-step":
+		err = lab.FrameStep(screen)
+	case "frame-back-step":
+		err = lab.FrameBackStep(screen)
 	}
 
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "%s failed: %v\n", action, err)
-		os.Exit(1)
+		exit(1)
 	}
-	fmt.Printf("%s on screen %d\n", action, int(screen)+1)
+	fmt.Printf("%s on %s\n", action, screen)
 }
 
 func cmdVolume(lab *medialab.MediaLab, args []string) {
@@ -268,23 +1454,27 @@ func cmdVolume(lab *medialab.MediaLab, args []string) {
 		info, err := lab.GetPlaybackInfo(screen)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "failed to get volume: %v\n", err)
-			os.Exit(1)
+			exit(1)
 		}
-		fmt.Printf("Volume on screen %d: %.0f\n", int(screen)+1, info.Volume)
+		if info.State == medialab.StateStopped {
+			fmt.Printf("No player on %s\n", screen)
+			return
+		}
+		fmt.Printf("Volume on %s: %.0f\n", screen, info.Volume)
 		return
 	}
 
 	vol, err := strconv.Atoi(remaining[0])
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "invalid volume: %s\n", remaining[0])
-		os.Exit(1)
+		exit(1)
 	}
 
 	if err := lab.SetVolume(screen, vol); err != nil {
 		fmt.Fprintf(os.Stderr, "volume failed: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
-	fmt.Printf("Volume set to %d on screen %d\n", vol, int(screen)+1)
+	fmt.Printf("Volume set to %d on %s\n", vol, screen)
 }
 
 func cmdSeek(lab *medialab.MediaLab, args []string) {
@@ -302,25 +1492,25 @@ func cmdSeek(lab *medialab.MediaLab, args []string) {
 
 	if posStr == "" {
 		fmt.Fprintln(os.Stderr, "position required")
-		os.Exit(1)
+		exit(1)
 	}
 
 	pos, err := strconv.ParseFloat(posStr, 64)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "invalid position: %s\n", posStr)
-		os.Exit(1)
+		exit(1)
 	}
 
 	if err := lab.Seek(screen, pos, relative); err != nil {
 		fmt.Fprintf(os.Stderr, "seek failed: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	mode := "absolute"
 	if relative {
 		mode = "relative"
 	}
-	fmt.Printf("Seek %s %.1fs on screen %d\n", mode, pos, int(screen)+1)
+	fmt.Printf("Seek %s %.1fs on %s\n", mode, pos, screen)
 }
 
 func cmdInfo(lab *medialab.MediaLab, args []string) {
@@ -329,7 +1519,7 @@ func cmdInfo(lab *medialab.MediaLab, args []string) {
 	info, err := lab.GetPlaybackInfo(screen)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to get info: %v\n", err)
-		os.Exit(1)
+		exit(1)
 	}
 
 	data, _ := json.MarshalIndent(info, "", "  ")
@@ -346,13 +1536,229 @@ func cmdList(lab *medialab.MediaLab) {
 
 	fmt.Println("Active players:")
 	for _, p := range players {
-		fmt.Printf("  Screen %d: PID %d\n", int(p.Screen)+1, p.PID)
+		fmt.Printf("  %s: PID %d\n", p.Screen, p.PID)
 		fmt.Printf("    URL: %s\n", p.URL)
 		fmt.Printf("    Socket: %s\n", p.Socket)
 		fmt.Printf("    Started: %s\n", p.StartedAt.Format(time.RFC3339))
 	}
 }
 
+func cmdSpeed(lab *medialab.MediaLab, args []string) {
+	screen, remaining := parseScreen(args)
+	pitch := !hasFlag(args, "--no-pitch")
+
+	var speedStr string
+	for _, arg := range remaining {
+		if arg != "--no-pitch" {
+			speedStr = arg
+			break
+		}
+	}
+
+	if speedStr == "" {
+		info, err := lab.GetPlaybackInfo(screen)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to get speed: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("Speed on %s: %.2fx\n", screen, info.Speed)
+		return
+	}
+
+	speed, err := strconv.ParseFloat(strings.TrimSuffix(speedStr, "x"), 64)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid speed: %s\n", speedStr)
+		exit(1)
+	}
+
+	if err := lab.SetSpeed(screen, speed, pitch); err != nil {
+		fmt.Fprintf(os.Stderr, "speed failed: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("Speed set to %.2fx on %s\n", speed, screen)
+}
+
+func cmdLoop(lab *medialab.MediaLab, args []string) {
+	screen, remaining := parseScreen(args)
+
+	if len(remaining) == 0 {
+		fmt.Fprintln(os.Stderr, "loop mode required: file, playlist, ab or off")
+		exit(1)
+	}
+
+	mode := remaining[0]
+	var err error
+	switch mode {
+	case "file", "playlist":
+		count := medialab.LoopInfinite
+		if len(remaining) > 1 {
+			count, err = parseLoopCount(remaining[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid loop count: %s\n", remaining[1])
+				exit(1)
+			}
+		}
+		if mode == "file" {
+			err = lab.SetLoopFile(screen, count)
+		} else {
+			err = lab.SetLoopPlaylist(screen, count)
+		}
+	case "ab":
+		if len(remaining) < 3 {
+			fmt.Fprintln(os.Stderr, "loop ab requires <a> <b>")
+			exit(1)
+		}
+		a, errA := medialab.ParseTimestamp(remaining[1])
+		b, errB := medialab.ParseTimestamp(remaining[2])
+		if errA != nil || errB != nil {
+			fmt.Fprintf(os.Stderr, "invalid A-B range: %s %s\n", remaining[1], remaining[2])
+			exit(1)
+		}
+		err = lab.SetABLoop(screen, a, b)
+	case "off", "none":
+		err = lab.ClearLoop(screen)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown loop mode: %s\n", mode)
+		exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "loop failed: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("loop %s on %s\n", strings.Join(remaining, " "), screen)
+}
+
+func parseLoopCount(s string) (int, error) {
+	switch s {
+	case "inf", "infinite", "forever":
+		return medialab.LoopInfinite, nil
+	case "off", "no":
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid loop count: %s", s)
+	}
+	return n, nil
+}
+
+func cmdClip(lab *medialab.MediaLab, args []string) {
+	screen, remaining := parseScreen(args)
+	output, remaining := flagValue(remaining, "--out", "-o")
+	lastStr, remaining := flagValue(remaining, "--last")
+
+	opts := medialab.ClipOptions{Screen: screen, Output: output}
+
+	if lastStr != "" {
+		last, err := medialab.ParseTimestamp(lastStr)
+		if err != nil || last <= 0 {
+			fmt.Fprintf(os.Stderr, "invalid --last: %s\n", lastStr)
+			exit(1)
+		}
+		opts.Last = last
+	} else {
+		if len(remaining) < 2 {
+			fmt.Fprintln(os.Stderr, "start and end required (or --last N)")
+			exit(1)
+		}
+		start, err := medialab.ParseTimestamp(remaining[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid start: %s\n", remaining[0])
+			exit(1)
+		}
+		end, err := medialab.ParseTimestamp(remaining[1])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid end: %s\n", remaining[1])
+			exit(1)
+		}
+		opts.Start, opts.End = start, end
+		remaining = remaining[2:]
+	}
+	if len(remaining) > 0 {
+		opts.URL = strings.Join(remaining, " ")
+	}
+
+	// Encoding can take much longer than the default command timeout
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	result, err := lab.ExportClip(ctx, opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "clip failed: %v\n", err)
+		exit(1)
+	}
+	fmt.Printf("Saved %.1fs clip (%s): %s\n", result.Duration, result.Method, result.Output)
+}
+
+func cmdLibrary(lab *medialab.MediaLab, args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "library subcommand required: scan, watch, search or stats")
+		exit(1)
+	}
+	library := lab.Library()
+
+	switch args[0] {
+	case "scan":
+		result, err := library.Scan(context.Background())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "scan failed: %v\n", err)
+			exit(1)
+		}
+		fmt.Printf("Indexed %d files (+%d added, %d updated, -%d removed) in %s\n",
+			result.Total, result.Added, result.Updated, result.Removed, result.Duration.Round(time.Millisecond))
+
+	case "watch":
+		interval := 5 * time.Minute
+		if v, _ := flagValue(args[1:], "--interval"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				fmt.Fprintf(os.Stderr, "invalid --interval: %s\n", v)
+				exit(1)
+			}
+			interval = d
+		}
+		fmt.Printf("Watching library every %s (Ctrl-C to stop)\n", interval)
+		library.Watch(context.Background(), interval, func(result *medialab.ScanResult, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "scan failed: %v\n", err)
+				return
+			}
+			fmt.Printf("[%s] %d files (+%d ~%d -%d)\n", time.Now().Format("15:04:05"),
+				result.Total, result.Added, result.Updated, result.Removed)
+		})
+
+	case "search", "find":
+		query := strings.Join(args[1:], " ")
+		if query == "" {
+			fmt.Fprintln(os.Stderr, "search query required")
+			exit(1)
+		}
+		matches := library.Search(query, 20)
+		if len(matches) == 0 {
+			fmt.Println("No matches (run 'medialab library scan' to refresh the index)")
+			return
+		}
+		for i, m := range matches {
+			fmt.Printf("%2d. %s", i+1, m.Title)
+			if m.Artist != "" {
+				fmt.Printf(" - %s", m.Artist)
+			}
+			fmt.Printf("  [%.2f]\n", m.Score)
+			fmt.Printf("    %s\n", m.Path)
+		}
+
+	case "stats":
+		stats := library.Stats()
+		data, _ := json.MarshalIndent(stats, "", "  ")
+		fmt.Println(string(data))
+
+	default:
+		fmt.Fprintf(os.Stderr, "unknown library subcommand: %s\n", args[0])
+		exit(1)
+	}
+}
+
 func cmdSetup() {
 	home, _ := os.UserHomeDir()
 	configDir := filepath.Join(home, ".config", "mpv")
@@ -467,3 +1873,25 @@ esac
 	fmt.Println("  mpv2ctl vol 50                         # Set volume on screen 2")
 	fmt.Println("  medialab search 'jazz' --play -s 2     # Search and play")
 }
+
+func cmdCache(lab *medialab.MediaLab, args []string) {
+	cache := lab.Cache()
+	if len(args) > 0 && args[0] == "clear" {
+		cache.Clear()
+		fmt.Println("Cache cleared")
+		return
+	}
+	if len(args) > 0 && args[0] != "stats" {
+		fmt.Fprintf(os.Stderr, "unknown cache subcommand: %s (use stats or clear)\n", args[0])
+		exit(1)
+	}
+
+	stats := cache.Stats()
+	if !stats.Enabled {
+		fmt.Println("Cache disabled")
+		return
+	}
+	fmt.Printf("Entries: %d\n", stats.Entries)
+	fmt.Printf("TTL:     %s\n", time.Duration(stats.TTLSeconds*float64(time.Second)))
+	fmt.Printf("File:    %s\n", stats.Path)
+}
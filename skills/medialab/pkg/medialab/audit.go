@@ -0,0 +1,370 @@
+package medialab
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/phenomenon0/Agent-GO/core"
+)
+
+// AuditEntry is one media action in the audit log
+type AuditEntry struct {
+	Time       time.Time       `json:"time"`
+	Actor      string          `json:"actor"`              // API client, agent or local user
+	Source     string          `json:"source"`             // http, skill, cli, scheduler or timer
+	Endpoint   string          `json:"endpoint,omitempty"` // HTTP route, "POST /v1/screens/{id}/play"
+	Tool       string          `json:"tool,omitempty"`     // agent skill, "media.play"
+	Remote     string          `json:"remote,omitempty"`   // HTTP client address
+	Screen     string          `json:"screen,omitempty"`   // 1-4 or speaker; empty for several screens
+	Action     string          `json:"action"`
+	Args       json.RawMessage `json:"args,omitempty"`
+	Result     string          `json:"result"` // "ok" or an error code
+	Error      string          `json:"error,omitempty"`
+	DurationMS int64           `json:"duration_ms"`
+}
+
+// AuditFilter selects audit entries; zero fields match everything
+type AuditFilter struct {
+	Actor  string
+	Source string
+	Screen string // 1-4 or speaker
+	Action string
+	Since  time.Time // entries after this time
+	Limit  int       // the most recent entries, 0 = all
+}
+
+func (f AuditFilter) match(e AuditEntry) bool {
+	return (f.Actor == "" || e.Actor == f.Actor) &&
+		(f.Source == "" || e.Source == f.Source) &&
+		(f.Screen == "" || e.Screen == f.Screen) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.Since.IsZero() || e.Time.After(f.Since))
+}
+
+// maxAuditArgs bounds the arguments kept per entry
+const maxAuditArgs = 4 << 10
+
+type actorKey struct{}
+
+// WithActor names who acts in ctx, for the audit log. Agents embedding the
+// skills set it on the tool context; the HTTP API uses the client name.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor
+func ActorFromContext(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	actor, ok := ctx.Value(actorKey{}).(string)
+	return actor, ok && actor != ""
+}
+
+// auditScreen is the Screen of an audit entry: 1-4 or speaker
+func auditScreen(screen Screen) string {
+	return fmt.Sprint(screenID(screen))
+}
+
+// auditArgs renders the arguments of an action, dropping them if they
+// are not JSON or too large to keep
+func auditArgs(args any) json.RawMessage {
+	var data []byte
+	switch v := args.(type) {
+	case nil:
+		return nil
+	case json.RawMessage:
+		data = v
+	case []byte:
+		data = v
+	default:
+		data, _ = json.Marshal(v)
+	}
+	if len(data) == 0 || string(data) == "null" || len(data) > maxAuditArgs || !json.Valid(data) {
+		return nil
+	}
+	return json.RawMessage(data)
+}
+
+// Audit logs a media action and appends it to Config.AuditFile. Failing
+// to write the audit log is logged but does not fail the action.
+func (m *MediaLab) Audit(e AuditEntry) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+	if e.Result == "" {
+		e.Result = "ok"
+	}
+
+	level := slog.LevelInfo
+	if e.Result != "ok" {
+		level = slog.LevelWarn
+	}
+	attrs := []slog.Attr{slog.String("actor", e.Actor), slog.String("source", e.Source)}
+	if e.Endpoint != "" {
+		attrs = append(attrs, slog.String("endpoint", e.Endpoint))
+	}
+	if e.Tool != "" {
+		attrs = append(attrs, slog.String("tool", e.Tool))
+	}
+	if e.Screen != "" {
+		attrs = append(attrs, slog.String("screen", e.Screen))
+	}
+	attrs = append(attrs,
+		slog.String("action", e.Action),
+		slog.String("result", e.Result),
+		slog.Int64("duration_ms", e.DurationMS),
+	)
+	if e.Error != "" {
+		attrs = append(attrs, slog.String("err", e.Error))
+	}
+	m.logger.LogAttrs(context.Background(), level, "media action", attrs...)
+
+	if m.config.AuditFile == "" {
+		return
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		m.logger.Error("audit entry", "err", err)
+		return
+	}
+
+	m.auditMu.Lock()
+	defer m.auditMu.Unlock()
+	if err := appendLine(m.config.AuditFile, data); err != nil {
+		m.logger.Error("audit log", "path", m.config.AuditFile, "err", err)
+	}
+}
+
+// appendLine appends data and a newline to path. O_APPEND keeps lines
+// whole when the CLI and a server share the file.
+func appendLine(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// AuditLog returns the entries of the audit log matching filter, oldest
+// first. Lines that do not parse are skipped.
+func (m *MediaLab) AuditLog(filter AuditFilter) ([]AuditEntry, error) {
+	entries := make([]AuditEntry, 0)
+	if m.config.AuditFile == "" {
+		return entries, nil
+	}
+	f, err := os.Open(m.config.AuditFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	for {
+		line, err := reader.ReadBytes('\n')
+		var e AuditEntry
+		if len(line) > 0 && json.Unmarshal(line, &e) == nil && filter.match(e) {
+			entries = append(entries, e)
+			if filter.Limit > 0 && len(entries) > 2*filter.Limit {
+				entries = append(entries[:0], entries[len(entries)-filter.Limit:]...)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// discardLogger is the logger of a Config without one
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+// auditScreenArg returns the screen of JSON arguments with a "screen"
+// field (1-4 or "speaker"), or ""
+func auditScreenArg(args json.RawMessage) string {
+	var v struct {
+		Screen *screenArg `json:"screen"`
+	}
+	if json.Unmarshal(args, &v) != nil || v.Screen == nil {
+		return ""
+	}
+	return auditScreen(Screen(*v.Screen))
+}
+
+// limitedBuffer keeps the first max bytes written to it
+type limitedBuffer struct {
+	bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
+
+// auditRecorder captures the status and the start of the body a handler
+// writes
+type auditRecorder struct {
+	statusRecorder
+	body limitedBuffer
+}
+
+func (r *auditRecorder) Write(p []byte) (int, error) {
+	r.body.Write(p)
+	return r.ResponseWriter.Write(p)
+}
+
+// audited records requests to route that change something (all but GET
+// and HEAD) in the audit log as action. It runs inside protect, which
+// names the client.
+func (s *Server) audited(route, action string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			h(w, r)
+			return
+		}
+		start := time.Now()
+		args := &limitedBuffer{max: maxAuditArgs + 1}
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.TeeReader(r.Body, args), r.Body}
+		rec := &auditRecorder{statusRecorder: statusRecorder{ResponseWriter: w, status: http.StatusOK}, body: limitedBuffer{max: maxAuditArgs}}
+		h(rec, r)
+		// Handlers that fail early leave the body unread
+		io.CopyN(io.Discard, r.Body, maxAuditArgs+1)
+
+		e := AuditEntry{
+			Actor:      "anonymous",
+			Source:     "http",
+			Endpoint:   r.Method + " " + route,
+			Remote:     r.RemoteAddr,
+			Action:     action,
+			Args:       auditArgs(args.Bytes()),
+			DurationMS: time.Since(start).Milliseconds(),
+		}
+		if p, ok := PrincipalFromContext(r.Context()); ok {
+			e.Actor = p.Name
+		}
+		if strings.HasPrefix(route, "/v1/screens/{id}") {
+			if screen, err := ParseScreen(r.PathValue("id")); err == nil {
+				e.Screen = auditScreen(screen)
+			}
+		} else {
+			e.Screen = auditScreenArg(e.Args)
+		}
+
+		var envelope struct {
+			Success *bool  `json:"success"`
+			Code    string `json:"code"`
+			Error   string `json:"error"`
+		}
+		json.Unmarshal(rec.body.Bytes(), &envelope)
+		switch {
+		case rec.status >= http.StatusBadRequest:
+			e.Result, e.Error = envelope.Code, envelope.Error
+			if e.Result == "" {
+				e.Result = statusCode(rec.status)
+			}
+		case envelope.Success != nil && !*envelope.Success:
+			e.Result, e.Error = envelope.Code, envelope.Error
+			if e.Result == "" {
+				e.Result = "error"
+			}
+		}
+		s.lab.Audit(e)
+	}
+}
+
+// readOnlySkills never change what plays, so their calls are not audited
+var readOnlySkills = map[string]bool{"media.info": true, "media.search": true, "media.list": true}
+
+// readOnlyActions are the listing actions of skills that otherwise act
+var readOnlyActions = map[string]bool{"list": true, "show": true, "status": true, "conflicts": true}
+
+// skillDefaultActions are the actions of skills called without one
+var skillDefaultActions = map[string]string{
+	"media.download": "start",
+	"media.scene":    "list",
+	"media.schedule": "list",
+	"media.timer":    "list",
+}
+
+// auditedTool records the calls of a skill in the audit log. The actor is
+// set with WithActor on the tool context, "agent" without one.
+type auditedTool struct {
+	core.Tool
+	lab *MediaLab
+}
+
+func (t auditedTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
+	var input map[string]any
+	extractInput(ctx, &input)
+	action, _ := input["action"].(string)
+	if action == "" {
+		action = skillDefaultActions[t.Name()]
+	}
+	if action == "" {
+		action = strings.TrimPrefix(t.Name(), "media.")
+	}
+	if readOnlySkills[t.Name()] || readOnlyActions[action] {
+		return t.Tool.Execute(ctx)
+	}
+
+	start := time.Now()
+	result := t.Tool.Execute(ctx)
+	e := AuditEntry{
+		Actor:      "agent",
+		Source:     "skill",
+		Tool:       t.Name(),
+		Action:     action,
+		Args:       auditArgs(input),
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if actor, ok := ActorFromContext(ctx.Ctx); ok {
+		e.Actor = actor
+	}
+	e.Screen = auditScreenArg(e.Args)
+	if result != nil && result.Status == core.ToolFailed {
+		e.Result, e.Error = "error", result.Error
+		if output, ok := result.Output.(map[string]any); ok {
+			if code, ok := output["code"].(string); ok {
+				e.Result = code
+			}
+		}
+	}
+	t.lab.Audit(e)
+	return result
+}
@@ -0,0 +1,126 @@
+package medialab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAuditLab(t *testing.T, auth AuthConfig) *MediaLab {
+	t.Helper()
+	lab := newTestLab(t)
+	lab.config.Auth = auth
+	lab.config.AuditFile = filepath.Join(t.TempDir(), "audit", "audit.jsonl")
+	return lab
+}
+
+func TestAuditLog(t *testing.T) {
+	lab := newAuditLab(t, AuthConfig{})
+	if entries, err := lab.AuditLog(AuditFilter{}); err != nil || len(entries) != 0 {
+		t.Fatalf("AuditLog() before any entry = %v, %v", entries, err)
+	}
+
+	base := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	lab.Audit(AuditEntry{Time: base, Actor: "wall", Source: "http", Screen: "2", Action: "play", Args: auditArgs(map[string]any{"url": "https://example.com/a"})})
+	lab.Audit(AuditEntry{Time: base.Add(time.Minute), Actor: "agent", Source: "skill", Screen: "1", Action: "pause", Result: "no_player"})
+	lab.Audit(AuditEntry{Time: base.Add(2 * time.Minute), Actor: "wall", Source: "http", Screen: "2", Action: "stopScreen"})
+
+	// Torn or foreign lines are skipped
+	f, _ := os.OpenFile(lab.config.AuditFile, os.O_WRONLY|os.O_APPEND, 0)
+	f.WriteString("{\"time\": \"garbage\n")
+	f.Close()
+	lab.Audit(AuditEntry{Time: base.Add(3 * time.Minute), Actor: "root", Source: "cli", Action: "scene load"})
+
+	all, err := lab.AuditLog(AuditFilter{})
+	if err != nil || len(all) != 4 {
+		t.Fatalf("AuditLog() = %d entries, %v; want 4", len(all), err)
+	}
+	if all[0].Result != "ok" || string(all[0].Args) != `{"url":"https://example.com/a"}` {
+		t.Errorf("first entry = %+v", all[0])
+	}
+
+	for _, tt := range []struct {
+		filter AuditFilter
+		want   []string // actions
+	}{
+		{AuditFilter{Actor: "wall"}, []string{"play", "stopScreen"}},
+		{AuditFilter{Screen: "1"}, []string{"pause"}},
+		{AuditFilter{Source: "cli"}, []string{"scene load"}},
+		{AuditFilter{Since: base.Add(time.Minute)}, []string{"stopScreen", "scene load"}},
+		{AuditFilter{Limit: 2}, []string{"stopScreen", "scene load"}},
+		{AuditFilter{Actor: "wall", Limit: 1}, []string{"stopScreen"}},
+	} {
+		entries, _ := lab.AuditLog(tt.filter)
+		var got []string
+		for _, e := range entries {
+			got = append(got, e.Action)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("AuditLog(%+v) = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+
+	lab.config.AuditFile = ""
+	lab.Audit(AuditEntry{Actor: "x", Action: "play"})
+	if entries, err := lab.AuditLog(AuditFilter{}); err != nil || len(entries) != 0 {
+		t.Errorf("AuditLog() without AuditFile = %v, %v", entries, err)
+	}
+}
+
+func TestAuditHTTP(t *testing.T) {
+	lab := newAuditLab(t, AuthConfig{Clients: []APIClient{
+		{Name: "admin", Token: "admin-token"},
+		{Name: "wall", Token: "wall-token", Scopes: []Scope{ScopeRead, ScopeControl}, Screens: []Screen{Screen2}},
+	}})
+	s := NewServer(lab)
+	request := func(token, method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		return serveRequest(s, r)
+	}
+
+	request("wall-token", "POST", "/v1/timers", `{"screen": 2, "action": "stop", "after": 3600}`)
+	request("wall-token", "POST", "/v1/screens/3/control", `{"action": "next"}`)
+	request("admin-token", "POST", "/v1/timers", `{"screen": "speaker", "action": "pause", "after": 3600}`)
+	request("admin-token", "GET", "/v1/timers", "")
+	defer lab.CancelTimer("1")
+	defer lab.CancelTimer("2")
+
+	entries, _ := lab.AuditLog(AuditFilter{})
+	if len(entries) != 3 {
+		t.Fatalf("audit log = %+v, want the 3 requests that act", entries)
+	}
+	want := []AuditEntry{
+		{Actor: "wall", Endpoint: "POST /v1/timers", Screen: "2", Action: "startTimer", Result: "ok"},
+		{Actor: "wall", Endpoint: "POST /v1/screens/{id}/control", Screen: "3", Action: "control", Result: "forbidden"},
+		{Actor: "admin", Endpoint: "POST /v1/timers", Screen: "speaker", Action: "startTimer", Result: "ok"},
+	}
+	for i, e := range entries {
+		w := want[i]
+		if e.Source != "http" || e.Actor != w.Actor || e.Endpoint != w.Endpoint || e.Screen != w.Screen || e.Action != w.Action || e.Result != w.Result {
+			t.Errorf("entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+	if entries[1].Error == "" || string(entries[1].Args) != `{"action":"next"}` {
+		t.Errorf("forbidden entry = %+v, want its error and args", entries[1])
+	}
+
+	// Clients limited to some screens only see actions on them
+	w := request("wall-token", "GET", "/v1/audit", "")
+	if body := decodeEnvelope(t, w); w.Code != http.StatusOK || body["count"] != 1.0 {
+		t.Errorf("GET /v1/audit as wall = %d %v", w.Code, body)
+	}
+	w = request("admin-token", "GET", "/v1/audit?actor=wall&limit=1", "")
+	body := decodeEnvelope(t, w)
+	list, _ := body["entries"].([]any)
+	if w.Code != http.StatusOK || len(list) != 1 || list[0].(map[string]any)["action"] != "control" {
+		t.Errorf("GET /v1/audit?actor=wall&limit=1 = %d %v", w.Code, body)
+	}
+	if w := request("admin-token", "GET", "/v1/audit?since=yesterday", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("GET /v1/audit with a bad since = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,400 @@
+package medialab
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scope is a permission granted to an API client
+type Scope string
+
+const (
+	ScopeRead    Scope = "read"    // info, list, search, formats, stations, library and download listings
+	ScopeControl Scope = "control" // pause/seek/volume/loop/speed, downloads, library scans
+	ScopePlay    Scope = "play"    // start playback
+)
+
+// AuthConfig controls access to the HTTP API. With no clients configured
+// and no authenticators added to the Server, the API is open.
+type AuthConfig struct {
+	Clients       []APIClient
+	ClientCAFile  string // PEM CA bundle for mTLS client certificates
+	PublicHealth  bool   // serve /health and /ready without credentials
+	PublicMetrics bool   // serve /metrics without credentials, for Prometheus
+
+	// MaxClockSkew is the accepted age of HMAC-signed requests, 0 = 5m.
+	// Signatures already seen within it are rejected as replays; the cache
+	// lives in memory, so a replay right after a restart still passes.
+	MaxClockSkew time.Duration
+}
+
+// APIClient is one client of the HTTP API and the credentials it may
+// present. Any of Token, Secret and CertCN may be set.
+type APIClient struct {
+	Name    string   // identifies the client; the HMAC key ID
+	Token   string   // static bearer token
+	Secret  string   // HMAC-SHA256 signing key, see SignRequest
+	CertCN  string   // common name of an mTLS client certificate
+	Scopes  []Scope  // granted scopes, empty = all
+	Screens []Screen // screens the client may target, empty = all
+}
+
+// Principal is an authenticated API client
+type Principal struct {
+	Name    string
+	Scopes  []Scope
+	Screens []Screen
+}
+
+// Allows reports whether p was granted scope
+func (p *Principal) Allows(scope Scope) bool {
+	return len(p.Scopes) == 0 || slices.Contains(p.Scopes, scope)
+}
+
+// AllowsScreen reports whether p may target screen
+func (p *Principal) AllowsScreen(screen Screen) bool {
+	return len(p.Screens) == 0 || slices.Contains(p.Screens, screen)
+}
+
+func (c APIClient) principal() *Principal {
+	return &Principal{Name: c.Name, Scopes: c.Scopes, Screens: c.Screens}
+}
+
+// Authenticator identifies the client behind a request. It returns nil and
+// no error when the request carries none of its credentials, so the next
+// authenticator can try; an error rejects the request.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// BearerAuth authenticates "Authorization: Bearer <token>" headers
+type BearerAuth struct {
+	Clients []APIClient
+}
+
+func (a BearerAuth) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return nil, nil
+	}
+	for _, c := range a.Clients {
+		if c.Token != "" && subtle.ConstantTimeCompare([]byte(c.Token), []byte(token)) == 1 {
+			return c.principal(), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: invalid bearer token", ErrUnauthorized)
+}
+
+// HMAC request signing headers, see SignRequest
+const (
+	HeaderKeyID     = "X-Medialab-Key"
+	HeaderTimestamp = "X-Medialab-Timestamp"
+	HeaderSignature = "X-Medialab-Signature"
+	HeaderNonce     = "X-Medialab-Nonce"
+)
+
+// maxSignedBody bounds the body HMACAuth reads before the signature is
+// verified
+const maxSignedBody = 1 << 20
+
+// HMACAuth authenticates requests signed with SignRequest. Signatures are
+// bound to the method, path, query, body, time and nonce. One made by
+// NewHMACAuth, as the Server's is, also rejects signatures it has already
+// accepted within MaxSkew; a bare HMACAuth lets them be replayed.
+type HMACAuth struct {
+	Clients []APIClient
+	MaxSkew time.Duration // 0 = 5m
+
+	seen *signatureCache
+}
+
+// NewHMACAuth returns an HMACAuth that rejects replayed signatures
+func NewHMACAuth(clients []APIClient, maxSkew time.Duration) HMACAuth {
+	return HMACAuth{Clients: clients, MaxSkew: maxSkew, seen: &signatureCache{seen: make(map[string]time.Time)}}
+}
+
+// signatureCache remembers accepted signatures until their timestamp
+// leaves the allowed window
+type signatureCache struct {
+	mu     sync.Mutex
+	seen   map[string]time.Time // signature -> expiry
+	pruned time.Time
+}
+
+// add records sig until expires; it reports false if sig was already seen
+func (c *signatureCache) add(sig string, expires time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if now.Sub(c.pruned) > time.Minute {
+		for s, exp := range c.seen {
+			if now.After(exp) {
+				delete(c.seen, s)
+			}
+		}
+		c.pruned = now
+	}
+	if exp, ok := c.seen[sig]; ok && !now.After(exp) {
+		return false
+	}
+	c.seen[sig] = expires
+	return true
+}
+
+func (a HMACAuth) Authenticate(r *http.Request) (*Principal, error) {
+	keyID := r.Header.Get(HeaderKeyID)
+	if keyID == "" {
+		return nil, nil
+	}
+	idx := slices.IndexFunc(a.Clients, func(c APIClient) bool { return c.Secret != "" && c.Name == keyID })
+	if idx < 0 {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrUnauthorized, keyID)
+	}
+
+	ts, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing or invalid %s", ErrUnauthorized, HeaderTimestamp)
+	}
+	skew := a.MaxSkew
+	if skew <= 0 {
+		skew = 5 * time.Minute
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > skew || age < -skew {
+		return nil, fmt.Errorf("%w: request timestamp outside the allowed window", ErrUnauthorized)
+	}
+
+	if r.Body != nil {
+		r.Body = http.MaxBytesReader(nil, r.Body, maxSignedBody)
+	}
+	body, err := readBody(r)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return nil, fmt.Errorf("%w: signed request body over %d bytes", ErrInvalidArgument, tooLarge.Limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	want := requestSignature(a.Clients[idx].Secret, r.Method, r.URL.RequestURI(), r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderNonce), body)
+	got, err := hex.DecodeString(r.Header.Get(HeaderSignature))
+	if err != nil || !hmac.Equal(got, want) {
+		return nil, fmt.Errorf("%w: bad signature", ErrUnauthorized)
+	}
+	if a.seen != nil && !a.seen.add(keyID+" "+hex.EncodeToString(got), time.Unix(ts, 0).Add(skew)) {
+		return nil, fmt.Errorf("%w: replayed signature", ErrUnauthorized)
+	}
+	return a.Clients[idx].principal(), nil
+}
+
+// SignRequest signs r for HMACAuth as client keyID: HMAC-SHA256 over the
+// method, request URI, Unix timestamp, body SHA-256 and a random nonce,
+// each on its own line, hex-encoded in X-Medialab-Signature. The nonce
+// keeps identical requests signed in the same second apart.
+func SignRequest(r *http.Request, keyID, secret string) error {
+	body, err := readBody(r)
+	if err != nil {
+		return err
+	}
+	var nonce [16]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	r.Header.Set(HeaderKeyID, keyID)
+	r.Header.Set(HeaderTimestamp, ts)
+	r.Header.Set(HeaderNonce, hex.EncodeToString(nonce[:]))
+	r.Header.Set(HeaderSignature, hex.EncodeToString(requestSignature(secret, r.Method, r.URL.RequestURI(), ts, r.Header.Get(HeaderNonce), body)))
+	return nil
+}
+
+// requestSignature signs a request; requests without a nonce sign the
+// first four lines only
+func requestSignature(secret, method, uri, ts, nonce string, body []byte) []byte {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%x", method, uri, ts, bodyHash)
+	if nonce != "" {
+		fmt.Fprintf(mac, "\n%s", nonce)
+	}
+	return mac.Sum(nil)
+}
+
+// readBody reads r's body and puts it back for the handler
+func readBody(r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// MTLSAuth authenticates verified TLS client certificates by common name.
+// The server's tls.Config must request and verify them, see
+// Server.ClientTLSConfig.
+type MTLSAuth struct {
+	Clients []APIClient
+}
+
+func (a MTLSAuth) Authenticate(r *http.Request) (*Principal, error) {
+	if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+		return nil, nil
+	}
+	cn := r.TLS.VerifiedChains[0][0].Subject.CommonName
+	for _, c := range a.Clients {
+		if c.CertCN != "" && c.CertCN == cn {
+			return c.principal(), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: client certificate %q not authorized", ErrUnauthorized, cn)
+}
+
+// authenticatorsFor returns the built-in authenticators cfg needs
+func authenticatorsFor(cfg AuthConfig) []Authenticator {
+	var bearer, signed, certs bool
+	for _, c := range cfg.Clients {
+		bearer = bearer || c.Token != ""
+		signed = signed || c.Secret != ""
+		certs = certs || c.CertCN != ""
+	}
+	var auths []Authenticator
+	if certs {
+		auths = append(auths, MTLSAuth{Clients: cfg.Clients})
+	}
+	if bearer {
+		auths = append(auths, BearerAuth{Clients: cfg.Clients})
+	}
+	if signed {
+		auths = append(auths, NewHMACAuth(cfg.Clients, cfg.MaxClockSkew))
+	}
+	return auths
+}
+
+// ClientTLSConfig returns a TLS config that verifies client certificates
+// against Config.Auth.ClientCAFile when presented, for MTLSAuth. Clients
+// without a certificate can still use other credentials.
+func (s *Server) ClientTLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	caFile := s.lab.config.Auth.ClientCAFile
+	if caFile == "" {
+		return cfg, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading client CAs: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates in %s", caFile)
+	}
+	cfg.ClientCAs = pool
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	return cfg, nil
+}
+
+type principalKey struct{}
+
+// PrincipalFromContext returns the client authenticated for a request.
+// It is absent when the API is open.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// AddAuthenticator adds a to the authenticators tried, in order, for each
+// request. Adding any authenticator closes an otherwise open API.
+func (s *Server) AddAuthenticator(a Authenticator) {
+	s.auth = append(s.auth, a)
+}
+
+// authenticate identifies the client of r; nil means the API is open
+func (s *Server) authenticate(r *http.Request) (*Principal, error) {
+	if len(s.auth) == 0 && len(s.lab.config.Auth.Clients) == 0 {
+		return nil, nil
+	}
+	if local, _ := r.Context().Value(unixConnKey{}).(bool); local {
+		return &Principal{Name: "unix-socket"}, nil
+	}
+	for _, a := range s.auth {
+		p, err := a.Authenticate(r)
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: credentials required", ErrUnauthorized)
+}
+
+// handle registers h for path behind authentication requiring scope; an
+// empty scope leaves the route public. Requests other than GET and HEAD
+// are audited.
+func (s *Server) handle(path string, scope Scope, h http.HandlerFunc) {
+	s.mux.HandleFunc(path, s.instrument(path, s.protect(scope, s.audited(path, strings.TrimPrefix(path, "/"), h))))
+}
+
+// protect wraps h with authentication requiring scope
+func (s *Server) protect(scope Scope, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if scope == "" {
+			h(w, r)
+			return
+		}
+		p, err := s.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="medialab"`)
+			s.writeLabError(w, err)
+			return
+		}
+		if p != nil {
+			if !p.Allows(scope) {
+				s.writeLabError(w, fmt.Errorf("%w: %s lacks scope %q", ErrForbidden, p.Name, scope))
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), principalKey{}, p))
+		}
+		h(w, r)
+	}
+}
+
+// requireScope checks an additional scope for part of a route, such as
+// the POST side of a listing; it writes the error and returns false when
+// the client lacks it
+func (s *Server) requireScope(w http.ResponseWriter, r *http.Request, scope Scope) bool {
+	if p, ok := PrincipalFromContext(r.Context()); ok && !p.Allows(scope) {
+		s.writeLabError(w, fmt.Errorf("%w: %s lacks scope %q", ErrForbidden, p.Name, scope))
+		return false
+	}
+	return true
+}
+
+// authorizeScreen checks the client's screen ACL; it writes the error and
+// returns false when screen is off limits
+func (s *Server) authorizeScreen(w http.ResponseWriter, r *http.Request, screen Screen) bool {
+	if p, ok := PrincipalFromContext(r.Context()); ok && !p.AllowsScreen(screen) {
+		s.writeLabError(w, fmt.Errorf("%w: %s may not use %s", ErrForbidden, p.Name, screen))
+		return false
+	}
+	return true
+}
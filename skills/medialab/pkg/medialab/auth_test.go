@@ -0,0 +1,162 @@
+package medialab
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newAuthServer(auth AuthConfig) *Server {
+	cfg := DefaultConfig()
+	cfg.Auth = auth
+	cfg.AuditFile = ""
+	return NewServer(New(cfg))
+}
+
+func serveRequest(s *Server, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	return w
+}
+
+func TestServerOpenByDefault(t *testing.T) {
+	s := newAuthServer(AuthConfig{})
+	if w := serveRequest(s, httptest.NewRequest("GET", "/stations", nil)); w.Code != http.StatusOK {
+		t.Errorf("GET /stations = %d, want 200", w.Code)
+	}
+}
+
+func TestBearerScopes(t *testing.T) {
+	s := newAuthServer(AuthConfig{
+		Clients: []APIClient{
+			{Name: "dashboard", Token: "read-token", Scopes: []Scope{ScopeRead}},
+			{Name: "remote", Token: "control-token", Scopes: []Scope{ScopeRead, ScopeControl}},
+		},
+		PublicHealth: true,
+	})
+	request := func(method, path, token, body string) int {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return serveRequest(s, r).Code
+	}
+
+	tests := []struct {
+		method, path, token, body string
+		want                      int
+	}{
+		{"GET", "/stations", "", "", http.StatusUnauthorized},
+		{"GET", "/stations", "wrong", "", http.StatusUnauthorized},
+		{"GET", "/stations", "read-token", "", http.StatusOK},
+		{"GET", "/health", "", "", http.StatusOK},
+		{"POST", "/control", "read-token", `{"action": "bogus"}`, http.StatusForbidden},
+		{"POST", "/control", "control-token", `{"action": "bogus"}`, http.StatusBadRequest},
+		{"POST", "/play", "control-token", `{}`, http.StatusForbidden},
+		{"POST", "/downloads", "read-token", `{}`, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if got := request(tt.method, tt.path, tt.token, tt.body); got != tt.want {
+			t.Errorf("%s %s with %q = %d, want %d", tt.method, tt.path, tt.token, got, tt.want)
+		}
+	}
+}
+
+func TestScreenACL(t *testing.T) {
+	s := newAuthServer(AuthConfig{Clients: []APIClient{
+		{Name: "kids-room", Token: "t", Screens: []Screen{Screen2, ScreenSpeaker}},
+	}})
+	info := func(screen string) int {
+		r := httptest.NewRequest("GET", "/info?screen="+screen, nil)
+		r.Header.Set("Authorization", "Bearer t")
+		return serveRequest(s, r).Code
+	}
+	if got := info("1"); got != http.StatusForbidden {
+		t.Errorf("GET /info?screen=1 = %d, want 403", got)
+	}
+	if got := info("speaker"); got == http.StatusForbidden {
+		t.Errorf("GET /info?screen=speaker = %d, want allowed", got)
+	}
+
+	r := httptest.NewRequest("POST", "/volume", strings.NewReader(`{"volume": 10, "screen": 3}`))
+	r.Header.Set("Authorization", "Bearer t")
+	if got := serveRequest(s, r).Code; got != http.StatusForbidden {
+		t.Errorf("POST /volume screen 3 = %d, want 403", got)
+	}
+}
+
+func TestHMACAuth(t *testing.T) {
+	s := newAuthServer(AuthConfig{Clients: []APIClient{{Name: "agent", Secret: "s3cret"}}})
+	signed := func(secret, body string) *http.Request {
+		r := httptest.NewRequest("POST", "/control?x=1", strings.NewReader(body))
+		if err := SignRequest(r, "agent", secret); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	// Authenticated, then rejected by the handler for the unknown action
+	if got := serveRequest(s, signed("s3cret", `{"action": "bogus"}`)).Code; got != http.StatusBadRequest {
+		t.Errorf("signed request = %d, want 400", got)
+	}
+	if got := serveRequest(s, signed("guess", `{"action": "bogus"}`)).Code; got != http.StatusUnauthorized {
+		t.Errorf("wrong secret = %d, want 401", got)
+	}
+
+	tampered := signed("s3cret", `{"action": "bogus"}`)
+	tampered.Body = httptest.NewRequest("POST", "/", strings.NewReader(`{"action": "stop"}`)).Body
+	if got := serveRequest(s, tampered).Code; got != http.StatusUnauthorized {
+		t.Errorf("tampered body = %d, want 401", got)
+	}
+
+	stale := signed("s3cret", `{}`)
+	stale.Header.Set(HeaderTimestamp, "1000000000")
+	if got := serveRequest(s, stale).Code; got != http.StatusUnauthorized {
+		t.Errorf("stale timestamp = %d, want 401", got)
+	}
+
+	// A captured request cannot be sent again; the nonce keeps identical
+	// requests apart
+	first := signed("s3cret", `{"action": "bogus"}`)
+	replay := httptest.NewRequest("POST", "/control?x=1", strings.NewReader(`{"action": "bogus"}`))
+	replay.Header = first.Header.Clone()
+	if got := serveRequest(s, first).Code; got != http.StatusBadRequest {
+		t.Errorf("first request = %d, want 400", got)
+	}
+	if got := serveRequest(s, replay).Code; got != http.StatusUnauthorized {
+		t.Errorf("replayed request = %d, want 401", got)
+	}
+	if got := serveRequest(s, signed("s3cret", `{"action": "bogus"}`)).Code; got != http.StatusBadRequest {
+		t.Errorf("identical request with a new nonce = %d, want 400", got)
+	}
+
+	huge := signed("s3cret", `{"action": "bogus", "pad": "`+strings.Repeat("x", maxSignedBody)+`"}`)
+	if got := serveRequest(s, huge).Code; got != http.StatusBadRequest {
+		t.Errorf("oversized body = %d, want 400", got)
+	}
+}
+
+func TestMTLSAuth(t *testing.T) {
+	auth := MTLSAuth{Clients: []APIClient{{Name: "tv", CertCN: "livingroom-tv", Scopes: []Scope{ScopeRead}}}}
+	withCert := func(cn string) *http.Request {
+		r := httptest.NewRequest("GET", "/info", nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: cn}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		return r
+	}
+
+	p, err := auth.Authenticate(withCert("livingroom-tv"))
+	if err != nil || p == nil || p.Name != "tv" || p.Allows(ScopePlay) {
+		t.Errorf("Authenticate(known cert) = %+v, %v", p, err)
+	}
+	if _, err := auth.Authenticate(withCert("stranger")); err == nil {
+		t.Error("Authenticate(unknown cert) succeeded")
+	}
+	if p, err := auth.Authenticate(httptest.NewRequest("GET", "/info", nil)); p != nil || err != nil {
+		t.Errorf("Authenticate(no TLS) = %v, %v; want nil, nil", p, err)
+	}
+}
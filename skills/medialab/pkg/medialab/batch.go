@@ -0,0 +1,456 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchStep is one operation of a batch or macro. In JSON, "screen" is a
+// screen number, "speaker", "all" (every screen with a running player) or
+// a list of screens; it defaults to Config.DefaultScreen.
+type BatchStep struct {
+	Action     string   // see batchActions
+	Screens    []Screen // screens the action applies to, empty = default screen
+	AllScreens bool     // apply to every screen with a running player
+
+	URL     string   // play, queue
+	URLs    []string // queue
+	Query   string   // play, queue: resolved like media.play's query
+	Station string   // play
+	Quality string   // play, queue
+
+	Volume   int     // volume
+	Position float64 // seek
+	Relative bool    // seek
+	Speed    float64 // speed
+	Count    int     // loop-file, loop-playlist: LoopInfinite = forever, 0 = off
+	Seconds  float64 // wait
+}
+
+// batchActions are the actions a BatchStep may take
+var batchActions = []string{
+	"play", "queue", "stop", "pause", "resume", "playpause", "next", "prev",
+	"volume", "mute", "unmute", "seek", "fullscreen", "speed",
+	"loop-file", "loop-playlist", "loop-off", "wait",
+}
+
+// batchStepSchema is the JSON schema of one step, shared by media.batch
+// and POST /v1/batch
+const batchStepSchema = `{
+	"type": "object",
+	"properties": {
+		"action": {"type": "string", "enum": ["play", "queue", "stop", "pause", "resume", "playpause", "next", "prev", "volume", "mute", "unmute", "seek", "fullscreen", "speed", "loop-file", "loop-playlist", "loop-off", "wait"]},
+		"screen": {"description": "1-4, speaker, all (screens with a player) or a list; default screen when omitted"},
+		"url": {"type": "string"},
+		"urls": {"type": "array", "items": {"type": "string"}},
+		"query": {"type": "string"},
+		"station": {"type": "string"},
+		"quality": {"type": "string"},
+		"volume": {"type": "integer", "minimum": 0, "maximum": 100},
+		"position": {"type": "number"},
+		"relative": {"type": "boolean"},
+		"speed": {"type": "number"},
+		"count": {"type": "integer", "minimum": -1, "description": "loop count, -1 or omitted = infinite, 0 = off"},
+		"seconds": {"type": "number", "description": "wait duration"}
+	},
+	"required": ["action"]
+}`
+
+// batchStepJSON is the wire form of BatchStep
+type batchStepJSON struct {
+	Action   string          `json:"action"`
+	Screen   json.RawMessage `json:"screen,omitempty"`
+	URL      string          `json:"url,omitempty"`
+	URLs     []string        `json:"urls,omitempty"`
+	Query    string          `json:"query,omitempty"`
+	Station  string          `json:"station,omitempty"`
+	Quality  string          `json:"quality,omitempty"`
+	Volume   int             `json:"volume,omitempty"`
+	Position float64         `json:"position,omitempty"`
+	Relative bool            `json:"relative,omitempty"`
+	Speed    float64         `json:"speed,omitempty"`
+	Count    *int            `json:"count,omitempty"`
+	Seconds  float64         `json:"seconds,omitempty"`
+}
+
+func (s *BatchStep) UnmarshalJSON(data []byte) error {
+	var raw batchStepJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = BatchStep{
+		Action: raw.Action, URL: raw.URL, URLs: raw.URLs, Query: raw.Query, Station: raw.Station,
+		Quality: raw.Quality, Volume: raw.Volume, Position: raw.Position, Relative: raw.Relative,
+		Speed: raw.Speed, Seconds: raw.Seconds,
+	}
+	if raw.Action == "loop-file" || raw.Action == "loop-playlist" {
+		s.Count = loopCount(raw.Count)
+	}
+	if len(raw.Screen) == 0 || string(raw.Screen) == "null" {
+		return nil
+	}
+
+	var list []json.RawMessage
+	if json.Unmarshal(raw.Screen, &list) != nil {
+		list = []json.RawMessage{raw.Screen}
+	}
+	for _, item := range list {
+		str := strings.Trim(string(item), `"`)
+		if strings.EqualFold(str, "all") {
+			s.AllScreens = true
+			continue
+		}
+		screen, err := ParseScreen(str)
+		if err != nil {
+			return err
+		}
+		s.Screens = append(s.Screens, screen)
+	}
+	return nil
+}
+
+func (s BatchStep) MarshalJSON() ([]byte, error) {
+	raw := batchStepJSON{
+		Action: s.Action, URL: s.URL, URLs: s.URLs, Query: s.Query, Station: s.Station,
+		Quality: s.Quality, Volume: s.Volume, Position: s.Position, Relative: s.Relative,
+		Speed: s.Speed, Seconds: s.Seconds,
+	}
+	if s.Action == "loop-file" || s.Action == "loop-playlist" {
+		count := s.Count
+		raw.Count = &count
+	}
+	var screen any
+	switch {
+	case s.AllScreens:
+		screen = "all"
+	case len(s.Screens) == 1:
+		screen = screenID(s.Screens[0])
+	case len(s.Screens) > 1:
+		ids := make([]any, len(s.Screens))
+		for i, sc := range s.Screens {
+			ids[i] = screenID(sc)
+		}
+		screen = ids
+	}
+	if screen != nil {
+		raw.Screen, _ = json.Marshal(screen)
+	}
+	return json.Marshal(raw)
+}
+
+// validate checks s without touching any player, so a typo late in a
+// batch does not leave the earlier steps applied
+func (s BatchStep) validate() error {
+	if !slices.Contains(batchActions, s.Action) {
+		return fmt.Errorf("%w: unknown action %q", ErrInvalidArgument, s.Action)
+	}
+	for _, screen := range s.Screens {
+		if !screen.Valid() {
+			return fmt.Errorf("%w: invalid screen %d", ErrInvalidArgument, screen)
+		}
+	}
+	switch s.Action {
+	case "play":
+		if s.URL == "" && s.Query == "" && s.Station == "" {
+			return fmt.Errorf("%w: play needs url, query or station", ErrInvalidArgument)
+		}
+	case "queue":
+		if s.URL == "" && len(s.URLs) == 0 && s.Query == "" {
+			return fmt.Errorf("%w: queue needs url, urls or query", ErrInvalidArgument)
+		}
+	case "volume":
+		if s.Volume < 0 || s.Volume > 100 {
+			return fmt.Errorf("%w: volume out of range (0-100): %d", ErrInvalidArgument, s.Volume)
+		}
+	case "speed":
+		if s.Speed < 0.01 || s.Speed > 100 {
+			return fmt.Errorf("%w: speed out of range (0.01-100): %g", ErrInvalidArgument, s.Speed)
+		}
+	case "wait":
+		if s.Seconds <= 0 {
+			return fmt.Errorf("%w: wait needs seconds", ErrInvalidArgument)
+		}
+	}
+	if s.Quality != "" {
+		if _, err := ParseQuality(s.Quality); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BatchOptions controls how RunBatch executes its steps
+type BatchOptions struct {
+	Parallel    bool // run all steps at once instead of in order
+	StopOnError bool // skip the steps not yet started after a failure
+}
+
+// BatchStepResult is the outcome of one step
+type BatchStepResult struct {
+	Index      int    `json:"index"`
+	Action     string `json:"action"`
+	Screens    []any  `json:"screens,omitempty"` // screen numbers or "speaker"
+	Success    bool   `json:"success"`
+	Skipped    bool   `json:"skipped,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Code       string `json:"code,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// BatchResult is the outcome of RunBatch, one result per step in order
+type BatchResult struct {
+	Success bool              `json:"success"` // every step succeeded
+	Steps   []BatchStepResult `json:"steps"`
+	Failed  int               `json:"failed"`
+	Skipped int               `json:"skipped"`
+}
+
+// RunBatch runs steps against the players, in order or in parallel. Steps
+// are validated before any runs; a failing step does not stop the others
+// unless opts.StopOnError is set.
+func (m *MediaLab) RunBatch(ctx context.Context, steps []BatchStep, opts BatchOptions) (*BatchResult, error) {
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: empty batch", ErrInvalidArgument)
+	}
+	for i, step := range steps {
+		if err := step.validate(); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	result := &BatchResult{Success: true, Steps: make([]BatchStepResult, len(steps))}
+	run := func(i int) {
+		res := &result.Steps[i]
+		res.Index = i + 1
+		res.Action = steps[i].Action
+		if ctx.Err() != nil {
+			res.Skipped = true
+			return
+		}
+
+		began := time.Now()
+		screens, err := m.runStep(ctx, steps[i])
+		res.DurationMS = time.Since(began).Milliseconds()
+		for _, screen := range screens {
+			res.Screens = append(res.Screens, screenID(screen))
+		}
+		if err != nil {
+			res.Error = err.Error()
+			res.Code = errorCode(err)
+			if opts.StopOnError {
+				cancel()
+			}
+			return
+		}
+		res.Success = true
+	}
+
+	if opts.Parallel {
+		var wg sync.WaitGroup
+		for i := range steps {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				run(i)
+			}(i)
+		}
+		wg.Wait()
+	} else {
+		for i := range steps {
+			run(i)
+		}
+	}
+
+	for _, res := range result.Steps {
+		switch {
+		case res.Skipped:
+			result.Skipped++
+			result.Success = false
+		case !res.Success:
+			result.Failed++
+			result.Success = false
+		}
+	}
+	return result, nil
+}
+
+// stepScreens returns the screens step applies to
+func (m *MediaLab) stepScreens(step BatchStep) ([]Screen, error) {
+	if step.AllScreens {
+		var screens []Screen
+		for _, p := range m.ListPlayers() {
+			screens = append(screens, p.Screen)
+		}
+		slices.Sort(screens)
+		if len(screens) == 0 {
+			return nil, fmt.Errorf("%w: no screen is playing", ErrNoPlayer)
+		}
+		return screens, nil
+	}
+	if len(step.Screens) == 0 {
+		return []Screen{m.config.DefaultScreen}, nil
+	}
+	return step.Screens, nil
+}
+
+// runStep applies step to each of its screens, returning them and the
+// joined errors
+func (m *MediaLab) runStep(ctx context.Context, step BatchStep) ([]Screen, error) {
+	if step.Action == "wait" {
+		select {
+		case <-time.After(time.Duration(step.Seconds * float64(time.Second))):
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	screens, err := m.stepScreens(step)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	for _, screen := range screens {
+		if err := m.applyStep(ctx, screen, step); err != nil {
+			if len(screens) > 1 {
+				err = fmt.Errorf("%s: %w", screen, err)
+			}
+			errs = append(errs, err)
+		}
+	}
+	return screens, errors.Join(errs...)
+}
+
+func (m *MediaLab) applyStep(ctx context.Context, screen Screen, step BatchStep) error {
+	var opts PlayOptions
+	if step.Quality != "" {
+		q, err := ParseQuality(step.Quality)
+		if err != nil {
+			return err
+		}
+		opts.Quality = &q
+	}
+
+	switch step.Action {
+	case "play":
+		url := step.URL
+		var err error
+		switch {
+		case url != "":
+		case step.Station != "":
+			var st Station
+			if st, err = m.Station(step.Station); err == nil {
+				url = st.URL
+			}
+		default:
+			url, err = m.ResolveQuery(ctx, step.Query)
+		}
+		if err != nil {
+			return err
+		}
+		if IsPlaylistURL(url) {
+			_, _, err = m.PlayPlaylist(ctx, url, PlaylistOptions{Quality: opts.Quality}, screen)
+		} else {
+			_, err = m.PlayWith(ctx, url, screen, opts)
+		}
+		return err
+	case "queue":
+		urls := append([]string(nil), step.URLs...)
+		if step.URL != "" {
+			urls = append(urls, step.URL)
+		}
+		if step.Query != "" {
+			url, err := m.ResolveQuery(ctx, step.Query)
+			if err != nil {
+				return err
+			}
+			urls = append(urls, url)
+		}
+		_, err := m.Enqueue(ctx, screen, urls, opts)
+		return err
+	case "stop":
+		return m.Stop(screen)
+	case "pause":
+		return m.Pause(screen)
+	case "resume":
+		return m.Resume(screen)
+	case "playpause":
+		return m.PlayPause(screen)
+	case "next":
+		return m.Next(screen)
+	case "prev":
+		return m.Prev(screen)
+	case "volume":
+		return m.SetVolume(screen, step.Volume)
+	case "mute":
+		return m.SetMute(screen, true)
+	case "unmute":
+		return m.SetMute(screen, false)
+	case "seek":
+		return m.Seek(screen, step.Position, step.Relative)
+	case "fullscreen":
+		return m.Fullscreen(screen)
+	case "speed":
+		return m.SetSpeed(screen, step.Speed, true)
+	case "loop-file":
+		return m.SetLoopFile(screen, step.Count)
+	case "loop-playlist":
+		return m.SetLoopPlaylist(screen, step.Count)
+	case "loop-off":
+		return m.ClearLoop(screen)
+	}
+	return fmt.Errorf("%w: unknown action %q", ErrInvalidArgument, step.Action)
+}
+
+// Macros returns the named macros: those in Config.MacroFile, overridden
+// by Config.Macros
+func (m *MediaLab) Macros() (map[string][]BatchStep, error) {
+	macros := make(map[string][]BatchStep)
+	if m.config.MacroFile != "" {
+		data, err := os.ReadFile(m.config.MacroFile)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, &macros); err != nil {
+				return nil, fmt.Errorf("parsing %s: %w", m.config.MacroFile, err)
+			}
+		}
+	}
+	for name, steps := range m.config.Macros {
+		macros[name] = steps
+	}
+	return macros, nil
+}
+
+// Macro returns the steps of the named macro
+func (m *MediaLab) Macro(name string) ([]BatchStep, error) {
+	macros, err := m.Macros()
+	if err != nil {
+		return nil, err
+	}
+	steps, ok := macros[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: macro %q", ErrNotFound, name)
+	}
+	return steps, nil
+}
+
+// RunMacro runs the named macro as a batch
+func (m *MediaLab) RunMacro(ctx context.Context, name string, opts BatchOptions) (*BatchResult, error) {
+	steps, err := m.Macro(name)
+	if err != nil {
+		return nil, err
+	}
+	return m.RunBatch(ctx, steps, opts)
+}
@@ -0,0 +1,204 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatchStepJSON(t *testing.T) {
+	var steps []BatchStep
+	err := json.Unmarshal([]byte(`[
+		{"action": "play", "screen": 2, "url": "https://example.com/a.mp4"},
+		{"action": "mute", "screen": "speaker"},
+		{"action": "fullscreen", "screen": "all"},
+		{"action": "volume", "screen": [1, "3"], "volume": 40},
+		{"action": "pause"}
+	]`), &steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if steps[0].Screens[0] != Screen2 || steps[0].URL == "" {
+		t.Errorf("step 1 = %+v", steps[0])
+	}
+	if steps[1].Screens[0] != ScreenSpeaker {
+		t.Errorf("step 2 screens = %v, want speaker", steps[1].Screens)
+	}
+	if !steps[2].AllScreens || len(steps[2].Screens) != 0 {
+		t.Errorf("step 3 = %+v, want all screens", steps[2])
+	}
+	if len(steps[3].Screens) != 2 || steps[3].Screens[1] != Screen3 || steps[3].Volume != 40 {
+		t.Errorf("step 4 = %+v", steps[3])
+	}
+	if len(steps[4].Screens) != 0 || steps[4].AllScreens {
+		t.Errorf("step 5 = %+v, want default screen", steps[4])
+	}
+
+	data, err := json.Marshal(steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"screen":2`, `"screen":"speaker"`, `"screen":"all"`, `"screen":[1,3]`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Marshal() = %s, missing %s", data, want)
+		}
+	}
+
+	var bad BatchStep
+	if err := json.Unmarshal([]byte(`{"action": "stop", "screen": 9}`), &bad); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("screen 9 error = %v, want ErrInvalidArgument", err)
+	}
+}
+
+func TestRunBatch(t *testing.T) {
+	lab := New(nil)
+	ctx := context.Background()
+
+	if _, err := lab.RunBatch(ctx, nil, BatchOptions{}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("empty batch error = %v", err)
+	}
+	invalid := []BatchStep{{Action: "wait", Seconds: 0.01}, {Action: "explode"}}
+	if _, err := lab.RunBatch(ctx, invalid, BatchOptions{}); !errors.Is(err, ErrInvalidArgument) || !strings.Contains(err.Error(), "step 2") {
+		t.Errorf("invalid step error = %v", err)
+	}
+
+	// No players are running, so every screen step fails with no_player
+	steps := []BatchStep{
+		{Action: "wait", Seconds: 0.01},
+		{Action: "pause", Screens: []Screen{Screen2}},
+		{Action: "volume", Screens: []Screen{Screen1, Screen3}, Volume: 20},
+	}
+	result, err := lab.RunBatch(ctx, steps, BatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success || result.Failed != 2 || result.Skipped != 0 || !result.Steps[0].Success {
+		t.Errorf("result = %+v, want steps 2 and 3 failed", result)
+	}
+	if res := result.Steps[1]; res.Code != "no_player" || res.Screens[0] != 2 {
+		t.Errorf("step 2 = %+v", res)
+	}
+
+	result, err = lab.RunBatch(ctx, steps[1:], BatchOptions{StopOnError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Failed != 1 || result.Skipped != 1 || !result.Steps[1].Skipped {
+		t.Errorf("stop on error result = %+v, want step 2 skipped", result)
+	}
+
+	result, err = lab.RunBatch(ctx, []BatchStep{{Action: "fullscreen", AllScreens: true}}, BatchOptions{Parallel: true})
+	if err != nil || result.Steps[0].Code != "no_player" {
+		t.Errorf("all screens with no players = %+v, %v", result, err)
+	}
+}
+
+func TestRunBatchPlayerOutlivesBatch(t *testing.T) {
+	lab := newTestLab(t)
+	lab.config.MPVBinary = fakeMPVBinary(t)
+	video := filepath.Join(t.TempDir(), "a.mp4")
+	os.WriteFile(video, nil, 0o644)
+	t.Cleanup(func() { lab.Stop(Screen2) })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result, err := lab.RunBatch(ctx, []BatchStep{{Action: "play", Screens: []Screen{Screen2}, URL: video}}, BatchOptions{})
+	cancel()
+	if err != nil || !result.Success {
+		t.Fatalf("RunBatch(play) = %+v, %v", result, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if !playerAlive(lab, Screen2) {
+		t.Error("the player died with the batch's context")
+	}
+}
+
+func TestBatchLoopCount(t *testing.T) {
+	lab := newTestLab(t)
+	player := newFakePlayer(t, lab, Screen1, nil)
+
+	var steps []BatchStep
+	if err := json.Unmarshal([]byte(`[
+		{"action": "loop-file", "screen": 1},
+		{"action": "loop-playlist", "screen": 1, "count": 0},
+		{"action": "stop", "screen": 1}
+	]`), &steps); err != nil {
+		t.Fatal(err)
+	}
+	if steps[0].Count != LoopInfinite || steps[1].Count != 0 || steps[2].Count != 0 {
+		t.Errorf("counts = %d, %d, %d; want infinite, off, none", steps[0].Count, steps[1].Count, steps[2].Count)
+	}
+	if data, _ := json.Marshal(steps); !strings.Contains(string(data), `"count":-1`) || !strings.Contains(string(data), `"count":0`) {
+		t.Errorf("Marshal() = %s, want both loop counts", data)
+	}
+
+	if _, err := lab.RunBatch(context.Background(), steps[:2], BatchOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := player.prop("loop-file"); got != "inf" {
+		t.Errorf("loop-file = %v, want inf", got)
+	}
+	if got := player.prop("loop-playlist"); got != "no" {
+		t.Errorf("loop-playlist = %v, want no", got)
+	}
+}
+
+func TestMacros(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MacroFile = filepath.Join(t.TempDir(), "macros.json")
+	cfg.Macros = map[string][]BatchStep{
+		"quiet": {{Action: "mute", AllScreens: true}},
+	}
+	lab := New(cfg)
+
+	if _, err := lab.Macro("quiet"); err != nil {
+		t.Errorf("Macro(quiet) without a macro file: %v", err)
+	}
+
+	file := `{"quiet": [{"action": "stop"}], "night": [{"action": "volume", "volume": 10, "screen": "all"}]}`
+	if err := os.WriteFile(cfg.MacroFile, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	macros, err := lab.Macros()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(macros) != 2 || macros["quiet"][0].Action != "mute" || !macros["night"][0].AllScreens {
+		t.Errorf("Macros() = %+v, want file macros with quiet from Config.Macros", macros)
+	}
+	if _, err := lab.Macro("missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Macro(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestV1Batch(t *testing.T) {
+	s := newAuthServer(AuthConfig{
+		Clients: []APIClient{
+			{Name: "remote", Token: "control-token", Scopes: []Scope{ScopeRead, ScopeControl}},
+		},
+	})
+	request := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/v1/batch", strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer control-token")
+		return serveRequest(s, r)
+	}
+
+	if w := request(`{"steps": [{"action": "play", "url": "https://example.com/a.mp4"}]}`); w.Code != http.StatusForbidden {
+		t.Errorf("play step without play scope = %d, want 403", w.Code)
+	}
+	w := request(`{"steps": [{"action": "pause", "screen": 2}, {"action": "stop", "screen": 2}], "stop_on_error": true}`)
+	body := decodeEnvelope(t, w)
+	if w.Code != http.StatusOK || body["success"] != false || body["skipped"] != 1.0 {
+		t.Errorf("batch = %d %v, want step 2 skipped", w.Code, body)
+	}
+	if w := request(`{"steps": []}`); w.Code != http.StatusBadRequest {
+		t.Errorf("empty batch = %d, want 400", w.Code)
+	}
+}
@@ -0,0 +1,177 @@
+package medialab
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxCacheEntries bounds the cache; the entries closest to expiry are
+// evicted first
+const maxCacheEntries = 512
+
+// Cache is a TTL cache held in memory and persisted as JSON so results
+// survive CLI invocations. A zero TTL disables it.
+type Cache struct {
+	path string
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	loaded  bool
+	hits    int64
+	misses  int64
+}
+
+type cacheEntry struct {
+	Value   json.RawMessage `json:"value"`
+	Expires time.Time       `json:"expires"`
+}
+
+// CacheStats reports cache usage
+type CacheStats struct {
+	Enabled    bool    `json:"enabled"`
+	Entries    int     `json:"entries"`
+	Hits       int64   `json:"hits"`
+	Misses     int64   `json:"misses"`
+	TTLSeconds float64 `json:"ttl_seconds"`
+	Path       string  `json:"path"`
+}
+
+// NewCache creates a cache persisted at path (empty = memory only)
+func NewCache(path string, ttl time.Duration) *Cache {
+	return &Cache{
+		path:    path,
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+	}
+}
+
+// Cache returns the search/resolution cache
+func (m *MediaLab) Cache() *Cache {
+	return m.cache
+}
+
+// Get decodes a live entry into v, reporting whether it was found
+func (c *Cache) Get(key string, v any) bool {
+	if c.ttl <= 0 {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+
+	entry, ok := c.entries[key]
+	if !ok || time.Now().After(entry.Expires) || json.Unmarshal(entry.Value, v) != nil {
+		c.misses++
+		return false
+	}
+	c.hits++
+	return true
+}
+
+// Set stores v under key for the cache TTL and persists the cache
+func (c *Cache) Set(key string, v any) {
+	if c.ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.loadLocked()
+
+	c.entries[key] = cacheEntry{Value: data, Expires: time.Now().Add(c.ttl)}
+	c.pruneLocked()
+	c.saveLocked()
+}
+
+// Clear drops every entry, in memory and on disk
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]cacheEntry)
+	c.loaded = true
+	if c.path != "" {
+		os.Remove(c.path)
+	}
+}
+
+// Stats reports cache usage
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.ttl > 0 {
+		c.loadLocked()
+	}
+	return CacheStats{
+		Enabled:    c.ttl > 0,
+		Entries:    len(c.entries),
+		Hits:       c.hits,
+		Misses:     c.misses,
+		TTLSeconds: c.ttl.Seconds(),
+		Path:       c.path,
+	}
+}
+
+func (c *Cache) loadLocked() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	if c.path == "" {
+		return
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+	var entries map[string]cacheEntry
+	if json.Unmarshal(data, &entries) == nil {
+		for k, e := range entries {
+			c.entries[k] = e
+		}
+	}
+	c.pruneLocked()
+}
+
+// pruneLocked drops expired entries, then the soonest-expiring ones while
+// over maxCacheEntries
+func (c *Cache) pruneLocked() {
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.Expires) {
+			delete(c.entries, k)
+		}
+	}
+	for len(c.entries) > maxCacheEntries {
+		var oldest string
+		for k, e := range c.entries {
+			if oldest == "" || e.Expires.Before(c.entries[oldest].Expires) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
+
+func (c *Cache) saveLocked() {
+	if c.path == "" {
+		return
+	}
+	data, err := json.Marshal(c.entries)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return
+	}
+	tmp := c.path + ".tmp"
+	if os.WriteFile(tmp, data, 0644) == nil {
+		os.Rename(tmp, c.path)
+	}
+}
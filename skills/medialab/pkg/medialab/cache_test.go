@@ -0,0 +1,88 @@
+package medialab
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCachePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.json")
+	NewCache(path, time.Hour).Set("key", []string{"a", "b"})
+
+	var got []string
+	c := NewCache(path, time.Hour)
+	if !c.Get("key", &got) || len(got) != 2 || got[1] != "b" {
+		t.Fatalf("Get() after reload = %v, want [a b]", got)
+	}
+	if c.Get("missing", &got) {
+		t.Error("Get(missing) = true, want false")
+	}
+	if stats := c.Stats(); stats.Hits != 1 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("Stats() = %+v, want 1 hit, 1 miss, 1 entry", stats)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c := NewCache("", 10*time.Millisecond)
+	c.Set("key", 1)
+	time.Sleep(20 * time.Millisecond)
+
+	var v int
+	if c.Get("key", &v) {
+		t.Error("Get() returned an expired entry")
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	c := NewCache("", 0)
+	c.Set("key", 1)
+
+	var v int
+	if c.Get("key", &v) {
+		t.Error("Get() hit with caching disabled")
+	}
+	if c.Stats().Enabled {
+		t.Error("Stats().Enabled = true, want false")
+	}
+}
+
+type countingProvider struct {
+	calls int
+}
+
+func (p *countingProvider) Name() string { return "counting" }
+
+func (p *countingProvider) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	p.calls++
+	results := make([]SearchResult, opts.MaxResults)
+	for i := range results {
+		results[i] = SearchResult{Provider: "counting", ID: query, Title: query}
+	}
+	return results, nil
+}
+
+func TestSearchUsesCache(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CacheFile = filepath.Join(t.TempDir(), "cache.json")
+	cfg.LastSearchFile = ""
+	lab := New(cfg)
+	p := &countingProvider{}
+	lab.RegisterSearchProvider(p)
+	ctx := context.Background()
+
+	lab.Search(ctx, "counting", "lofi beats", SearchOptions{MaxResults: 5})
+	// Same words, smaller limit: served from the cache
+	results, _ := lab.Search(ctx, "counting", "  Lofi   beats", SearchOptions{MaxResults: 1})
+	if p.calls != 1 || len(results) != 1 {
+		t.Errorf("after repeat: calls = %d, results = %d, want 1 and 1", p.calls, len(results))
+	}
+
+	// Larger limit or different filters need a fresh search
+	lab.Search(ctx, "counting", "lofi beats", SearchOptions{MaxResults: 10})
+	lab.Search(ctx, "counting", "lofi beats", SearchOptions{MaxResults: 5, ExcludeLive: true})
+	if p.calls != 3 {
+		t.Errorf("calls = %d, want 3", p.calls)
+	}
+}
@@ -0,0 +1,186 @@
+package medialab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ClipOptions describes a time range to export to a local file
+type ClipOptions struct {
+	URL    string  // Source URL/file (empty = media currently loaded on Screen)
+	Screen Screen  // Screen to read the current media and position from
+	Start  float64 // Range start in seconds
+	End    float64 // Range end in seconds
+	Last   float64 // If > 0, export the last N seconds up to the current position
+	Output string  // Output file (default: generated in Config.ClipDir)
+}
+
+// ClipResult describes an exported clip
+type ClipResult struct {
+	Source   string  `json:"source"`
+	Output   string  `json:"output"`
+	Start    float64 `json:"start"`
+	End      float64 `json:"end"`
+	Duration float64 `json:"duration"`
+	Method   string  `json:"method"`
+}
+
+// ExportClip cuts a time range of a URL/file, or of what is currently loaded
+// on a screen, to a local file.
+//
+// Method selection:
+//   - "cache":  Last on a running screen dumps mpv's demuxer cache (works for live streams)
+//   - "yt-dlp": remote URLs use yt-dlp --download-sections (needs ffmpeg)
+//   - "mpv":    local files are re-encoded with mpv --start/--end --o
+func (m *MediaLab) ExportClip(ctx context.Context, opts ClipOptions) (*ClipResult, error) {
+	source := opts.URL
+	fromScreen := source == ""
+	if fromScreen {
+		path, err := m.GetProperty(opts.Screen, "path")
+		if err != nil {
+			return nil, fmt.Errorf("no media loaded on %s: %w", opts.Screen, err)
+		}
+		source, _ = path.(string)
+		if source == "" {
+			return nil, fmt.Errorf("no media loaded on %s", opts.Screen)
+		}
+	}
+
+	start, end := opts.Start, opts.End
+	if opts.Last > 0 {
+		if !fromScreen {
+			return nil, fmt.Errorf("%w: last requires the current media of a screen, not a URL", ErrInvalidArgument)
+		}
+		val, err := m.GetProperty(opts.Screen, "time-pos")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get position: %w", err)
+		}
+		pos, _ := val.(float64)
+		end = pos
+		start = pos - opts.Last
+		if start < 0 {
+			start = 0
+		}
+	}
+	if end <= start {
+		return nil, fmt.Errorf("%w: end (%.1f) must be after start (%.1f)", ErrInvalidArgument, end, start)
+	}
+
+	output := opts.Output
+	if output == "" {
+		output = filepath.Join(m.config.ClipDir, "clip-"+time.Now().Format("20060102-150405")+".mkv")
+	}
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	result := &ClipResult{
+		Source:   source,
+		Output:   output,
+		Start:    start,
+		End:      end,
+		Duration: end - start,
+	}
+
+	var err error
+	switch {
+	case fromScreen && opts.Last > 0:
+		result.Method = "cache"
+		err = m.dumpCache(opts.Screen, start, end, output)
+	case isRemoteURL(source):
+		result.Method = "yt-dlp"
+		err = m.clipWithYTDLP(ctx, source, start, end, output)
+	default:
+		result.Method = "mpv"
+		err = m.clipWithMPV(ctx, source, start, end, output)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (m *MediaLab) dumpCache(screen Screen, start, end float64, output string) error {
+	resp, err := m.IPCCommand(screen, map[string]any{"command": []any{"dump-cache", start, end, output}})
+	if err != nil {
+		return fmt.Errorf("dump-cache failed: %w", err)
+	}
+	if err := checkIPCResponse(resp); err != nil {
+		return fmt.Errorf("dump-cache failed: %w", err)
+	}
+	return nil
+}
+
+func (m *MediaLab) clipWithYTDLP(ctx context.Context, url string, start, end float64, output string) error {
+	args := []string{
+		"--download-sections", "*" + formatSeconds(start) + "-" + formatSeconds(end),
+		"--force-keyframes-at-cuts",
+		"--no-playlist",
+		"--quiet",
+		"-o", output,
+	}
+	if ext := strings.TrimPrefix(filepath.Ext(output), "."); ext != "" {
+		args = append(args, "--merge-output-format", ext)
+	}
+	args = append(args, "--", url)
+
+	cmd := exec.CommandContext(ctx, m.config.YTDLPBinary, args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("yt-dlp clip failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func (m *MediaLab) clipWithMPV(ctx context.Context, path string, start, end float64, output string) error {
+	args := []string{
+		"--no-config",
+		"--no-terminal",
+		"--start=" + formatSeconds(start),
+		"--end=" + formatSeconds(end),
+		"--o=" + output,
+		"--", path,
+	}
+	cmd := exec.CommandContext(ctx, m.config.MPVBinary, args...)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("mpv encode failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func isRemoteURL(s string) bool {
+	return strings.Contains(s, "://") && !strings.HasPrefix(s, "file://")
+}
+
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'f', -1, 64)
+}
+
+// ParseTimestamp parses "SS", "MM:SS" or "HH:MM:SS" (fractional seconds allowed)
+func ParseTimestamp(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("empty timestamp")
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) > 3 {
+		return 0, fmt.Errorf("invalid timestamp: %s", s)
+	}
+	var total float64
+	for _, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		// ParseFloat also accepts inf, nan and overflowing exponents
+		if err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return 0, fmt.Errorf("invalid timestamp: %s", s)
+		}
+		total = total*60 + v
+	}
+	return total, nil
+}
@@ -0,0 +1,57 @@
+package medialab
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	loc := time.UTC
+	// Thursday 2026-10-15 08:30
+	from := time.Date(2026, 10, 15, 8, 30, 0, 0, loc)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 9 * * mon-fri", time.Date(2026, 10, 15, 9, 0, 0, 0, loc)},
+		{"30 8 * * *", time.Date(2026, 10, 16, 8, 30, 0, 0, loc)}, // strictly after from
+		{"*/20 * * * *", time.Date(2026, 10, 15, 8, 40, 0, 0, loc)},
+		{"0 22 * * sat,sun", time.Date(2026, 10, 17, 22, 0, 0, 0, loc)},
+		{"0 12 * * 7", time.Date(2026, 10, 18, 12, 0, 0, 0, loc)},
+		{"0 0 1 jan *", time.Date(2027, 1, 1, 0, 0, 0, 0, loc)},
+		{"@daily", time.Date(2026, 10, 16, 0, 0, 0, 0, loc)},
+		{"@hourly", time.Date(2026, 10, 15, 9, 0, 0, 0, loc)},
+		{"15 10-12/2 * * *", time.Date(2026, 10, 15, 10, 15, 0, 0, loc)},
+		// Day of month and weekday both restricted: either matches
+		{"0 0 20 * fri", time.Date(2026, 10, 16, 0, 0, 0, 0, loc)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		spec, err := parseCron(tt.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q) error = %v", tt.expr, err)
+			continue
+		}
+		if got := spec.next(from); !got.Equal(tt.want) {
+			t.Errorf("next(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+
+	spec, _ := parseCron("0 0 31 2 *")
+	if got := spec.next(from); !got.IsZero() {
+		t.Errorf("next(Feb 31) = %s, want zero", got)
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	for _, expr := range []string{
+		"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *",
+		"* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "* * * foo *", "@often",
+	} {
+		if _, err := parseCron(expr); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("parseCron(%q) error = %v, want ErrInvalidArgument", expr, err)
+		}
+	}
+}
@@ -0,0 +1,570 @@
+package medialab
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DownloadState is the lifecycle state of a download job
+type DownloadState string
+
+const (
+	DownloadQueued      DownloadState = "queued"
+	DownloadDownloading DownloadState = "downloading"
+	DownloadDone        DownloadState = "done"
+	DownloadFailed      DownloadState = "failed"
+	DownloadCancelled   DownloadState = "cancelled"
+)
+
+// DownloadJob is a snapshot of a download
+type DownloadJob struct {
+	ID         string        `json:"id"`
+	URL        string        `json:"url"`
+	State      DownloadState `json:"state"`
+	Progress   float64       `json:"progress"` // percent, 0-100
+	Downloaded int64         `json:"downloaded"`
+	Total      int64         `json:"total,omitempty"` // bytes, 0 = unknown
+	Speed      float64       `json:"speed,omitempty"` // bytes/s
+	ETA        int           `json:"eta,omitempty"`   // seconds
+	Path       string        `json:"path,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Created    time.Time     `json:"created"`
+	Finished   time.Time     `json:"finished,omitempty"`
+}
+
+// CachedMedia is a completed download in the offline cache
+type CachedMedia struct {
+	URL      string    `json:"url"`
+	Path     string    `json:"path"`
+	Quality  string    `json:"quality,omitempty"` // preset downloaded, as Quality.String
+	Size     int64     `json:"size"`
+	Added    time.Time `json:"added"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// DownloadStats summarizes the offline cache
+type DownloadStats struct {
+	Dir    string `json:"dir"`
+	Files  int    `json:"files"`
+	Usage  int64  `json:"usage"`
+	Quota  int64  `json:"quota"`
+	Active int    `json:"active"`
+}
+
+// DownloadManager fetches media with yt-dlp into an on-disk cache that Play
+// prefers over streaming when the cached quality is the one requested.
+// Each URL keeps one file; downloading it at another quality replaces it.
+// Completed files are evicted least recently used first once the cache
+// exceeds its quota.
+type DownloadManager struct {
+	dir   string
+	quota int64
+	ytdlp string
+	slots chan struct{} // concurrency limit
+
+	mu     sync.Mutex
+	jobs   map[string]*downloadJob
+	nextID int
+	cache  map[string]*CachedMedia // by cacheKey
+	loaded bool
+}
+
+type downloadJob struct {
+	DownloadJob
+	key     string
+	quality *Quality
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// NewDownloadManager creates a download manager storing files under dir
+func NewDownloadManager(dir string, concurrency int, quota int64, ytdlp string) *DownloadManager {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &DownloadManager{
+		dir:   dir,
+		quota: quota,
+		ytdlp: ytdlp,
+		slots: make(chan struct{}, concurrency),
+		jobs:  make(map[string]*downloadJob),
+		cache: make(map[string]*CachedMedia),
+	}
+}
+
+// Downloads returns the download manager
+func (m *MediaLab) Downloads() *DownloadManager {
+	return m.downloads
+}
+
+// cacheKey identifies media independently of URL spelling where possible
+func cacheKey(rawURL string) string {
+	if id := youtubeVideoID(rawURL); id != "" {
+		return "youtube:" + id
+	}
+	return strings.TrimSpace(rawURL)
+}
+
+// qualityName is the preset a download of quality fetches; nil and the
+// zero Quality both fetch yt-dlp's best
+func qualityName(quality *Quality) string {
+	if quality == nil {
+		return Quality{}.String()
+	}
+	return quality.String()
+}
+
+// matches reports whether the cached file has quality; nil accepts any
+func (c *CachedMedia) matches(quality *Quality) bool {
+	if quality == nil {
+		return true
+	}
+	cached := c.Quality
+	if cached == "" {
+		cached = qualityName(nil) // indexes written before qualities were kept
+	}
+	return cached == quality.String()
+}
+
+// youtubeVideoID extracts the video ID from watch, youtu.be and shorts URLs
+func youtubeVideoID(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	var id string
+	switch {
+	case strings.HasSuffix(u.Host, "youtu.be"):
+		id = strings.Trim(u.Path, "/")
+	case strings.Contains(u.Host, "youtube.com") && u.Path == "/watch":
+		id = u.Query().Get("v")
+	case strings.Contains(u.Host, "youtube.com") && strings.HasPrefix(u.Path, "/shorts/"):
+		id = strings.TrimPrefix(u.Path, "/shorts/")
+	}
+	if !youtubeID.MatchString(id) {
+		return ""
+	}
+	return id
+}
+
+func (d *DownloadManager) indexPath() string {
+	return filepath.Join(d.dir, "index.json")
+}
+
+// loadLocked reads the cache index, dropping entries whose files are gone
+func (d *DownloadManager) loadLocked() {
+	if d.loaded {
+		return
+	}
+	d.loaded = true
+	data, err := os.ReadFile(d.indexPath())
+	if err != nil {
+		return
+	}
+	var cache map[string]*CachedMedia
+	if json.Unmarshal(data, &cache) != nil {
+		return
+	}
+	for key, c := range cache {
+		if _, err := os.Stat(c.Path); err == nil {
+			d.cache[key] = c
+		}
+	}
+}
+
+func (d *DownloadManager) saveLocked() error {
+	data, err := json.MarshalIndent(d.cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return fmt.Errorf("failed to create download directory: %w", err)
+	}
+	tmp := d.indexPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write download index: %w", err)
+	}
+	return os.Rename(tmp, d.indexPath())
+}
+
+// Lookup returns the cached file for url at quality, marking it as
+// recently used. A nil quality accepts a file of any quality.
+func (d *DownloadManager) Lookup(rawURL string, quality *Quality) (string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loadLocked()
+
+	path, ok, changed := d.lookupLocked(rawURL, quality, time.Now())
+	if changed {
+		d.saveLocked()
+	}
+	return path, ok
+}
+
+// Resolve returns urls with the ones cached at quality replaced by their
+// files, marking those as recently used. The index is written once for
+// all of them.
+func (d *DownloadManager) Resolve(urls []string, quality *Quality) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loadLocked()
+
+	now := time.Now()
+	targets := make([]string, len(urls))
+	save := false
+	for i, rawURL := range urls {
+		targets[i] = rawURL
+		path, ok, changed := d.lookupLocked(rawURL, quality, now)
+		if ok {
+			targets[i] = path
+		}
+		save = save || changed
+	}
+	if save {
+		d.saveLocked()
+	}
+	return targets
+}
+
+// lookupLocked finds the cached file for url at quality, dropping entries
+// whose file is gone; changed reports whether the index needs saving
+func (d *DownloadManager) lookupLocked(rawURL string, quality *Quality, now time.Time) (path string, ok, changed bool) {
+	c, ok := d.cache[cacheKey(rawURL)]
+	if !ok || !c.matches(quality) {
+		return "", false, false
+	}
+	if _, err := os.Stat(c.Path); err != nil {
+		delete(d.cache, cacheKey(rawURL))
+		return "", false, true
+	}
+	c.LastUsed = now
+	return c.Path, true, true
+}
+
+// Start queues a download of url, returning the existing job if the URL is
+// already downloading at quality and a completed job if it is already
+// cached at quality
+func (d *DownloadManager) Start(rawURL string, quality *Quality) (DownloadJob, error) {
+	if rawURL == "" {
+		return DownloadJob{}, fmt.Errorf("%w: url required", ErrInvalidArgument)
+	}
+	key := cacheKey(rawURL)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loadLocked()
+
+	for _, job := range d.jobs {
+		if job.key == key && qualityName(job.quality) == qualityName(quality) && (job.State == DownloadQueued || job.State == DownloadDownloading) {
+			return job.DownloadJob, nil
+		}
+	}
+
+	d.nextID++
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &downloadJob{
+		DownloadJob: DownloadJob{
+			ID:      strconv.Itoa(d.nextID),
+			URL:     rawURL,
+			State:   DownloadQueued,
+			Created: time.Now(),
+		},
+		key:     key,
+		quality: quality,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	d.jobs[job.ID] = job
+
+	want := quality
+	if want == nil {
+		want = &Quality{}
+	}
+	if c, ok := d.cache[key]; ok && c.matches(want) {
+		job.State = DownloadDone
+		job.Progress = 100
+		job.Path = c.Path
+		job.Downloaded = c.Size
+		job.Total = c.Size
+		job.Finished = job.Created
+		close(job.done)
+		cancel()
+		return job.DownloadJob, nil
+	}
+
+	go d.run(ctx, job)
+	return job.DownloadJob, nil
+}
+
+// Jobs returns all download jobs of this session, newest first
+func (d *DownloadManager) Jobs() []DownloadJob {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	jobs := make([]DownloadJob, 0, len(d.jobs))
+	for _, job := range d.jobs {
+		jobs = append(jobs, job.DownloadJob)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		a, _ := strconv.Atoi(jobs[i].ID)
+		b, _ := strconv.Atoi(jobs[j].ID)
+		return a > b
+	})
+	return jobs
+}
+
+// Job returns a snapshot of a download job
+func (d *DownloadManager) Job(id string) (DownloadJob, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	job, ok := d.jobs[id]
+	if !ok {
+		return DownloadJob{}, fmt.Errorf("%w: download %s", ErrNotFound, id)
+	}
+	return job.DownloadJob, nil
+}
+
+// Cancel stops a queued or running download and discards partial data
+func (d *DownloadManager) Cancel(id string) error {
+	d.mu.Lock()
+	job, ok := d.jobs[id]
+	d.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: download %s", ErrNotFound, id)
+	}
+	job.cancel()
+	return nil
+}
+
+// Wait blocks until a download finishes or ctx is done
+func (d *DownloadManager) Wait(ctx context.Context, id string) (DownloadJob, error) {
+	d.mu.Lock()
+	job, ok := d.jobs[id]
+	d.mu.Unlock()
+	if !ok {
+		return DownloadJob{}, fmt.Errorf("%w: download %s", ErrNotFound, id)
+	}
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+		return DownloadJob{}, ctx.Err()
+	}
+	return d.Job(id)
+}
+
+// Cached returns the cached files, most recently used first
+func (d *DownloadManager) Cached() []CachedMedia {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loadLocked()
+	cached := make([]CachedMedia, 0, len(d.cache))
+	for _, c := range d.cache {
+		cached = append(cached, *c)
+	}
+	sort.Slice(cached, func(i, j int) bool { return cached[i].LastUsed.After(cached[j].LastUsed) })
+	return cached
+}
+
+// Remove deletes the cached file for url
+func (d *DownloadManager) Remove(rawURL string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loadLocked()
+	key := cacheKey(rawURL)
+	c, ok := d.cache[key]
+	if !ok {
+		return fmt.Errorf("%w: %s is not cached", ErrNotFound, rawURL)
+	}
+	os.Remove(c.Path)
+	delete(d.cache, key)
+	return d.saveLocked()
+}
+
+// Stats summarizes the offline cache
+func (d *DownloadManager) Stats() DownloadStats {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loadLocked()
+	stats := DownloadStats{Dir: d.dir, Files: len(d.cache), Quota: d.quota}
+	for _, c := range d.cache {
+		stats.Usage += c.Size
+	}
+	for _, job := range d.jobs {
+		if job.State == DownloadQueued || job.State == DownloadDownloading {
+			stats.Active++
+		}
+	}
+	return stats
+}
+
+func (d *DownloadManager) update(job *downloadJob, fn func(*DownloadJob)) {
+	d.mu.Lock()
+	fn(&job.DownloadJob)
+	d.mu.Unlock()
+}
+
+func (d *DownloadManager) run(ctx context.Context, job *downloadJob) {
+	defer close(job.done)
+	defer job.cancel()
+
+	select {
+	case d.slots <- struct{}{}:
+		defer func() { <-d.slots }()
+	case <-ctx.Done():
+		d.update(job, func(j *DownloadJob) {
+			j.State = DownloadCancelled
+			j.Finished = time.Now()
+		})
+		return
+	}
+	d.update(job, func(j *DownloadJob) { j.State = DownloadDownloading })
+
+	path, err := d.fetch(ctx, job)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	job.Finished = time.Now()
+	switch {
+	case ctx.Err() != nil:
+		job.State = DownloadCancelled
+	case err != nil:
+		job.State = DownloadFailed
+		job.Error = err.Error()
+	default:
+		job.State = DownloadDone
+		job.Progress = 100
+		job.Path = path
+		d.addLocked(job, path)
+	}
+}
+
+// fetch runs yt-dlp into a per-job temporary directory, then moves the
+// finished file into the cache directory
+func (d *DownloadManager) fetch(ctx context.Context, job *downloadJob) (string, error) {
+	tmpDir := filepath.Join(d.dir, ".partial", job.ID)
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create download directory: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	args := []string{
+		"--no-playlist",
+		"--newline",
+		"--progress",
+		"--progress-template", "download:progress %(progress.downloaded_bytes)s %(progress.total_bytes)s %(progress.total_bytes_estimate)s %(progress.speed)s %(progress.eta)s",
+		"--print", "after_move:file %(filepath)s",
+		"-o", filepath.Join(tmpDir, "%(extractor)s-%(id)s.%(ext)s"),
+	}
+	if job.quality != nil {
+		args = append(args, job.quality.ytdlpArgs()...)
+	}
+	args = append(args, "--", job.URL)
+
+	cmd := exec.CommandContext(ctx, d.ytdlp, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", err
+	}
+	cmd.Stderr = cmd.Stdout // progress goes to stderr in quiet mode
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("failed to start yt-dlp: %w", err)
+	}
+
+	var file string
+	var lastLine string
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "progress "):
+			d.update(job, func(j *DownloadJob) { parseProgress(j, strings.Fields(line)[1:]) })
+		case strings.HasPrefix(line, "file "):
+			file = strings.TrimPrefix(line, "file ")
+		case strings.TrimSpace(line) != "":
+			lastLine = line
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return "", fmt.Errorf("yt-dlp download failed: %w: %s", err, lastLine)
+	}
+	if file == "" {
+		return "", errors.New("yt-dlp did not report the downloaded file")
+	}
+
+	dest := filepath.Join(d.dir, filepath.Base(file))
+	if err := os.Rename(file, dest); err != nil {
+		return "", fmt.Errorf("failed to move download into cache: %w", err)
+	}
+	return dest, nil
+}
+
+// parseProgress applies a progress line: downloaded, total, total estimate,
+// speed and ETA, with "NA" for unknown values
+func parseProgress(job *DownloadJob, fields []string) {
+	num := func(i int) float64 {
+		if i >= len(fields) {
+			return 0
+		}
+		v, _ := strconv.ParseFloat(fields[i], 64)
+		return v
+	}
+	job.Downloaded = int64(num(0))
+	job.Total = int64(num(1))
+	if job.Total == 0 {
+		job.Total = int64(num(2))
+	}
+	job.Speed = num(3)
+	job.ETA = int(num(4))
+	if job.Total > 0 {
+		job.Progress = float64(job.Downloaded) / float64(job.Total) * 100
+	}
+}
+
+// addLocked records a finished download and evicts least recently used
+// files until the cache fits the quota. The new file itself is kept.
+func (d *DownloadManager) addLocked(job *downloadJob, path string) {
+	d.loadLocked()
+	var size int64
+	if fi, err := os.Stat(path); err == nil {
+		size = fi.Size()
+	}
+	if old, ok := d.cache[job.key]; ok && old.Path != path {
+		os.Remove(old.Path) // a copy at another quality
+	}
+	now := time.Now()
+	d.cache[job.key] = &CachedMedia{URL: job.URL, Path: path, Quality: qualityName(job.quality), Size: size, Added: now, LastUsed: now}
+	job.Downloaded, job.Total = size, size
+
+	if d.quota > 0 {
+		var usage int64
+		entries := make([]string, 0, len(d.cache))
+		for key, c := range d.cache {
+			usage += c.Size
+			if key != job.key {
+				entries = append(entries, key)
+			}
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return d.cache[entries[i]].LastUsed.Before(d.cache[entries[j]].LastUsed)
+		})
+		for _, key := range entries {
+			if usage <= d.quota {
+				break
+			}
+			usage -= d.cache[key].Size
+			os.Remove(d.cache[key].Path)
+			delete(d.cache, key)
+		}
+	}
+	d.saveLocked()
+}
@@ -0,0 +1,214 @@
+package medialab
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeYTDLP writes a script that mimics yt-dlp's download output: a progress
+// line, a 1000-byte file named after the URL's last path element and the
+// after_move path. URLs containing "fail" exit 1, "slow" ones hang.
+func fakeYTDLP(t *testing.T) string {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "yt-dlp")
+	os.WriteFile(script, []byte(`#!/bin/sh
+out=""
+while [ $# -gt 0 ]; do
+	case "$1" in
+		-o) out="$2"; shift ;;
+	esac
+	url="$1"
+	shift
+done
+case "$url" in
+	*fail*) echo "ERROR: unavailable"; exit 1 ;;
+	*slow*) exec sleep 10 ;;
+esac
+path="$(dirname "$out")/$(basename "$url").mp4"
+echo "progress 500 1000 NA 2048 1"
+head -c 1000 /dev/zero > "$path"
+echo "file $path"
+`), 0755)
+	return script
+}
+
+func newTestDownloads(t *testing.T, quota int64) *DownloadManager {
+	t.Helper()
+	return NewDownloadManager(t.TempDir(), 2, quota, fakeYTDLP(t))
+}
+
+func waitDownload(t *testing.T, d *DownloadManager, url string) DownloadJob {
+	t.Helper()
+	job, err := d.Start(url, nil)
+	if err != nil {
+		t.Fatalf("Start(%s) error = %v", url, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, err = d.Wait(ctx, job.ID)
+	if err != nil {
+		t.Fatalf("Wait(%s) error = %v", url, err)
+	}
+	return job
+}
+
+func TestDownloadAndLookup(t *testing.T) {
+	d := newTestDownloads(t, 0)
+	job := waitDownload(t, d, "https://example.com/a")
+	if job.State != DownloadDone || job.Progress != 100 || job.Total != 1000 {
+		t.Fatalf("job = %+v, want done with 1000 bytes", job)
+	}
+
+	path, ok := d.Lookup("https://example.com/a", nil)
+	if !ok || path != job.Path {
+		t.Fatalf("Lookup() = %q, %v; want %q", path, ok, job.Path)
+	}
+	if _, err := os.Stat(filepath.Join(d.dir, ".partial", job.ID)); !os.IsNotExist(err) {
+		t.Error("partial directory not cleaned up")
+	}
+
+	// Already cached: completes immediately
+	again, _ := d.Start("https://example.com/a", nil)
+	if again.State != DownloadDone || again.Path != job.Path {
+		t.Errorf("Start(cached) = %+v, want done", again)
+	}
+
+	// The index survives a restart
+	reloaded := NewDownloadManager(d.dir, 1, 0, d.ytdlp)
+	if path, ok := reloaded.Lookup("https://example.com/a", nil); !ok || path != job.Path {
+		t.Errorf("Lookup() after reload = %q, %v", path, ok)
+	}
+}
+
+func TestDownloadResolve(t *testing.T) {
+	d := newTestDownloads(t, 0)
+	a := waitDownload(t, d, "https://example.com/a")
+	b := waitDownload(t, d, "https://example.com/b")
+	before := time.Now()
+
+	got := d.Resolve([]string{"https://example.com/a", "https://example.com/x", "https://example.com/b"}, nil)
+	want := []string{a.Path, "https://example.com/x", b.Path}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Resolve() = %v, want %v", got, want)
+	}
+
+	// Both marks reach the index
+	for _, c := range NewDownloadManager(d.dir, 1, 0, d.ytdlp).Cached() {
+		if c.LastUsed.Before(before) {
+			t.Errorf("%s last used %v, want after %v", c.URL, c.LastUsed, before)
+		}
+	}
+}
+
+func TestDownloadQuality(t *testing.T) {
+	d := newTestDownloads(t, 0)
+	audio := Quality{AudioOnly: true}
+	job, _ := d.Start("https://example.com/a", &audio)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if job, _ = d.Wait(ctx, job.ID); job.State != DownloadDone {
+		t.Fatalf("audio download = %+v", job)
+	}
+
+	if _, ok := d.Lookup("https://example.com/a", &Quality{}); ok {
+		t.Error("audio-only copy used for a video request")
+	}
+	if path, ok := d.Lookup("https://example.com/a", &audio); !ok || path != job.Path {
+		t.Errorf("Lookup(audio) = %q, %v; want %q", path, ok, job.Path)
+	}
+	if _, ok := d.Lookup("https://example.com/a", nil); !ok {
+		t.Error("Lookup(any quality) missed the audio copy")
+	}
+	if got := d.Resolve([]string{"https://example.com/a"}, &Quality{MaxHeight: 720}); got[0] != "https://example.com/a" {
+		t.Errorf("Resolve(720p) = %v, want the URL streamed", got)
+	}
+
+	// Downloading it at the default quality replaces the audio copy
+	if best := waitDownload(t, d, "https://example.com/a"); best.ID == job.ID {
+		t.Fatalf("Start(best) reused the audio download %+v", best)
+	}
+	if cached := d.Cached(); len(cached) != 1 || cached[0].Quality != "best" {
+		t.Errorf("Cached() = %+v, want one best copy", cached)
+	}
+	if _, ok := d.Lookup("https://example.com/a", &Quality{}); !ok {
+		t.Error("best copy not used for a default request")
+	}
+}
+
+func TestDownloadEviction(t *testing.T) {
+	d := newTestDownloads(t, 2500)
+	waitDownload(t, d, "https://example.com/a")
+	waitDownload(t, d, "https://example.com/b")
+	d.Lookup("https://example.com/a", nil) // b is now least recently used
+	waitDownload(t, d, "https://example.com/c")
+
+	if _, ok := d.Lookup("https://example.com/b", nil); ok {
+		t.Error("least recently used download was not evicted")
+	}
+	for _, url := range []string{"https://example.com/a", "https://example.com/c"} {
+		if _, ok := d.Lookup(url, nil); !ok {
+			t.Errorf("%s evicted, want kept", url)
+		}
+	}
+	if stats := d.Stats(); stats.Usage != 2000 || stats.Files != 2 {
+		t.Errorf("Stats() = %+v, want 2 files, 2000 bytes", stats)
+	}
+}
+
+func TestDownloadFailure(t *testing.T) {
+	d := newTestDownloads(t, 0)
+	job := waitDownload(t, d, "https://example.com/fail")
+	if job.State != DownloadFailed || job.Error == "" {
+		t.Errorf("job = %+v, want failed with an error", job)
+	}
+}
+
+func TestDownloadCancel(t *testing.T) {
+	d := newTestDownloads(t, 0)
+	job, _ := d.Start("https://example.com/slow", nil)
+	for job.State == DownloadQueued {
+		time.Sleep(10 * time.Millisecond)
+		job, _ = d.Job(job.ID)
+	}
+	if err := d.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, _ = d.Wait(ctx, job.ID)
+	if job.State != DownloadCancelled {
+		t.Errorf("State = %s, want cancelled", job.State)
+	}
+	if _, err := d.Job("999"); err == nil {
+		t.Error("Job(unknown) = nil error, want ErrNotFound")
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	want := "youtube:dQw4w9WgXcQ"
+	for _, url := range []string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://youtube.com/watch?v=dQw4w9WgXcQ&t=42",
+		"https://youtu.be/dQw4w9WgXcQ",
+		"https://www.youtube.com/shorts/dQw4w9WgXcQ",
+	} {
+		if got := cacheKey(url); got != want {
+			t.Errorf("cacheKey(%q) = %q, want %q", url, got, want)
+		}
+	}
+	if got := cacheKey("https://example.com/video.mp4"); got != "https://example.com/video.mp4" {
+		t.Errorf("cacheKey(other) = %q", got)
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	var job DownloadJob
+	parseProgress(&job, []string{"250", "NA", "1000", "512.5", "3"})
+	if job.Downloaded != 250 || job.Total != 1000 || job.Progress != 25 || job.Speed != 512.5 || job.ETA != 3 {
+		t.Errorf("parseProgress() = %+v", job)
+	}
+}
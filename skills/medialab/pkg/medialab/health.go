@@ -0,0 +1,325 @@
+package medialab
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckStatus is the outcome of a health check; a report takes the worst
+// status of its checks
+type CheckStatus string
+
+const (
+	CheckOK   CheckStatus = "ok"
+	CheckWarn CheckStatus = "warn" // degraded: some features will not work
+	CheckFail CheckStatus = "fail" // playback will not work
+)
+
+func (s CheckStatus) rank() int {
+	switch s {
+	case CheckWarn:
+		return 1
+	case CheckFail:
+		return 2
+	}
+	return 0
+}
+
+// HealthCheck is the result of one check
+type HealthCheck struct {
+	Name       string      `json:"name"`
+	Status     CheckStatus `json:"status"`
+	Message    string      `json:"message"`
+	Version    string      `json:"version,omitempty"` // of binaries
+	DurationMS int64       `json:"duration_ms"`
+}
+
+// HealthReport is the result of CheckHealth
+type HealthReport struct {
+	Status CheckStatus   `json:"status"`
+	Ready  bool          `json:"ready"` // no check failed
+	Time   time.Time     `json:"time"`
+	Checks []HealthCheck `json:"checks"`
+}
+
+// healthCheckTimeout bounds each check, so one hung binary or socket
+// cannot stall the report
+const healthCheckTimeout = 5 * time.Second
+
+// CheckHealth verifies what playback depends on: the mpv, yt-dlp and
+// playerctl binaries, each screen's IPC socket, the display server, the
+// screen profiles in mpv.conf and the audio devices. Checks run
+// concurrently.
+func (m *MediaLab) CheckHealth(ctx context.Context) *HealthReport {
+	checks := []struct {
+		name string
+		run  func(ctx context.Context) HealthCheck
+	}{
+		{"mpv", func(ctx context.Context) HealthCheck {
+			return m.cachedCheck(ctx, "mpv "+m.config.MPVBinary, func(ctx context.Context) HealthCheck {
+				return checkBinary(ctx, m.config.MPVBinary, CheckFail)
+			})
+		}},
+		{"yt-dlp", func(ctx context.Context) HealthCheck {
+			return m.cachedCheck(ctx, "yt-dlp "+m.config.YTDLPBinary, func(ctx context.Context) HealthCheck {
+				return checkBinary(ctx, m.config.YTDLPBinary, CheckFail)
+			})
+		}},
+		// playerctl only backs the generic MPRIS fallback
+		{"playerctl", func(ctx context.Context) HealthCheck {
+			return m.cachedCheck(ctx, "playerctl "+m.config.PlayerctlPath, func(ctx context.Context) HealthCheck {
+				return checkBinary(ctx, m.config.PlayerctlPath, CheckWarn)
+			})
+		}},
+		{"sockets", m.checkSockets},
+		{"display", func(context.Context) HealthCheck { return checkDisplay() }},
+		{"profiles", func(context.Context) HealthCheck { return m.checkProfiles() }},
+		{"audio", func(ctx context.Context) HealthCheck {
+			return m.cachedCheck(ctx, "audio "+m.config.MPVBinary, m.checkAudio)
+		}},
+	}
+
+	report := &HealthReport{Time: time.Now().UTC(), Checks: make([]HealthCheck, len(checks))}
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+			defer cancel()
+			start := time.Now()
+			check := checks[i].run(ctx)
+			check.Name = checks[i].name
+			check.DurationMS = time.Since(start).Milliseconds()
+			report.Checks[i] = check
+		}(i)
+	}
+	wg.Wait()
+
+	report.Status = CheckOK
+	for _, c := range report.Checks {
+		if c.Status.rank() > report.Status.rank() {
+			report.Status = c.Status
+		}
+	}
+	report.Ready = report.Status != CheckFail
+	return report
+}
+
+// healthCacheTTL is how long the results of checks that run binaries are
+// reused. The health endpoints may be public, so a flood of requests must
+// not fork a process each.
+const healthCacheTTL = time.Minute
+
+type cachedCheck struct {
+	check HealthCheck
+	at    time.Time
+}
+
+// cachedCheck returns the result of run under key if it is recent enough,
+// and runs it otherwise
+func (m *MediaLab) cachedCheck(ctx context.Context, key string, run func(ctx context.Context) HealthCheck) HealthCheck {
+	m.healthMu.Lock()
+	cached, ok := m.healthCache[key]
+	m.healthMu.Unlock()
+	if ok && time.Since(cached.at) < healthCacheTTL {
+		return cached.check
+	}
+
+	check := run(ctx)
+	if ctx.Err() != nil {
+		// A check cut short by its caller says nothing about the binary
+		return check
+	}
+	m.healthMu.Lock()
+	if m.healthCache == nil {
+		m.healthCache = make(map[string]cachedCheck)
+	}
+	m.healthCache[key] = cachedCheck{check: check, at: time.Now()}
+	m.healthMu.Unlock()
+	return check
+}
+
+// checkBinary looks up binary and reports its version; a missing binary
+// has status missing
+func checkBinary(ctx context.Context, binary string, missing CheckStatus) HealthCheck {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return HealthCheck{Status: missing, Message: fmt.Sprintf("%s not found", binary)}
+	}
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return HealthCheck{Status: missing, Message: fmt.Sprintf("%s --version failed: %v", path, err)}
+	}
+	version, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	version, _, _ = strings.Cut(version, " Copyright")
+	return HealthCheck{Status: CheckOK, Message: path, Version: strings.TrimSpace(version)}
+}
+
+// checkSockets probes every IPC socket that exists. A socket nothing
+// listens on is left behind by a crashed mpv and only warns, as the next
+// play replaces it; a player that accepts but does not answer is hung.
+func (m *MediaLab) checkSockets(ctx context.Context) HealthCheck {
+	screens := []Screen{Screen1, Screen2, Screen3, Screen4, ScreenSpeaker}
+	errs := make([]error, len(screens))
+	present := make([]bool, len(screens))
+	var wg sync.WaitGroup
+	for i, screen := range screens {
+		if _, err := os.Stat(m.socketPath(screen)); err != nil {
+			continue
+		}
+		present[i] = true
+		wg.Add(1)
+		go func(i int, screen Screen) {
+			defer wg.Done()
+			_, errs[i] = m.getProperties(m.socketPath(screen), []string{"mpv-version"})
+		}(i, screen)
+	}
+	wg.Wait()
+
+	var running, stale, hung []string
+	for i, screen := range screens {
+		switch {
+		case !present[i]:
+		case errors.Is(errs[i], ErrNoPlayer):
+			stale = append(stale, screen.String())
+		case errs[i] != nil:
+			hung = append(hung, fmt.Sprintf("%s (%v)", screen, errs[i]))
+		default:
+			running = append(running, screen.String())
+		}
+	}
+	switch {
+	case len(hung) > 0:
+		return HealthCheck{Status: CheckFail, Message: "not responding: " + strings.Join(hung, ", ")}
+	case len(stale) > 0:
+		return HealthCheck{Status: CheckWarn, Message: "stale sockets: " + strings.Join(stale, ", ")}
+	case len(running) == 0:
+		return HealthCheck{Status: CheckOK, Message: "no players running"}
+	}
+	return HealthCheck{Status: CheckOK, Message: "responding: " + strings.Join(running, ", ")}
+}
+
+// checkDisplay verifies the Wayland or X11 display the screens open
+// windows on accepts connections. Without one only the speaker works,
+// which is a warning: a headless speaker-only box is still ready.
+func checkDisplay() HealthCheck {
+	if wayland := os.Getenv("WAYLAND_DISPLAY"); wayland != "" {
+		socket := wayland
+		if !filepath.IsAbs(socket) {
+			socket = filepath.Join(os.Getenv("XDG_RUNTIME_DIR"), wayland)
+		}
+		if err := dialDisplay("unix", socket); err != nil {
+			return HealthCheck{Status: CheckFail, Message: fmt.Sprintf("Wayland display %s: %v", wayland, err)}
+		}
+		return HealthCheck{Status: CheckOK, Message: "Wayland " + wayland}
+	}
+
+	display := os.Getenv("DISPLAY")
+	if display == "" {
+		return HealthCheck{Status: CheckWarn, Message: "neither WAYLAND_DISPLAY nor DISPLAY is set; only the speaker can play"}
+	}
+	network, addr, err := x11Addr(display)
+	if err != nil {
+		return HealthCheck{Status: CheckFail, Message: err.Error()}
+	}
+	if err := dialDisplay(network, addr); err != nil {
+		return HealthCheck{Status: CheckFail, Message: fmt.Sprintf("X11 display %s: %v", display, err)}
+	}
+	return HealthCheck{Status: CheckOK, Message: "X11 " + display}
+}
+
+// x11Addr resolves an X11 DISPLAY ("[host]:N[.screen]") to its socket
+func x11Addr(display string) (string, string, error) {
+	host, rest, ok := strings.Cut(display, ":")
+	if !ok {
+		return "", "", fmt.Errorf("invalid DISPLAY %q", display)
+	}
+	number, _, _ := strings.Cut(rest, ".")
+	var n int
+	if _, err := fmt.Sscanf(number, "%d", &n); err != nil {
+		return "", "", fmt.Errorf("invalid DISPLAY %q", display)
+	}
+	if host == "" || host == "unix" {
+		return "unix", fmt.Sprintf("/tmp/.X11-unix/X%d", n), nil
+	}
+	return "tcp", net.JoinHostPort(host, fmt.Sprint(6000+n)), nil
+}
+
+func dialDisplay(network, addr string) error {
+	conn, err := net.DialTimeout(network, addr, 2*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// checkProfiles verifies mpv.conf defines the profile of every screen
+func (m *MediaLab) checkProfiles() HealthCheck {
+	path := filepath.Join(m.config.MPVConfigDir, "mpv.conf")
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return HealthCheck{Status: CheckWarn, Message: path + " not found (run medialab setup)"}
+	}
+	if err != nil {
+		return HealthCheck{Status: CheckWarn, Message: err.Error()}
+	}
+	defer f.Close()
+
+	defined := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			defined[strings.TrimSpace(line[1:len(line)-1])] = true
+		}
+	}
+
+	var missing []string
+	for screen := Screen1; screen <= Screen4; screen++ {
+		if !defined[screen.ProfileName()] {
+			missing = append(missing, "["+screen.ProfileName()+"]")
+		}
+	}
+	if len(missing) > 0 {
+		return HealthCheck{Status: CheckWarn, Message: fmt.Sprintf("%s lacks %s (run medialab setup)", path, strings.Join(missing, ", "))}
+	}
+	return HealthCheck{Status: CheckOK, Message: path}
+}
+
+// checkAudio asks mpv which audio devices it can open; "auto" alone means
+// there is nothing to play sound on
+func (m *MediaLab) checkAudio(ctx context.Context) HealthCheck {
+	path, err := exec.LookPath(m.config.MPVBinary)
+	if err != nil {
+		return HealthCheck{Status: CheckWarn, Message: "cannot list audio devices without mpv"}
+	}
+	out, err := exec.CommandContext(ctx, path, "--audio-device=help").Output()
+	if err != nil {
+		return HealthCheck{Status: CheckWarn, Message: fmt.Sprintf("listing audio devices failed: %v", err)}
+	}
+
+	var devices []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "'") {
+			continue
+		}
+		if name, _, _ := strings.Cut(line[1:], "'"); name != "auto" {
+			devices = append(devices, name)
+		}
+	}
+	if len(devices) == 0 {
+		return HealthCheck{Status: CheckFail, Message: "no audio devices found"}
+	}
+	return HealthCheck{Status: CheckOK, Message: fmt.Sprintf("%d devices (%s)", len(devices), strings.Join(devices, ", "))}
+}
@@ -0,0 +1,236 @@
+package medialab
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeScript writes an executable shell script to dir
+func writeScript(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newHealthLab returns a MediaLab whose binaries are fake scripts, whose
+// mpv.conf defines every screen profile and whose Wayland display accepts
+// connections
+func newHealthLab(t *testing.T) *MediaLab {
+	t.Helper()
+	lab := newTestLab(t)
+	dir := lab.config.SocketDir
+	lab.config.MPVBinary = writeScript(t, dir, "mpv", `case "$1" in
+--audio-device=help) printf "List of detected audio devices:\n  'auto' (Autoselect device)\n  'pulse' (Default (pulse))\n  'alsa/default' (Default (alsa))\n" ;;
+*) printf "mpv 0.38.0 Copyright (C) 2000-2024 mpv/MPlayer/mplayer2 projects\n built on ...\n" ;;
+esac
+`)
+	lab.config.YTDLPBinary = writeScript(t, dir, "yt-dlp", "echo 2024.08.06\n")
+	lab.config.PlayerctlPath = writeScript(t, dir, "playerctl", "echo v2.4.1\n")
+	lab.config.MPVConfigDir = dir
+	conf := "[screen1]\n[screen2]\n[screen3]\n[screen4]\n[speaker]\n"
+	if err := os.WriteFile(filepath.Join(dir, "mpv.conf"), []byte(conf), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("unix", filepath.Join(dir, "wayland-0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	t.Setenv("WAYLAND_DISPLAY", filepath.Join(dir, "wayland-0"))
+	return lab
+}
+
+func healthCheck(report *HealthReport, name string) HealthCheck {
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	return HealthCheck{}
+}
+
+func TestCheckHealth(t *testing.T) {
+	lab := newHealthLab(t)
+	report := lab.CheckHealth(context.Background())
+	if !report.Ready || report.Status != CheckOK {
+		t.Errorf("CheckHealth() = %s, ready %v: %+v", report.Status, report.Ready, report.Checks)
+	}
+	if got := healthCheck(report, "mpv").Version; got != "mpv 0.38.0" {
+		t.Errorf("mpv version = %q", got)
+	}
+	if got := healthCheck(report, "yt-dlp").Version; got != "2024.08.06" {
+		t.Errorf("yt-dlp version = %q", got)
+	}
+	if got := healthCheck(report, "audio").Message; !strings.HasPrefix(got, "2 devices") {
+		t.Errorf("audio check = %q, want 2 devices", got)
+	}
+
+	// A missing playerctl only degrades; a missing yt-dlp fails
+	lab.config.PlayerctlPath = filepath.Join(t.TempDir(), "playerctl")
+	if report := lab.CheckHealth(context.Background()); !report.Ready || report.Status != CheckWarn {
+		t.Errorf("without playerctl = %s, ready %v", report.Status, report.Ready)
+	}
+
+	// A headless box can still play on the speaker; a dead display fails
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("DISPLAY", "")
+	if report := lab.CheckHealth(context.Background()); !report.Ready || healthCheck(report, "display").Status != CheckWarn {
+		t.Errorf("without a display = %s, ready %v", report.Status, report.Ready)
+	}
+	t.Setenv("WAYLAND_DISPLAY", filepath.Join(t.TempDir(), "wayland-9"))
+	if report := lab.CheckHealth(context.Background()); report.Ready || healthCheck(report, "display").Status != CheckFail {
+		t.Errorf("with a dead display = %s, ready %v", report.Status, report.Ready)
+	}
+
+	lab.config.YTDLPBinary = "medialab-no-such-binary"
+	report = lab.CheckHealth(context.Background())
+	if report.Ready || healthCheck(report, "yt-dlp").Status != CheckFail {
+		t.Errorf("without yt-dlp = %s, ready %v", report.Status, report.Ready)
+	}
+}
+
+func TestCheckProfiles(t *testing.T) {
+	lab := newHealthLab(t)
+	path := filepath.Join(lab.config.MPVConfigDir, "mpv.conf")
+	os.WriteFile(path, []byte("[screen1]\nvolume=80\n[ screen3 ]\n"), 0o644)
+	check := lab.checkProfiles()
+	if check.Status != CheckWarn || !strings.Contains(check.Message, "[screen2], [screen4]") {
+		t.Errorf("checkProfiles() = %+v, want screen2 and screen4 missing", check)
+	}
+	os.Remove(path)
+	if check := lab.checkProfiles(); check.Status != CheckWarn || !strings.Contains(check.Message, "not found") {
+		t.Errorf("checkProfiles() without mpv.conf = %+v", check)
+	}
+}
+
+func TestCheckAudioNoDevices(t *testing.T) {
+	lab := newHealthLab(t)
+	lab.config.MPVBinary = writeScript(t, t.TempDir(), "mpv", `printf "List of detected audio devices:\n  'auto' (Autoselect device)\n"`)
+	if check := lab.checkAudio(context.Background()); check.Status != CheckFail {
+		t.Errorf("checkAudio() = %+v, want fail", check)
+	}
+}
+
+func TestCheckSockets(t *testing.T) {
+	lab := newHealthLab(t)
+	lab.config.IPCTimeout = 100 * time.Millisecond
+	if check := lab.checkSockets(context.Background()); check.Status != CheckOK || check.Message != "no players running" {
+		t.Errorf("checkSockets() without sockets = %+v", check)
+	}
+
+	// A crashed mpv leaves its socket behind
+	ln, err := net.Listen("unix", lab.socketPath(Screen2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	if check := lab.checkSockets(context.Background()); check.Status != CheckWarn || !strings.Contains(check.Message, "stale") {
+		t.Errorf("checkSockets() with a stale socket = %+v, want warn", check)
+	}
+
+	// A hung mpv accepts but never answers
+	os.Remove(lab.socketPath(Screen2))
+	ln, err = net.Listen("unix", lab.socketPath(Screen2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	if check := lab.checkSockets(context.Background()); check.Status != CheckFail {
+		t.Errorf("checkSockets() with a hung player = %+v, want fail", check)
+	}
+}
+
+func TestCheckHealthCache(t *testing.T) {
+	lab := newHealthLab(t)
+	count := filepath.Join(t.TempDir(), "count")
+	lab.config.YTDLPBinary = writeScript(t, t.TempDir(), "yt-dlp", "echo x >> "+count+"\necho 2024.08.06\n")
+	lab.CheckHealth(context.Background())
+	lab.CheckHealth(context.Background())
+	if data, _ := os.ReadFile(count); strings.Count(string(data), "x") != 1 {
+		t.Errorf("yt-dlp ran %d times for two reports, want 1", strings.Count(string(data), "x"))
+	}
+
+	lab.healthMu.Lock()
+	for key, cached := range lab.healthCache {
+		cached.at = cached.at.Add(-healthCacheTTL)
+		lab.healthCache[key] = cached
+	}
+	lab.healthMu.Unlock()
+	lab.CheckHealth(context.Background())
+	if data, _ := os.ReadFile(count); strings.Count(string(data), "x") != 2 {
+		t.Errorf("yt-dlp ran %d times after the cache expired, want 2", strings.Count(string(data), "x"))
+	}
+}
+
+func TestX11Addr(t *testing.T) {
+	for _, tt := range []struct {
+		display, network, addr string
+	}{
+		{":0", "unix", "/tmp/.X11-unix/X0"},
+		{":1.0", "unix", "/tmp/.X11-unix/X1"},
+		{"unix:2", "unix", "/tmp/.X11-unix/X2"},
+		{"localhost:10.0", "tcp", "localhost:6010"},
+	} {
+		network, addr, err := x11Addr(tt.display)
+		if err != nil || network != tt.network || addr != tt.addr {
+			t.Errorf("x11Addr(%q) = %s %s, %v", tt.display, network, addr, err)
+		}
+	}
+	for _, display := range []string{"0", ":", "host:x"} {
+		if _, _, err := x11Addr(display); err == nil {
+			t.Errorf("x11Addr(%q) succeeded", display)
+		}
+	}
+}
+
+func TestReadyEndpoint(t *testing.T) {
+	lab := newHealthLab(t)
+	s := NewServer(lab)
+	w := serveRequest(s, httptest.NewRequest("GET", "/v1/ready", nil))
+	if body := decodeEnvelope(t, w); w.Code != http.StatusOK || body["ready"] != true {
+		t.Errorf("GET /v1/ready = %d %v", w.Code, body)
+	}
+
+	lab.config.MPVBinary = "medialab-no-such-binary"
+	for _, path := range []string{"/v1/ready", "/ready"} {
+		w := serveRequest(s, httptest.NewRequest("GET", path, nil))
+		if body := decodeEnvelope(t, w); w.Code != http.StatusServiceUnavailable || body["status"] != "fail" {
+			t.Errorf("GET %s without mpv = %d %v", path, w.Code, body)
+		}
+	}
+	// Health reports failures without failing itself
+	w = serveRequest(s, httptest.NewRequest("GET", "/v1/health", nil))
+	if body := decodeEnvelope(t, w); w.Code != http.StatusOK || body["status"] != "fail" || body["cache"] == nil {
+		t.Errorf("GET /v1/health without mpv = %d %v", w.Code, body)
+	}
+}
@@ -0,0 +1,413 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// libraryPlayThreshold is the minimum match score for a query passed to
+// PlayQuery to resolve to a local file instead of a YouTube search
+const libraryPlayThreshold = 0.6
+
+// LibraryItem is an indexed local media file
+type LibraryItem struct {
+	Path     string            `json:"path"`
+	Size     int64             `json:"size"`
+	ModTime  time.Time         `json:"mod_time"`
+	Duration float64           `json:"duration"`
+	Title    string            `json:"title"`
+	Artist   string            `json:"artist,omitempty"`
+	Album    string            `json:"album,omitempty"`
+	Tags     map[string]string `json:"tags,omitempty"`
+}
+
+// LibraryMatch is a search hit with its relevance score (0-1)
+type LibraryMatch struct {
+	*LibraryItem
+	Score float64 `json:"score"`
+}
+
+// LibraryStats summarizes the index
+type LibraryStats struct {
+	Items         int       `json:"items"`
+	TotalSize     int64     `json:"total_size"`
+	TotalDuration float64   `json:"total_duration"`
+	Dirs          []string  `json:"dirs"`
+	IndexPath     string    `json:"index_path"`
+	LastScan      time.Time `json:"last_scan"`
+}
+
+// ScanResult reports what a scan changed
+type ScanResult struct {
+	Added    int           `json:"added"`
+	Updated  int           `json:"updated"`
+	Removed  int           `json:"removed"`
+	Total    int           `json:"total"`
+	Duration time.Duration `json:"duration"`
+}
+
+var mediaExtensions = map[string]bool{
+	".mp4": true, ".mkv": true, ".webm": true, ".avi": true, ".mov": true, ".m4v": true,
+	".mp3": true, ".flac": true, ".ogg": true, ".opus": true, ".m4a": true, ".wav": true, ".aac": true,
+}
+
+// Library indexes media files under a set of directories
+type Library struct {
+	dirs      []string
+	indexPath string
+	ffprobe   string
+
+	scanMu   sync.Mutex // serializes scans
+	mu       sync.RWMutex
+	items    map[string]*LibraryItem
+	lastScan time.Time
+	loaded   bool
+}
+
+type libraryIndex struct {
+	LastScan time.Time      `json:"last_scan"`
+	Items    []*LibraryItem `json:"items"`
+}
+
+// NewLibrary creates a library over dirs, persisting its index at indexPath
+func NewLibrary(dirs []string, indexPath, ffprobe string) *Library {
+	return &Library{
+		dirs:      dirs,
+		indexPath: indexPath,
+		ffprobe:   ffprobe,
+		items:     make(map[string]*LibraryItem),
+	}
+}
+
+// Library returns the local media library
+func (m *MediaLab) Library() *Library {
+	return m.library
+}
+
+// Load reads the on-disk index. A missing index is not an error.
+func (l *Library) Load() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.loadLocked()
+}
+
+func (l *Library) loadLocked() error {
+	l.loaded = true
+	data, err := os.ReadFile(l.indexPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read library index: %w", err)
+	}
+	var index libraryIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return fmt.Errorf("invalid library index: %w", err)
+	}
+	l.items = make(map[string]*LibraryItem, len(index.Items))
+	for _, item := range index.Items {
+		l.items[item.Path] = item
+	}
+	l.lastScan = index.LastScan
+	return nil
+}
+
+func (l *Library) ensureLoaded() {
+	l.mu.RLock()
+	loaded := l.loaded
+	l.mu.RUnlock()
+	if !loaded {
+		l.Load()
+	}
+}
+
+// Save writes the index to disk atomically
+func (l *Library) Save() error {
+	l.mu.RLock()
+	index := libraryIndex{LastScan: l.lastScan, Items: make([]*LibraryItem, 0, len(l.items))}
+	for _, item := range l.items {
+		index.Items = append(index.Items, item)
+	}
+	l.mu.RUnlock()
+	sort.Slice(index.Items, func(i, j int) bool { return index.Items[i].Path < index.Items[j].Path })
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.indexPath), 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	tmp := l.indexPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write library index: %w", err)
+	}
+	return os.Rename(tmp, l.indexPath)
+}
+
+// Scan walks the library directories, probing new or modified files and
+// dropping ones that disappeared, then saves the index
+func (l *Library) Scan(ctx context.Context) (*ScanResult, error) {
+	l.scanMu.Lock()
+	defer l.scanMu.Unlock()
+	l.ensureLoaded()
+	started := time.Now()
+	result := &ScanResult{}
+
+	l.mu.RLock()
+	known := make(map[string]*LibraryItem, len(l.items))
+	for path, item := range l.items {
+		known[path] = item
+	}
+	l.mu.RUnlock()
+
+	found := make(map[string]*LibraryItem, len(known))
+	for _, dir := range l.dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil // skip unreadable entries and missing dirs
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if d.IsDir() || !mediaExtensions[strings.ToLower(filepath.Ext(path))] {
+				return nil
+			}
+			fi, err := d.Info()
+			if err != nil {
+				return nil
+			}
+
+			if old, ok := known[path]; ok && old.Size == fi.Size() && old.ModTime.Equal(fi.ModTime()) {
+				found[path] = old
+				return nil
+			} else if ok {
+				result.Updated++
+			} else {
+				result.Added++
+			}
+			found[path] = l.probe(ctx, path, fi)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	for path := range known {
+		if _, ok := found[path]; !ok {
+			result.Removed++
+		}
+	}
+
+	l.mu.Lock()
+	l.items = found
+	l.lastScan = time.Now()
+	l.mu.Unlock()
+
+	result.Total = len(found)
+	result.Duration = time.Since(started)
+	return result, l.Save()
+}
+
+// Watch rescans the library every interval until ctx is cancelled. Scans
+// are incremental, so only new or modified files are probed again.
+func (l *Library) Watch(ctx context.Context, interval time.Duration, onScan func(*ScanResult, error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		result, err := l.Scan(ctx)
+		if onScan != nil && ctx.Err() == nil {
+			onScan(result, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probe builds an item from ffprobe output, falling back to the file name
+// when ffprobe is missing or fails
+func (l *Library) probe(ctx context.Context, path string, fi fs.FileInfo) *LibraryItem {
+	name := filepath.Base(path)
+	item := &LibraryItem{
+		Path:    path,
+		Size:    fi.Size(),
+		ModTime: fi.ModTime(),
+		Title:   strings.TrimSuffix(name, filepath.Ext(name)),
+	}
+
+	cmd := exec.CommandContext(ctx, l.ffprobe, "-v", "quiet", "-print_format", "json", "-show_format", "-i", path)
+	output, err := cmd.Output()
+	if err != nil {
+		return item
+	}
+	var probe struct {
+		Format struct {
+			Duration string            `json:"duration"`
+			Tags     map[string]string `json:"tags"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(output, &probe); err != nil {
+		return item
+	}
+
+	item.Duration, _ = strconv.ParseFloat(probe.Format.Duration, 64)
+	if len(probe.Format.Tags) > 0 {
+		item.Tags = make(map[string]string, len(probe.Format.Tags))
+		for k, v := range probe.Format.Tags {
+			item.Tags[strings.ToLower(k)] = v
+		}
+	}
+	if title := item.Tags["title"]; title != "" {
+		item.Title = title
+	}
+	item.Artist = item.Tags["artist"]
+	if item.Artist == "" {
+		item.Artist = item.Tags["album_artist"]
+	}
+	item.Album = item.Tags["album"]
+	return item
+}
+
+// Search returns items fuzzily matching every word of query against title,
+// artist, album, file name and parent directory, best first
+func (l *Library) Search(query string, maxResults int) []LibraryMatch {
+	l.ensureLoaded()
+	terms := strings.Fields(strings.ToLower(query))
+	if len(terms) == 0 {
+		return nil
+	}
+
+	l.mu.RLock()
+	var matches []LibraryMatch
+	for _, item := range l.items {
+		// The parent directory often names the album or show
+		words := tokenize(item.Title + " " + item.Artist + " " + item.Album + " " +
+			filepath.Base(item.Path) + " " + filepath.Base(filepath.Dir(item.Path)))
+		if score := fuzzyScore(terms, words); score > 0 {
+			matches = append(matches, LibraryMatch{LibraryItem: item, Score: score})
+		}
+	}
+	l.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Path < matches[j].Path
+	})
+	if maxResults > 0 && len(matches) > maxResults {
+		matches = matches[:maxResults]
+	}
+	return matches
+}
+
+// Stats summarizes the index
+func (l *Library) Stats() LibraryStats {
+	l.ensureLoaded()
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	stats := LibraryStats{
+		Items:     len(l.items),
+		Dirs:      l.dirs,
+		IndexPath: l.indexPath,
+		LastScan:  l.lastScan,
+	}
+	for _, item := range l.items {
+		stats.TotalSize += item.Size
+		stats.TotalDuration += item.Duration
+	}
+	return stats
+}
+
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// fuzzyScore averages the best per-term match against words; any term
+// without a match scores the whole item 0
+func fuzzyScore(terms, words []string) float64 {
+	var total float64
+	for _, term := range terms {
+		best := 0.0
+		for _, word := range words {
+			if s := termScore(term, word); s > best {
+				best = s
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		total += best
+	}
+	return total / float64(len(terms))
+}
+
+func termScore(term, word string) float64 {
+	switch {
+	case word == term:
+		return 1.0
+	case strings.HasPrefix(word, term):
+		return 0.8
+	case strings.Contains(word, term):
+		return 0.6
+	case len(term) >= 4 && editDistance(term, word) <= 1:
+		return 0.5 // single typo
+	case len(term) >= 3 && isSubsequence(term, word):
+		return 0.3
+	}
+	return 0
+}
+
+func isSubsequence(sub, s string) bool {
+	i := 0
+	for j := 0; i < len(sub) && j < len(s); j++ {
+		if sub[i] == s[j] {
+			i++
+		}
+	}
+	return i == len(sub)
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,109 @@
+package medialab
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestLibrary(t *testing.T, files ...string) (*Library, string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, nil, 0644)
+	}
+	// ffprobe is pointed at a missing binary so items fall back to file names
+	lib := NewLibrary([]string{dir}, filepath.Join(t.TempDir(), "library.json"), "/nonexistent/ffprobe")
+	return lib, dir
+}
+
+func TestLibraryScan(t *testing.T) {
+	lib, dir := newTestLibrary(t,
+		"Jazz/Miles Davis - So What.flac",
+		"Jazz/Coltrane - Naima.mp3",
+		"Jazz/cover.jpg",
+	)
+
+	result, err := lib.Scan(context.Background())
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if result.Added != 2 || result.Total != 2 {
+		t.Errorf("Scan() = %+v, want 2 added, 2 total", result)
+	}
+
+	// Rescan without changes probes nothing
+	result, _ = lib.Scan(context.Background())
+	if result.Added != 0 || result.Updated != 0 || result.Removed != 0 {
+		t.Errorf("incremental Scan() = %+v, want no changes", result)
+	}
+
+	os.Remove(filepath.Join(dir, "Jazz/Coltrane - Naima.mp3"))
+	result, _ = lib.Scan(context.Background())
+	if result.Removed != 1 || result.Total != 1 {
+		t.Errorf("Scan() after delete = %+v, want 1 removed, 1 total", result)
+	}
+
+	// Index survives a reload from disk
+	reloaded := NewLibrary(nil, lib.indexPath, "")
+	if err := reloaded.Load(); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if got := reloaded.Stats().Items; got != 1 {
+		t.Errorf("reloaded Items = %d, want 1", got)
+	}
+}
+
+func TestLibrarySearch(t *testing.T) {
+	lib, _ := newTestLibrary(t,
+		"Miles Davis - So What.flac",
+		"Coltrane - Naima.mp3",
+		"Coltrane - Giant Steps.mp3",
+	)
+	lib.Scan(context.Background())
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"naima", "Coltrane - Naima"},
+		{"coltrane giant", "Coltrane - Giant Steps"},
+		{"coltane naima", "Coltrane - Naima"}, // typo
+		{"so what", "Miles Davis - So What"},
+	}
+	for _, tt := range tests {
+		matches := lib.Search(tt.query, 1)
+		if len(matches) == 0 {
+			t.Errorf("Search(%q) found nothing, want %q", tt.query, tt.want)
+			continue
+		}
+		if matches[0].Title != tt.want {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, matches[0].Title, tt.want)
+		}
+	}
+
+	if matches := lib.Search("beethoven", 5); len(matches) != 0 {
+		t.Errorf("Search(beethoven) = %d matches, want 0", len(matches))
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"abc", "abd", 1},
+		{"naima", "niama", 2},
+		{"kitten", "sitting", 3},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package medialab
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Station is a named internet radio or live stream, playable by name
+type Station struct {
+	Name  string `json:"name"`
+	URL   string `json:"url"`
+	Genre string `json:"genre,omitempty"`
+}
+
+// DefaultStations is the curated station list of DefaultConfig
+var DefaultStations = []Station{
+	{Name: "Groove Salad", URL: "https://ice1.somafm.com/groovesalad-128-mp3", Genre: "ambient"},
+	{Name: "Drone Zone", URL: "https://ice1.somafm.com/dronezone-128-mp3", Genre: "ambient"},
+	{Name: "Radio Paradise", URL: "https://stream.radioparadise.com/aac-320", Genre: "eclectic"},
+	{Name: "Lofi Girl", URL: "https://www.youtube.com/watch?v=jfKfPfyJRdk", Genre: "lofi"},
+}
+
+// Stations returns the configured station list
+func (m *MediaLab) Stations() []Station {
+	return m.config.Stations
+}
+
+// Station looks up a station by name, ignoring case, spaces and
+// punctuation ("groove salad" matches "Groove Salad")
+func (m *MediaLab) Station(name string) (Station, error) {
+	key := stationKey(name)
+	for _, st := range m.config.Stations {
+		if key != "" && stationKey(st.Name) == key {
+			return st, nil
+		}
+	}
+	return Station{}, fmt.Errorf("%w: station %q", ErrNotFound, name)
+}
+
+// stationByURL returns the station streaming from url, if any
+func (m *MediaLab) stationByURL(url string) (Station, bool) {
+	for _, st := range m.config.Stations {
+		if url != "" && st.URL == url {
+			return st, true
+		}
+	}
+	return Station{}, false
+}
+
+func stationKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// streamReconnectArgs make ffmpeg reopen dropped HTTP streams by itself.
+// Stalls that keep the connection open are left to watchStalls, which only
+// runs while the process that started the player is alive.
+var streamReconnectArgs = []string{
+	"--stream-lavf-o-append=reconnect=1",
+	"--stream-lavf-o-append=reconnect_streamed=1",
+	"--stream-lavf-o-append=reconnect_delay_max=5",
+}
+
+// isLive reports whether fetched player properties describe a live stream:
+// yt-dlp flagged it live, or a remote stream is playing without a duration
+func isLive(vals map[string]any, meta *MediaMetadata) bool {
+	if meta != nil && meta.IsLive {
+		return true
+	}
+	path, _ := vals["path"].(string)
+	_, hasPos := vals["time-pos"]
+	_, hasDuration := vals["duration"]
+	return isRemoteURL(path) && hasPos && !hasDuration
+}
+
+// stallWatch tracks playback progress of a live stream
+type stallWatch struct {
+	live     bool      // a live stream has been seen
+	path     string    // last stream path, for reloading after a drop
+	lastPos  float64   // last observed time-pos
+	progress time.Time // when time-pos last advanced
+}
+
+// check records fetched properties and reports whether the stream needs a
+// reconnect: it dropped (EOF or idle) or has not advanced for timeout
+// while not paused by the user
+func (w *stallWatch) check(vals map[string]any, meta *MediaMetadata, now time.Time, timeout time.Duration) bool {
+	idle, _ := vals["idle-active"].(bool)
+	eof, _ := vals["eof-reached"].(bool)
+	paused, _ := vals["pause"].(bool)
+	pos, hasPos := vals["time-pos"].(float64)
+	path, _ := vals["path"].(string)
+	_, hasDuration := vals["duration"]
+
+	switch {
+	case idle:
+	case isLive(vals, meta):
+		w.live = true
+		w.path = path
+	case path != w.path || hasDuration:
+		// Another entry replaced the stream; a stream that is only
+		// reopening keeps its path and still has no duration
+		*w = stallWatch{}
+	}
+	if !w.live {
+		return false
+	}
+	if idle || eof {
+		return w.path != ""
+	}
+
+	if paused || !hasPos || pos != w.lastPos || w.progress.IsZero() {
+		w.lastPos = pos
+		w.progress = now
+		return false
+	}
+	return now.Sub(w.progress) >= timeout
+}
+
+// stallProps are the properties the stall watchdog polls
+var stallProps = []string{"path", "time-pos", "duration", "pause", "idle-active", "eof-reached"}
+
+// watchStalls reloads instance's live stream whenever it stalls or drops,
+// until the instance is replaced or stopped. Non-live media is only
+// polled, never touched.
+func (m *MediaLab) watchStalls(instance *PlayerInstance) {
+	timeout := m.config.StallTimeout
+	if timeout <= 0 {
+		return
+	}
+	interval := timeout / 3
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	var w stallWatch
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if current, ok := m.GetPlayer(instance.Screen); !ok || current != instance {
+			return
+		}
+		vals, err := m.GetProperties(instance.Screen, stallProps)
+		if errors.Is(err, ErrIPCTimeout) {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		path, _ := vals["path"].(string)
+		if !w.check(vals, m.cachedMetadata(path), time.Now(), timeout) {
+			continue
+		}
+
+		// Restart the current entry in place; after a drop to idle there is
+		// no current entry left, so load the stream again
+		command := []any{"playlist-play-index", "current"}
+		if idle, _ := vals["idle-active"].(bool); idle {
+			command = []any{"loadfile", w.path, "replace"}
+		}
+		if _, err := m.IPCCommand(instance.Screen, map[string]any{"command": command}); err == nil {
+			instance.reconnects.Add(1)
+			m.metrics.inc("medialab_stream_reconnects_total", "screen", metricScreen(instance.Screen))
+			m.logger.Warn("stalled stream reconnected", "screen", instance.Screen.String(), "url", w.path)
+			w.progress = time.Time{}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package medialab
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestStationLookup(t *testing.T) {
+	lab := New(nil)
+	for _, name := range []string{"Groove Salad", "groove salad", "groovesalad", " GROOVE-SALAD "} {
+		st, err := lab.Station(name)
+		if err != nil || st.Name != "Groove Salad" {
+			t.Errorf("Station(%q) = %v, %v; want Groove Salad", name, st.Name, err)
+		}
+	}
+	if _, err := lab.Station("no such radio"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Station(unknown) error = %v, want ErrNotFound", err)
+	}
+	if _, err := lab.Station(""); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Station(\"\") error = %v, want ErrNotFound", err)
+	}
+
+	url, err := lab.ResolveQuery(context.Background(), "drone zone")
+	if err != nil || url != "https://ice1.somafm.com/dronezone-128-mp3" {
+		t.Errorf("ResolveQuery(station) = %q, %v", url, err)
+	}
+}
+
+func TestIsLive(t *testing.T) {
+	radio := map[string]any{"path": "https://ice1.somafm.com/groovesalad-128-mp3", "time-pos": 3.0}
+	if !isLive(radio, nil) {
+		t.Error("remote stream without duration should be live")
+	}
+	video := map[string]any{"path": "https://example.com/a.mp4", "time-pos": 3.0, "duration": 60.0}
+	if isLive(video, nil) {
+		t.Error("remote file with a duration should not be live")
+	}
+	if !isLive(video, &MediaMetadata{IsLive: true}) {
+		t.Error("yt-dlp is_live should win over the duration")
+	}
+	local := map[string]any{"path": "/music/a.flac", "time-pos": 3.0}
+	if isLive(local, nil) {
+		t.Error("local file should not be live")
+	}
+	loading := map[string]any{"path": "https://ice1.somafm.com/groovesalad-128-mp3"}
+	if isLive(loading, nil) {
+		t.Error("stream still opening should not be live yet")
+	}
+}
+
+func TestStallWatch(t *testing.T) {
+	const url = "https://ice1.somafm.com/groovesalad-128-mp3"
+	timeout := 10 * time.Second
+	now := time.Now()
+	var w stallWatch
+
+	playing := func(pos float64) map[string]any {
+		return map[string]any{"path": url, "time-pos": pos}
+	}
+	if w.check(playing(1), nil, now, timeout) {
+		t.Fatal("first sample reported a stall")
+	}
+	if w.check(playing(5), nil, now.Add(5*time.Second), timeout) {
+		t.Error("advancing stream reported a stall")
+	}
+	if w.check(playing(5), nil, now.Add(10*time.Second), timeout) {
+		t.Error("stall reported before the timeout")
+	}
+	if !w.check(playing(5), nil, now.Add(15*time.Second), timeout) {
+		t.Error("stream stuck for the timeout not reported")
+	}
+
+	// Paused by the user is not a stall
+	paused := playing(5)
+	paused["pause"] = true
+	if w.check(paused, nil, now.Add(60*time.Second), timeout) {
+		t.Error("paused stream reported a stall")
+	}
+
+	// A dropped stream needs a reconnect right away
+	if !w.check(map[string]any{"idle-active": true}, nil, now.Add(61*time.Second), timeout) || w.path != url {
+		t.Errorf("dropped stream not reported (path %q)", w.path)
+	}
+
+	// Media played after the stream is not treated as live
+	vod := map[string]any{"path": "https://example.com/a.mp4", "time-pos": 5.0, "duration": 60.0}
+	if w.check(vod, nil, now.Add(62*time.Second), timeout) || w.live || w.path != "" {
+		t.Fatalf("VOD after a stream still tracked as live (path %q)", w.path)
+	}
+	if w.check(vod, nil, now.Add(2*time.Minute), timeout) {
+		t.Error("VOD stuck after a stream reported a stall")
+	}
+	ended := map[string]any{"path": "https://example.com/a.mp4", "time-pos": 60.0, "duration": 60.0, "eof-reached": true}
+	if w.check(ended, nil, now.Add(3*time.Minute), timeout) {
+		t.Error("VOD ending after a stream reported a drop")
+	}
+	if w.check(map[string]any{"idle-active": true}, nil, now.Add(4*time.Minute), timeout) {
+		t.Error("idle player after a VOD reloaded the old stream")
+	}
+
+	// Non-live media is never touched
+	var file stallWatch
+	finished := map[string]any{"path": "https://example.com/a.mp4", "time-pos": 60.0, "duration": 60.0, "eof-reached": true}
+	if file.check(finished, nil, now, timeout) || file.check(finished, nil, now.Add(time.Minute), timeout) {
+		t.Error("finished file reported as a stalled stream")
+	}
+}
@@ -2,6 +2,7 @@
 //
 // Architecture:
 //   - mpv instances per screen with IPC sockets (/tmp/mpv-screen{N})
+//   - an audio-only "speaker" instance with no window (/tmp/mpv-speaker)
 //   - MPRIS integration via mpv-mpris plugin
 //   - playerctl for generic media control
 //   - JSON IPC for precise per-instance control
@@ -12,14 +13,23 @@
 //   - media.volume: Volume control
 //   - media.seek: Seek to position
 //   - media.info: Get current playback info
-//   - media.search: Search YouTube (via yt-dlp)
+//   - media.search: Search YouTube, SoundCloud, Internet Archive, local files or any yt-dlp extractor
+//   - media.clip: Export a time range to a local file
+//   - media.download: Download media for offline playback
+//   - media.batch: Run several operations across screens, or a named macro
+//   - media.scene: Save and restore the state of every screen
+//   - media.schedule: Run batches or scenes at cron or calendar times
+//   - media.timer: Stop or pause a screen later, with an optional fade-out
 package medialab
 
 import (
+	"bufio"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
 	"net"
 	"os"
 	"os/exec"
@@ -27,6 +37,8 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 )
 
@@ -38,42 +50,149 @@ const (
 	Screen2 Screen = 1
 	Screen3 Screen = 2
 	Screen4 Screen = 3
+
+	// ScreenSpeaker is an audio-only pseudo-screen: mpv runs without video
+	// or a window, alongside the four video screens (see Config.Speaker*)
+	ScreenSpeaker Screen = 4
 )
 
-// SocketPath returns the IPC socket path for a screen
+// DefaultSocketDir holds the IPC sockets unless Config.SocketDir is set
+const DefaultSocketDir = "/tmp"
+
+// SocketPath returns the IPC socket path for a screen in DefaultSocketDir
 func (s Screen) SocketPath() string {
-	return fmt.Sprintf("/tmp/mpv-screen%d", s+1)
+	return filepath.Join(DefaultSocketDir, s.socketName())
+}
+
+func (s Screen) socketName() string {
+	if s == ScreenSpeaker {
+		return "mpv-speaker"
+	}
+	return fmt.Sprintf("mpv-screen%d", s+1)
+}
+
+// socketPath returns the IPC socket path for a screen in Config.SocketDir
+func (m *MediaLab) socketPath(screen Screen) string {
+	dir := m.config.SocketDir
+	if dir == "" {
+		dir = DefaultSocketDir
+	}
+	return filepath.Join(dir, screen.socketName())
 }
 
 // ProfileName returns the mpv profile name for a screen
 func (s Screen) ProfileName() string {
+	if s == ScreenSpeaker {
+		return "speaker"
+	}
 	return fmt.Sprintf("screen%d", s+1)
 }
 
+// String returns "screen N" (1-based) or "speaker"
+func (s Screen) String() string {
+	if s == ScreenSpeaker {
+		return "speaker"
+	}
+	return fmt.Sprintf("screen %d", s+1)
+}
+
+// Valid reports whether s is one of the four screens or the speaker
+func (s Screen) Valid() bool {
+	return s >= Screen1 && s <= ScreenSpeaker
+}
+
+// ParseScreen parses a 1-based screen number (1-4) or "speaker"
+func ParseScreen(s string) (Screen, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "speaker" {
+		return ScreenSpeaker, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 4 {
+		return Screen1, fmt.Errorf("%w: screen %q (want 1-4 or speaker)", ErrInvalidArgument, s)
+	}
+	return Screen(n - 1), nil
+}
+
 // Config holds media lab configuration
 type Config struct {
-	MPVBinary     string
-	MPVConfigDir  string
-	DefaultScreen Screen
-	Profiles      map[Screen]string
-	YTDLPBinary   string
-	PlayerctlPath string
-	IPCTimeout    time.Duration
-	DefaultVolume int
+	MPVBinary      string
+	MPVConfigDir   string
+	SocketDir      string // mpv IPC sockets, "" = DefaultSocketDir
+	DefaultScreen  Screen
+	Profiles       map[Screen]string
+	YTDLPBinary    string
+	PlayerctlPath  string
+	IPCTimeout     time.Duration
+	DefaultVolume  int
+	ClipDir        string
+	LibraryDirs    []string // directories indexed by the local library
+	LibraryIndex   string   // on-disk library index (JSON)
+	FFprobeBinary  string
+	CacheFile      string             // on-disk search/resolution cache (JSON)
+	CacheTTL       time.Duration      // how long cached results stay fresh, 0 = no caching
+	LastSearchFile string             // results of the last search, for playing by number
+	Quality        Quality            // default yt-dlp format selection
+	ScreenQuality  map[Screen]Quality // per-screen overrides of Quality
+
+	DownloadDir         string // offline cache, preferred by Play over streaming
+	DownloadConcurrency int    // simultaneous downloads
+	DownloadQuota       int64  // bytes; least recently used files are evicted beyond it, 0 = unlimited
+
+	SpeakerAudioDevice string // mpv --audio-device for the speaker, "" = system default
+	SpeakerVolume      int    // initial speaker volume, 0 = DefaultVolume
+
+	Stations     []Station     // internet radio and live streams playable by name
+	StallTimeout time.Duration // live streams not advancing this long are reconnected, 0 = never
+
+	Auth   AuthConfig // HTTP API clients, scopes and screen ACLs; zero value = open API
+	TLSDir string     // self-signed certificate for Server.StartTLS without cert files
+
+	Macros    map[string][]BatchStep // named batches, see RunMacro
+	MacroFile string                 // JSON object of more macros, name -> steps
+
+	SceneDir     string // saved scenes, one JSON file each
+	ScheduleFile string // schedule entries, see Scheduler
+
+	Logger    *slog.Logger // diagnostics and media actions; nil = discard
+	AuditFile string       // append-only JSON lines of media actions, see Audit; "" = none
 }
 
 // DefaultConfig returns sensible defaults
 func DefaultConfig() *Config {
 	homeDir, _ := os.UserHomeDir()
 	return &Config{
-		MPVBinary:     "mpv",
-		MPVConfigDir:  filepath.Join(homeDir, ".config", "mpv"),
-		DefaultScreen: Screen1,
-		Profiles:      make(map[Screen]string),
-		YTDLPBinary:   "yt-dlp",
-		PlayerctlPath: "playerctl",
-		IPCTimeout:    5 * time.Second,
-		DefaultVolume: 80,
+		MPVBinary:      "mpv",
+		MPVConfigDir:   filepath.Join(homeDir, ".config", "mpv"),
+		DefaultScreen:  Screen1,
+		Profiles:       make(map[Screen]string),
+		YTDLPBinary:    "yt-dlp",
+		PlayerctlPath:  "playerctl",
+		IPCTimeout:     5 * time.Second,
+		DefaultVolume:  80,
+		ClipDir:        filepath.Join(homeDir, "Videos", "medialab"),
+		LibraryDirs:    []string{filepath.Join(homeDir, "Music"), filepath.Join(homeDir, "Videos")},
+		LibraryIndex:   filepath.Join(homeDir, ".cache", "medialab", "library.json"),
+		FFprobeBinary:  "ffprobe",
+		CacheFile:      filepath.Join(homeDir, ".cache", "medialab", "cache.json"),
+		CacheTTL:       6 * time.Hour,
+		LastSearchFile: filepath.Join(homeDir, ".cache", "medialab", "last-search.json"),
+		ScreenQuality:  make(map[Screen]Quality),
+
+		DownloadDir:         filepath.Join(homeDir, ".cache", "medialab", "downloads"),
+		DownloadConcurrency: 2,
+		DownloadQuota:       10 << 30, // 10 GiB
+
+		Stations:     append([]Station(nil), DefaultStations...),
+		StallTimeout: 15 * time.Second,
+
+		TLSDir: filepath.Join(homeDir, ".config", "medialab", "tls"),
+
+		MacroFile: filepath.Join(homeDir, ".config", "medialab", "macros.json"),
+		SceneDir:  filepath.Join(homeDir, ".config", "medialab", "scenes"),
+
+		ScheduleFile: filepath.Join(homeDir, ".config", "medialab", "schedule.json"),
+		AuditFile:    filepath.Join(homeDir, ".config", "medialab", "audit.jsonl"),
 	}
 }
 
@@ -82,6 +201,32 @@ type MediaLab struct {
 	config  *Config
 	mu      sync.RWMutex
 	players map[Screen]*PlayerInstance
+
+	metaMu   sync.RWMutex
+	metadata map[string]*MediaMetadata // yt-dlp metadata by URL
+
+	searchMu  sync.RWMutex
+	providers map[string]SearchProvider
+
+	lastMu      sync.Mutex
+	lastResults []SearchResult // most recent search, see LastResults
+
+	library   *Library
+	cache     *Cache
+	downloads *DownloadManager
+	scheduler *Scheduler
+
+	timerMu   sync.Mutex
+	timers    map[string]*timerJob
+	nextTimer int
+
+	logger  *slog.Logger
+	auditMu sync.Mutex
+
+	healthMu    sync.Mutex
+	healthCache map[string]cachedCheck // binary checks, see cachedCheck
+
+	metrics *metrics
 }
 
 // PlayerInstance tracks an active mpv instance
@@ -92,6 +237,8 @@ type PlayerInstance struct {
 	URL       string
 	StartedAt time.Time
 	cmd       *exec.Cmd
+
+	reconnects atomic.Int64 // live stream reloads by the stall watchdog
 }
 
 // New creates a new MediaLab instance
@@ -99,52 +246,144 @@ func New(config *Config) *MediaLab {
 	if config == nil {
 		config = DefaultConfig()
 	}
-	return &MediaLab{
-		config:  config,
-		players: make(map[Screen]*PlayerInstance),
+	m := &MediaLab{
+		config:    config,
+		players:   make(map[Screen]*PlayerInstance),
+		metadata:  make(map[string]*MediaMetadata),
+		providers: make(map[string]SearchProvider),
+		timers:    make(map[string]*timerJob),
+		metrics:   newMetrics(),
+		library:   NewLibrary(config.LibraryDirs, config.LibraryIndex, config.FFprobeBinary),
+		cache:     NewCache(config.CacheFile, config.CacheTTL),
+		downloads: NewDownloadManager(config.DownloadDir, config.DownloadConcurrency, config.DownloadQuota, config.YTDLPBinary),
 	}
+	m.logger = config.Logger
+	if m.logger == nil {
+		m.logger = discardLogger()
+	}
+	m.scheduler = newScheduler(m, config.ScheduleFile)
+	m.registerDefaultProviders()
+	return m
 }
 
 // Play starts playback of a URL/file on the specified screen
 func (m *MediaLab) Play(ctx context.Context, url string, screen Screen) (*PlayerInstance, error) {
+	return m.PlayWith(ctx, url, screen, PlayOptions{})
+}
+
+// PlayWith starts playback of a URL/file with per-request options
+func (m *MediaLab) PlayWith(ctx context.Context, url string, screen Screen, opts PlayOptions) (*PlayerInstance, error) {
+	return m.start(ctx, screen, opts, url, url)
+}
+
+// Enqueue appends urls to the playlist of screen's player, which starts
+// playing them if it was idle. Without a player on screen it starts one,
+// as PlayWith would.
+func (m *MediaLab) Enqueue(ctx context.Context, screen Screen, urls []string, opts PlayOptions) (*PlayerInstance, error) {
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("%w: nothing to enqueue", ErrInvalidArgument)
+	}
+	instance, ok := m.GetPlayer(screen)
+	if !ok {
+		return m.start(ctx, screen, opts, urls[0], urls...)
+	}
+	for _, target := range m.downloads.Resolve(urls, m.cachedQuality(screen, opts)) {
+		if _, err := m.IPCCommand(screen, map[string]any{"command": []string{"loadfile", target, "append-play"}}); err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
+}
+
+// start launches mpv on screen with targets as its playlist; url is what
+// the instance reports as playing
+func (m *MediaLab) start(ctx context.Context, screen Screen, opts PlayOptions, url string, targets ...string) (*PlayerInstance, error) {
+	// Prefer offline copies over streaming. Resolving may rewrite the
+	// download index, so it happens before taking m.mu.
+	remote := isRemoteURL(targets[0])
+	targets = m.downloads.Resolve(targets, m.cachedQuality(screen, opts))
+
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if existing, ok := m.players[screen]; ok {
 		m.stopLocked(existing)
+		m.metrics.inc("medialab_mpv_restarts_total", "screen", metricScreen(screen))
 	}
+	m.metrics.inc("medialab_mpv_starts_total", "screen", metricScreen(screen))
 
-	args := []string{
-		"--profile=" + screen.ProfileName(),
-		"--input-ipc-server=" + screen.SocketPath(),
-		"--volume=" + strconv.Itoa(m.config.DefaultVolume),
-		"--", url,
+	var args []string
+	if screen == ScreenSpeaker {
+		args = m.speakerArgs()
+	} else {
+		args = []string{
+			"--profile=" + screen.ProfileName(),
+			"--input-ipc-server=" + m.socketPath(screen),
+			"--volume=" + strconv.Itoa(m.config.DefaultVolume),
+		}
+		args = append(args, m.qualityFor(screen, opts).mpvArgs()...)
+	}
+	if remote {
+		args = append(args, streamReconnectArgs...)
 	}
+	args = append(args, opts.mpvArgs...)
+	args = append(args, "--")
+	args = append(args, targets...)
 
-	cmd := exec.CommandContext(ctx, m.config.MPVBinary, args...)
+	// The player outlives the call starting it; ctx only bounds the wait
+	// for its socket
+	cmd := exec.Command(m.config.MPVBinary, args...)
 	if err := cmd.Start(); err != nil {
+		m.metrics.inc("medialab_mpv_start_failures_total", "screen", metricScreen(screen))
+		m.logger.Error("mpv start failed", "screen", screen.String(), "err", err)
 		return nil, fmt.Errorf("failed to start mpv: %w", err)
 	}
 
 	instance := &PlayerInstance{
 		Screen:    screen,
 		PID:       cmd.Process.Pid,
-		Socket:    screen.SocketPath(),
+		Socket:    m.socketPath(screen),
 		URL:       url,
 		StartedAt: time.Now(),
 		cmd:       cmd,
 	}
 	m.players[screen] = instance
 
-	if err := m.waitForSocket(ctx, screen.SocketPath()); err != nil {
+	if err := m.waitForSocket(ctx, m.socketPath(screen)); err != nil {
 		cmd.Process.Kill()
 		delete(m.players, screen)
+		m.metrics.inc("medialab_mpv_start_failures_total", "screen", metricScreen(screen))
+		m.logger.Error("mpv IPC socket not available", "screen", screen.String(), "err", err)
 		return nil, fmt.Errorf("mpv IPC socket not available: %w", err)
 	}
+	m.logger.Info("mpv started", "screen", screen.String(), "pid", instance.PID, "url", url)
 
+	m.prefetchMetadata(targets[0])
+	go m.watchStalls(instance)
 	return instance, nil
 }
 
+// speakerArgs returns the mpv options for the speaker. It always plays
+// the best audio stream, whatever quality was requested, and never opens a
+// window, not even for cover art.
+func (m *MediaLab) speakerArgs() []string {
+	volume := m.config.SpeakerVolume
+	if volume == 0 {
+		volume = m.config.DefaultVolume
+	}
+	args := []string{
+		"--input-ipc-server=" + m.socketPath(ScreenSpeaker),
+		"--volume=" + strconv.Itoa(volume),
+		"--no-video",
+		"--force-window=no",
+		"--audio-display=no",
+	}
+	if m.config.SpeakerAudioDevice != "" {
+		args = append(args, "--audio-device="+m.config.SpeakerAudioDevice)
+	}
+	return append(args, Quality{AudioOnly: true}.mpvArgs()...)
+}
+
 func (m *MediaLab) waitForSocket(ctx context.Context, socketPath string) error {
 	deadline := time.Now().Add(5 * time.Second)
 	for time.Now().Before(deadline) {
@@ -194,31 +433,132 @@ func (m *MediaLab) StopAll() {
 	}
 }
 
+var (
+	// ErrNoPlayer is returned when no mpv instance is listening on a screen's socket
+	ErrNoPlayer = errors.New("no player running")
+	// ErrIPCTimeout is returned when mpv does not answer within Config.IPCTimeout
+	ErrIPCTimeout = errors.New("IPC timeout")
+	// ErrPropertyUnavailable is returned when mpv has no value for a property
+	// (e.g. time-pos while nothing is loaded)
+	ErrPropertyUnavailable = errors.New("property unavailable")
+	// ErrInvalidArgument is returned for out-of-range or malformed request values
+	ErrInvalidArgument = errors.New("invalid argument")
+	// ErrNotFound is returned for unknown IDs and names (downloads, ...)
+	ErrNotFound = errors.New("not found")
+	// ErrUnauthorized is returned for HTTP requests with missing or invalid credentials
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrForbidden is returned when a client lacks the scope or screen access a request needs
+	ErrForbidden = errors.New("forbidden")
+	// ErrConflict is returned when a change clashes with existing state
+	// (e.g. overlapping schedule entries)
+	ErrConflict = errors.New("conflict")
+)
+
+// errorCode returns a stable machine-readable code for API responses
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrNoPlayer):
+		return "no_player"
+	case errors.Is(err, ErrIPCTimeout):
+		return "ipc_timeout"
+	case errors.Is(err, ErrPropertyUnavailable):
+		return "property_unavailable"
+	case errors.Is(err, ErrUnknownProvider):
+		return "unknown_provider"
+	case errors.Is(err, ErrInvalidArgument):
+		return "invalid_argument"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, ErrConflict):
+		return "conflict"
+	}
+	return "error"
+}
+
+// classifyIPCError wraps socket errors with ErrNoPlayer or ErrIPCTimeout
+func classifyIPCError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("%w: %v", ErrIPCTimeout, err)
+	case errors.Is(err, syscall.ENOENT), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, io.EOF), errors.Is(err, syscall.EPIPE), errors.Is(err, syscall.ECONNRESET):
+		return fmt.Errorf("%w: %v", ErrNoPlayer, err)
+	}
+	return err
+}
+
+// ipcResponseError converts an mpv reply error string into an error
+func ipcResponseError(msg string) error {
+	switch msg {
+	case "", "success":
+		return nil
+	case "property unavailable":
+		return ErrPropertyUnavailable
+	}
+	return errors.New(msg)
+}
+
+func (m *MediaLab) dialIPC(socketPath string) (net.Conn, error) {
+	conn, err := net.DialTimeout("unix", socketPath, m.config.IPCTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to IPC socket: %w", classifyIPCError(err))
+	}
+	conn.SetDeadline(time.Now().Add(m.config.IPCTimeout))
+	return conn, nil
+}
+
 // IPCCommand sends a raw IPC command to a screen's player
 func (m *MediaLab) IPCCommand(screen Screen, command map[string]any) (json.RawMessage, error) {
-	return m.sendIPCCommand(screen.SocketPath(), command)
+	return m.sendIPCCommand(m.socketPath(screen), command)
 }
 
-func (m *MediaLab) sendIPCCommand(socketPath string, command map[string]any) (json.RawMessage, error) {
-	conn, err := net.DialTimeout("unix", socketPath, m.config.IPCTimeout)
+func (m *MediaLab) sendIPCCommand(socketPath string, command map[string]any) (_ json.RawMessage, err error) {
+	defer func(start time.Time) { m.metrics.observeIPC(ipcCommandName(command), start, err) }(time.Now())
+
+	conn, err := m.dialIPC(socketPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to IPC socket: %w", err)
+		return nil, err
 	}
 	defer conn.Close()
-	conn.SetDeadline(time.Now().Add(m.config.IPCTimeout))
 
-	data, _ := json.Marshal(command)
+	// Tag the request so the reply can be told apart from async events
+	tagged := make(map[string]any, len(command)+1)
+	for k, v := range command {
+		tagged[k] = v
+	}
+	if _, ok := tagged["request_id"]; !ok {
+		tagged["request_id"] = 1
+	}
+	requestID, _ := json.Marshal(tagged["request_id"])
+
+	data, _ := json.Marshal(tagged)
 	data = append(data, '\n')
 	if _, err := conn.Write(data); err != nil {
-		return nil, fmt.Errorf("failed to send command: %w", err)
+		return nil, fmt.Errorf("failed to send command: %w", classifyIPCError(err))
 	}
 
-	buf := make([]byte, 4096)
-	n, err := conn.Read(buf)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", classifyIPCError(err))
+		}
+		var reply struct {
+			RequestID json.RawMessage `json:"request_id"`
+			Event     string          `json:"event"`
+		}
+		if json.Unmarshal(line, &reply) != nil || reply.Event != "" {
+			continue
+		}
+		if string(reply.RequestID) == string(requestID) {
+			return json.RawMessage(line), nil
+		}
 	}
-	return json.RawMessage(buf[:n]), nil
 }
 
 // PlayPause toggles play/pause
@@ -263,6 +603,12 @@ func (m *MediaLab) SetVolume(screen Screen, volume int) error {
 	return err
 }
 
+// SetMute mutes or unmutes audio, keeping the volume
+func (m *MediaLab) SetMute(screen Screen, mute bool) error {
+	_, err := m.IPCCommand(screen, map[string]any{"command": []any{"set_property", "mute", mute}})
+	return err
+}
+
 // Seek seeks to position (seconds) or relative offset
 func (m *MediaLab) Seek(screen Screen, position float64, relative bool) error {
 	mode := "absolute"
@@ -275,10 +621,86 @@ func (m *MediaLab) Seek(screen Screen, position float64, relative bool) error {
 
 // Fullscreen toggles fullscreen
 func (m *MediaLab) Fullscreen(screen Screen) error {
+	if screen == ScreenSpeaker {
+		return fmt.Errorf("%w: the speaker has no window", ErrInvalidArgument)
+	}
 	_, err := m.IPCCommand(screen, map[string]any{"command": []string{"cycle", "fullscreen"}})
 	return err
 }
 
+// SetSpeed sets the playback speed multiplier (1.0 = normal). With pitch
+// correction on, audio keeps its original pitch at non-1.0 speeds.
+func (m *MediaLab) SetSpeed(screen Screen, speed float64, pitchCorrection bool) error {
+	if speed < 0.01 || speed > 100 {
+		return fmt.Errorf("%w: speed out of range (0.01-100): %g", ErrInvalidArgument, speed)
+	}
+	if _, err := m.IPCCommand(screen, map[string]any{"command": []any{"set_property", "audio-pitch-correction", pitchCorrection}}); err != nil {
+		return err
+	}
+	_, err := m.IPCCommand(screen, map[string]any{"command": []any{"set_property", "speed", speed}})
+	return err
+}
+
+// FrameStep advances one frame and pauses
+func (m *MediaLab) FrameStep(screen Screen) error {
+	_, err := m.IPCCommand(screen, map[string]any{"command": []string{"frame-step"}})
+	return err
+}
+
+// FrameBackStep goes back one frame and pauses
+func (m *MediaLab) FrameBackStep(screen Screen) error {
+	_, err := m.IPCCommand(screen, map[string]any{"command": []string{"frame-back-step"}})
+	return err
+}
+
+// LoopInfinite loops forever when passed as a loop count
+const LoopInfinite = -1
+
+func loopValue(count int) string {
+	switch {
+	case count < 0:
+		return "inf"
+	case count == 0:
+		return "no"
+	default:
+		return strconv.Itoa(count)
+	}
+}
+
+// SetLoopFile repeats the current file count times (LoopInfinite = forever, 0 = off)
+func (m *MediaLab) SetLoopFile(screen Screen, count int) error {
+	_, err := m.IPCCommand(screen, map[string]any{"command": []any{"set_property", "loop-file", loopValue(count)}})
+	return err
+}
+
+// SetLoopPlaylist repeats the playlist count times (LoopInfinite = forever, 0 = off)
+func (m *MediaLab) SetLoopPlaylist(screen Screen, count int) error {
+	_, err := m.IPCCommand(screen, map[string]any{"command": []any{"set_property", "loop-playlist", loopValue(count)}})
+	return err
+}
+
+// SetABLoop loops between two positions (seconds)
+func (m *MediaLab) SetABLoop(screen Screen, a, b float64) error {
+	if b <= a {
+		return fmt.Errorf("%w: A-B loop b (%.1f) must be after a (%.1f)", ErrInvalidArgument, b, a)
+	}
+	if _, err := m.IPCCommand(screen, map[string]any{"command": []any{"set_property", "ab-loop-a", a}}); err != nil {
+		return err
+	}
+	_, err := m.IPCCommand(screen, map[string]any{"command": []any{"set_property", "ab-loop-b", b}})
+	return err
+}
+
+// ClearLoop disables file, playlist and A-B looping
+func (m *MediaLab) ClearLoop(screen Screen) error {
+	for _, prop := range []string{"loop-file", "loop-playlist", "ab-loop-a", "ab-loop-b"} {
+		if _, err := m.IPCCommand(screen, map[string]any{"command": []any{"set_property", prop, "no"}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // GetProperty retrieves a property from the player
 func (m *MediaLab) GetProperty(screen Screen, property string) (any, error) {
 	resp, err := m.IPCCommand(screen, map[string]any{"command": []string{"get_property", property}})
@@ -292,46 +714,204 @@ func (m *MediaLab) GetProperty(screen Screen, property string) (any, error) {
 	if err := json.Unmarshal(resp, &result); err != nil {
 		return nil, err
 	}
-	if result.Error != "" && result.Error != "success" {
-		return nil, errors.New(result.Error)
+	if err := ipcResponseError(result.Error); err != nil {
+		return nil, fmt.Errorf("%s: %w", property, err)
 	}
 	return result.Data, nil
 }
 
-// PlaybackInfo contains current playback state
-type PlaybackInfo struct {
-	Screen     Screen  `json:"screen"`
-	Playing    bool    `json:"playing"`
-	Paused     bool    `json:"paused"`
-	Position   float64 `json:"position"`
-	Duration   float64 `json:"duration"`
-	Volume     float64 `json:"volume"`
-	Filename   string  `json:"filename"`
-	MediaTitle string  `json:"media_title"`
-	Fullscreen bool    `json:"fullscreen"`
-	PercentPos float64 `json:"percent_pos"`
-}
-
-// GetPlaybackInfo returns current playback information
-func (m *MediaLab) GetPlaybackInfo(screen Screen) (*PlaybackInfo, error) {
-	info := &PlaybackInfo{Screen: screen}
-	props := []string{"pause", "time-pos", "duration", "volume", "filename", "media-title", "fullscreen", "percent-pos"}
+func checkIPCResponse(resp json.RawMessage) error {
+	var result struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(resp, &result); err != nil {
+		return err
+	}
+	return ipcResponseError(result.Error)
+}
+
+// GetProperties fetches several properties over a single IPC connection.
+// Properties the player reports as unavailable are omitted from the result.
+func (m *MediaLab) GetProperties(screen Screen, props []string) (map[string]any, error) {
+	start := time.Now()
+	vals, err := m.getProperties(m.socketPath(screen), props)
+	m.metrics.observeIPC("get_property", start, err)
+	return vals, err
+}
+
+func (m *MediaLab) getProperties(socketPath string, props []string) (map[string]any, error) {
+	conn, err := m.dialIPC(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
 
-	for _, prop := range props {
-		val, err := m.GetProperty(screen, prop)
+	// Pipeline every request, tagged with request_id = index+1
+	var batch []byte
+	for i, prop := range props {
+		data, _ := json.Marshal(map[string]any{
+			"command":    []string{"get_property", prop},
+			"request_id": i + 1,
+		})
+		batch = append(batch, data...)
+		batch = append(batch, '\n')
+	}
+	if _, err := conn.Write(batch); err != nil {
+		return nil, fmt.Errorf("failed to send command: %w", classifyIPCError(err))
+	}
+
+	values := make(map[string]any, len(props))
+	reader := bufio.NewReader(conn)
+	for pending := len(props); pending > 0; {
+		line, err := reader.ReadBytes('\n')
 		if err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", classifyIPCError(err))
+		}
+		var resp struct {
+			RequestID int    `json:"request_id"`
+			Data      any    `json:"data"`
+			Error     string `json:"error"`
+			Event     string `json:"event"`
+		}
+		// Skip events and anything that is not a reply to this batch
+		if err := json.Unmarshal(line, &resp); err != nil || resp.Event != "" {
+			continue
+		}
+		if resp.RequestID < 1 || resp.RequestID > len(props) {
 			continue
 		}
+		pending--
+		if resp.Error == "success" {
+			values[props[resp.RequestID-1]] = resp.Data
+		}
+	}
+	return values, nil
+}
+
+// PlaybackState summarizes what a screen's player is doing
+type PlaybackState string
+
+const (
+	StateStopped   PlaybackState = "stopped"   // no player running
+	StateIdle      PlaybackState = "idle"      // player running, nothing loaded
+	StateLoading   PlaybackState = "loading"   // file opening, no position yet
+	StatePlaying   PlaybackState = "playing"   // actively playing
+	StatePaused    PlaybackState = "paused"    // paused by user
+	StateBuffering PlaybackState = "buffering" // stalled waiting for the cache
+	StateEnded     PlaybackState = "ended"     // reached end of file (keep-open)
+)
+
+// playbackState derives the state from fetched player properties
+func playbackState(vals map[string]any) PlaybackState {
+	idle, _ := vals["idle-active"].(bool)
+	eof, _ := vals["eof-reached"].(bool)
+	buffering, _ := vals["paused-for-cache"].(bool)
+	paused, _ := vals["pause"].(bool)
+	_, hasPos := vals["time-pos"]
+
+	switch {
+	case idle:
+		return StateIdle
+	case eof:
+		return StateEnded
+	case buffering:
+		return StateBuffering
+	case !hasPos:
+		return StateLoading
+	case paused:
+		return StatePaused
+	}
+	return StatePlaying
+}
+
+// PlaybackInfo contains current playback state
+type PlaybackInfo struct {
+	Screen     Screen        `json:"screen"`
+	State      PlaybackState `json:"state"`
+	Playing    bool          `json:"playing"`
+	Paused     bool          `json:"paused"`
+	Idle       bool          `json:"idle"`
+	Position   float64       `json:"position"`
+	Duration   float64       `json:"duration"`
+	Volume     float64       `json:"volume"`
+	Mute       bool          `json:"mute"`
+	Filename   string        `json:"filename"`
+	MediaTitle string        `json:"media_title"`
+	Fullscreen bool          `json:"fullscreen"`
+	PercentPos float64       `json:"percent_pos"`
+	Speed      float64       `json:"speed"`
+
+	Path      string `json:"path"`
+	StreamURL string `json:"stream_url,omitempty"` // resolved stream (differs from path for yt-dlp URLs)
+
+	Width      int     `json:"width,omitempty"`
+	Height     int     `json:"height,omitempty"`
+	FPS        float64 `json:"fps,omitempty"`
+	VideoCodec string  `json:"video_codec,omitempty"`
+	AudioCodec string  `json:"audio_codec,omitempty"`
+	HWDec      string  `json:"hwdec,omitempty"` // hwdec in use ("no" = software decoding)
+
+	CacheDuration  float64 `json:"cache_duration"` // seconds buffered ahead
+	PausedForCache bool    `json:"paused_for_cache"`
+	DroppedFrames  int     `json:"dropped_frames"`
+
+	PlaylistPos   int    `json:"playlist_pos"` // 1-based, 0 = none
+	PlaylistCount int    `json:"playlist_count"`
+	Chapter       int    `json:"chapter"` // 1-based, 0 = none
+	ChapterTitle  string `json:"chapter_title,omitempty"`
+
+	LoopFile     string   `json:"loop_file"`     // "no", "inf" or a repeat count
+	LoopPlaylist string   `json:"loop_playlist"` // "no", "inf" or a repeat count
+	ABLoopA      *float64 `json:"ab_loop_a,omitempty"`
+	ABLoopB      *float64 `json:"ab_loop_b,omitempty"`
+
+	Uploader   string `json:"uploader,omitempty"`
+	UploadDate string `json:"upload_date,omitempty"`
+	Thumbnail  string `json:"thumbnail,omitempty"`
+
+	Live       bool   `json:"live"`              // live stream or radio: no duration, position is time listened
+	Station    string `json:"station,omitempty"` // configured station name, else the stream's ICY name
+	Track      string `json:"track,omitempty"`   // current track announced by the stream (ICY title)
+	Reconnects int    `json:"reconnects,omitempty"`
+}
+
+var playbackProps = []string{
+	"pause", "idle-active", "time-pos", "duration", "volume", "mute", "filename", "media-title",
+	"fullscreen", "percent-pos", "speed", "path", "stream-open-filename",
+	"width", "height", "container-fps", "video-codec", "audio-codec-name", "hwdec-current",
+	"demuxer-cache-duration", "paused-for-cache", "frame-drop-count", "decoder-frame-drop-count",
+	"playlist-pos-1", "playlist-count", "chapter", "chapter-metadata/title",
+	"loop-file", "loop-playlist", "ab-loop-a", "ab-loop-b", "metadata", "eof-reached",
+}
+
+// GetPlaybackInfo returns current playback information, fetched in one
+// batched IPC round-trip. A screen without a player reports StateStopped
+// rather than an error; IPC failures such as ErrIPCTimeout are returned.
+func (m *MediaLab) GetPlaybackInfo(screen Screen) (*PlaybackInfo, error) {
+	vals, err := m.GetProperties(screen, playbackProps)
+	if errors.Is(err, ErrNoPlayer) {
+		return &PlaybackInfo{Screen: screen, State: StateStopped, LoopFile: "no", LoopPlaylist: "no"}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	info := &PlaybackInfo{Screen: screen, State: playbackState(vals)}
+	var tags map[string]any
+	for prop, val := range vals {
 		switch prop {
 		case "pause":
 			info.Paused, _ = val.(bool)
-			info.Playing = !info.Paused
+		case "idle-active":
+			info.Idle, _ = val.(bool)
 		case "time-pos":
 			info.Position, _ = val.(float64)
 		case "duration":
 			info.Duration, _ = val.(float64)
 		case "volume":
 			info.Volume, _ = val.(float64)
+		case "mute":
+			info.Mute, _ = val.(bool)
 		case "filename":
 			info.Filename, _ = val.(string)
 		case "media-title":
@@ -340,60 +920,131 @@ func (m *MediaLab) GetPlaybackInfo(screen Screen) (*PlaybackInfo, error) {
 			info.Fullscreen, _ = val.(bool)
 		case "percent-pos":
 			info.PercentPos, _ = val.(float64)
+		case "speed":
+			info.Speed, _ = val.(float64)
+		case "path":
+			info.Path, _ = val.(string)
+		case "stream-open-filename":
+			info.StreamURL, _ = val.(string)
+		case "width":
+			info.Width = intValue(val)
+		case "height":
+			info.Height = intValue(val)
+		case "container-fps":
+			info.FPS, _ = val.(float64)
+		case "video-codec":
+			info.VideoCodec, _ = val.(string)
+		case "audio-codec-name":
+			info.AudioCodec, _ = val.(string)
+		case "hwdec-current":
+			info.HWDec, _ = val.(string)
+		case "demuxer-cache-duration":
+			info.CacheDuration, _ = val.(float64)
+		case "paused-for-cache":
+			info.PausedForCache, _ = val.(bool)
+		case "frame-drop-count", "decoder-frame-drop-count":
+			info.DroppedFrames += intValue(val)
+		case "playlist-pos-1":
+			info.PlaylistPos = intValue(val)
+		case "playlist-count":
+			info.PlaylistCount = intValue(val)
+		case "chapter":
+			if _, ok := val.(float64); ok {
+				info.Chapter = intValue(val) + 1
+			}
+		case "chapter-metadata/title":
+			info.ChapterTitle, _ = val.(string)
+		case "loop-file":
+			info.LoopFile = loopString(val)
+		case "loop-playlist":
+			info.LoopPlaylist = loopString(val)
+		case "ab-loop-a":
+			if f, ok := val.(float64); ok {
+				info.ABLoopA = &f
+			}
+		case "ab-loop-b":
+			if f, ok := val.(float64); ok {
+				info.ABLoopB = &f
+			}
+		case "metadata":
+			tags, _ = val.(map[string]any)
 		}
 	}
+	info.Playing = info.State == StatePlaying
+	if info.StreamURL == info.Path {
+		info.StreamURL = ""
+	}
+	if info.LoopFile == "" {
+		info.LoopFile = "no"
+	}
+	if info.LoopPlaylist == "" {
+		info.LoopPlaylist = "no"
+	}
+
+	if meta := m.cachedMetadata(info.Path); meta != nil {
+		info.Uploader = meta.Uploader
+		info.UploadDate = meta.UploadDate
+		info.Thumbnail = meta.Thumbnail
+	}
+	if info.Uploader == "" {
+		info.Uploader = tagValue(tags, "uploader", "artist", "album_artist")
+	}
+	if info.UploadDate == "" {
+		info.UploadDate = tagValue(tags, "upload_date", "date")
+	}
+
+	info.Live = isLive(vals, m.cachedMetadata(info.Path))
+	info.Track = tagValue(tags, "icy-title")
+	if st, ok := m.stationByURL(info.Path); ok {
+		info.Station = st.Name
+	} else {
+		info.Station = tagValue(tags, "icy-name")
+	}
+	if instance, ok := m.GetPlayer(screen); ok {
+		info.Reconnects = int(instance.reconnects.Load())
+	}
 	return info, nil
 }
 
-// YouTubeResult represents a search result
-type YouTubeResult struct {
-	ID       string `json:"id"`
-	Title    string `json:"title"`
-	Channel  string `json:"channel"`
-	Duration string `json:"duration"`
-	URL      string `json:"url"`
+func intValue(val any) int {
+	f, _ := val.(float64)
+	return int(f)
 }
 
-// SearchYouTube searches YouTube and returns results
-func (m *MediaLab) SearchYouTube(ctx context.Context, query string, maxResults int) ([]YouTubeResult, error) {
-	if maxResults <= 0 {
-		maxResults = 10
-	}
-	args := []string{
-		"ytsearch" + strconv.Itoa(maxResults) + ":" + query,
-		"--flat-playlist", "--dump-json", "--no-download",
-	}
-	cmd := exec.CommandContext(ctx, m.config.YTDLPBinary, args...)
-	output, err := cmd.Output()
-	if err != nil {
-		return nil, fmt.Errorf("yt-dlp search failed: %w", err)
+// tagValue returns the first non-empty metadata tag, matching keys case-insensitively
+func tagValue(tags map[string]any, keys ...string) string {
+	for _, key := range keys {
+		for k, v := range tags {
+			if s, ok := v.(string); ok && s != "" && strings.EqualFold(k, key) {
+				return s
+			}
+		}
 	}
+	return ""
+}
 
-	var results []YouTubeResult
-	for _, line := range strings.Split(string(output), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
-		var entry struct {
-			ID         string `json:"id"`
-			Title      string `json:"title"`
-			Channel    string `json:"channel"`
-			Duration   int    `json:"duration"`
-			WebpageURL string `json:"webpage_url"`
-		}
-		if err := json.Unmarshal([]byte(line), &entry); err != nil {
-			continue
+// loopString normalizes mpv's loop-file/loop-playlist values (false, "inf", N)
+func loopString(val any) string {
+	switch v := val.(type) {
+	case bool:
+		if v {
+			return "inf"
 		}
-		results = append(results, YouTubeResult{
-			ID:       entry.ID,
-			Title:    entry.Title,
-			Channel:  entry.Channel,
-			Duration: formatDuration(entry.Duration),
-			URL:      entry.WebpageURL,
-		})
+		return "no"
+	case float64:
+		return strconv.Itoa(int(v))
+	case string:
+		return v
 	}
-	return results, nil
+	return "no"
+}
+
+// YouTubeResult is a search result from the youtube provider
+type YouTubeResult = SearchResult
+
+// SearchYouTube searches YouTube and returns results
+func (m *MediaLab) SearchYouTube(ctx context.Context, query string, maxResults int) ([]YouTubeResult, error) {
+	return m.Search(ctx, "youtube", query, SearchOptions{MaxResults: maxResults})
 }
 
 func formatDuration(seconds int) string {
@@ -417,6 +1068,47 @@ func (m *MediaLab) PlayYouTubeSearch(ctx context.Context, query string, screen S
 	return m.Play(ctx, results[0].URL, screen)
 }
 
+// PlayQuery plays the station named query, else the best local library
+// match, falling back to the first YouTube result when nothing in the
+// library matches well.
+// Resolved URLs are cached, so repeating a query skips the lookup.
+func (m *MediaLab) PlayQuery(ctx context.Context, query string, screen Screen) (*PlayerInstance, error) {
+	url, err := m.ResolveQuery(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return m.Play(ctx, url, screen)
+}
+
+// ResolveQuery returns the URL or file PlayQuery would play for query
+func (m *MediaLab) ResolveQuery(ctx context.Context, query string) (string, error) {
+	if st, err := m.Station(query); err == nil {
+		return st.URL, nil
+	}
+	key := "resolve|" + strings.ToLower(strings.Join(strings.Fields(query), " "))
+	var url string
+	if m.cache.Get(key, &url) {
+		if _, err := os.Stat(url); err == nil || isRemoteURL(url) {
+			return url, nil
+		}
+	}
+
+	if matches := m.library.Search(query, 1); len(matches) > 0 && matches[0].Score >= libraryPlayThreshold {
+		url = matches[0].Path
+	} else {
+		results, err := m.SearchYouTube(ctx, query, 1)
+		if err != nil {
+			return "", err
+		}
+		if len(results) == 0 {
+			return "", errors.New("no results found")
+		}
+		url = results[0].URL
+	}
+	m.cache.Set(key, url)
+	return url, nil
+}
+
 // ListPlayers returns all active player instances
 func (m *MediaLab) ListPlayers() []*PlayerInstance {
 	m.mu.RLock()
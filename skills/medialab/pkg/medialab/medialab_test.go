@@ -1,7 +1,18 @@
 package medialab
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestScreenSocketPath(t *testing.T) {
@@ -13,6 +24,7 @@ func TestScreenSocketPath(t *testing.T) {
 		{Screen2, "/tmp/mpv-screen2"},
 		{Screen3, "/tmp/mpv-screen3"},
 		{Screen4, "/tmp/mpv-screen4"},
+		{ScreenSpeaker, "/tmp/mpv-speaker"},
 	}
 
 	for _, tt := range tests {
@@ -32,6 +44,7 @@ func TestScreenProfileName(t *testing.T) {
 		{Screen2, "screen2"},
 		{Screen3, "screen3"},
 		{Screen4, "screen4"},
+		{ScreenSpeaker, "speaker"},
 	}
 
 	for _, tt := range tests {
@@ -42,6 +55,62 @@ func TestScreenProfileName(t *testing.T) {
 	}
 }
 
+func TestParseScreen(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Screen
+		wantErr bool
+	}{
+		{"1", Screen1, false},
+		{"4", Screen4, false},
+		{"speaker", ScreenSpeaker, false},
+		{" Speaker ", ScreenSpeaker, false},
+		{"0", Screen1, true},
+		{"5", Screen1, true},
+		{"tv", Screen1, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseScreen(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseScreen(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestScreenArg(t *testing.T) {
+	var input struct {
+		A screenArg `json:"a"`
+		B screenArg `json:"b"`
+		C screenArg `json:"c"`
+	}
+	if err := json.Unmarshal([]byte(`{"a": 3, "b": "speaker", "c": 9}`), &input); err != nil {
+		t.Fatal(err)
+	}
+	if Screen(input.A) != Screen3 || Screen(input.B) != ScreenSpeaker || Screen(input.C) != Screen1 {
+		t.Errorf("screens = %v, %v, %v; want screen 3, speaker, screen 1", Screen(input.A), Screen(input.B), Screen(input.C))
+	}
+	if screenID(ScreenSpeaker) != "speaker" || screenID(Screen2) != 2 {
+		t.Errorf("screenID() = %v, %v", screenID(ScreenSpeaker), screenID(Screen2))
+	}
+}
+
+func TestSpeakerArgs(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Quality = Quality{MaxHeight: 1080}
+	cfg.SpeakerAudioDevice = "pulse/headphones"
+	args := strings.Join(New(cfg).speakerArgs(), " ")
+
+	for _, want := range []string{"--input-ipc-server=/tmp/mpv-speaker", "--volume=80", "--no-video", "--force-window=no", "--audio-device=pulse/headphones", "--ytdl-format=bestaudio/best", "--vid=no"} {
+		if !strings.Contains(args, want) {
+			t.Errorf("speakerArgs() = %s, missing %s", args, want)
+		}
+	}
+	if strings.Contains(args, "--profile") || strings.Contains(args, "height") {
+		t.Errorf("speakerArgs() = %s, want no screen profile or video quality", args)
+	}
+}
+
 func TestDefaultConfig(t *testing.T) {
 	cfg := DefaultConfig()
 
@@ -128,3 +197,462 @@ func TestFormatDuration(t *testing.T) {
 		}
 	}
 }
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"30", 30, false},
+		{"1.5", 1.5, false},
+		{"1:30", 90, false},
+		{"1:02:03", 3723, false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"1:-5", 0, true},
+		{"1:2:3:4", 0, true},
+		{"inf", 0, true},
+		{"nan", 0, true},
+		{"1:NaN", 0, true},
+		{"1e309", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseTimestamp(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseTimestamp(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseTimestamp(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestIsRemoteURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{"https://youtube.com/watch?v=abc", true},
+		{"rtmp://example.com/live", true},
+		{"file:///home/user/video.mp4", false},
+		{"/home/user/video.mp4", false},
+		{"./video.mp4", false},
+	}
+
+	for _, tt := range tests {
+		if got := isRemoteURL(tt.in); got != tt.want {
+			t.Errorf("isRemoteURL(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+// fakeMPV reads n get_property requests on a unix socket and answers them in
+// reverse order after an unsolicited event, like a busy mpv would
+func fakeMPV(t *testing.T, n int, props map[string]any) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "mpv.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		type request struct {
+			Command   []string `json:"command"`
+			RequestID int      `json:"request_id"`
+		}
+		var reqs []request
+		scanner := bufio.NewScanner(conn)
+		for len(reqs) < n && scanner.Scan() {
+			var req request
+			json.Unmarshal(scanner.Bytes(), &req)
+			reqs = append(reqs, req)
+		}
+
+		fmt.Fprintln(conn, `{"event":"playback-restart"}`)
+		for i := len(reqs) - 1; i >= 0; i-- {
+			resp := map[string]any{"request_id": reqs[i].RequestID, "error": "property unavailable"}
+			if val, ok := props[reqs[i].Command[1]]; ok {
+				resp["error"] = "success"
+				resp["data"] = val
+			}
+			data, _ := json.Marshal(resp)
+			fmt.Fprintln(conn, string(data))
+		}
+	}()
+	return socket
+}
+
+// newTestLab returns a MediaLab keeping its sockets and state in a
+// temporary directory and writing no audit log
+func newTestLab(t *testing.T) *MediaLab {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.SocketDir = dir
+	cfg.CacheFile = filepath.Join(dir, "cache.json")
+	cfg.LastSearchFile = filepath.Join(dir, "last-search.json")
+	cfg.LibraryIndex = filepath.Join(dir, "library.json")
+	cfg.DownloadDir = filepath.Join(dir, "downloads")
+	cfg.MacroFile = filepath.Join(dir, "macros.json")
+	cfg.SceneDir = filepath.Join(dir, "scenes")
+	cfg.ScheduleFile = filepath.Join(dir, "schedule.json")
+	cfg.AuditFile = ""
+	cfg.IPCTimeout = time.Second
+	return New(cfg)
+}
+
+// fakePlayer is an mpv listening on a screen's socket. It keeps the
+// properties it is set, answers get_property from them and records every
+// other command.
+type fakePlayer struct {
+	mu       sync.Mutex
+	props    map[string]any
+	history  map[string][]any // values set, per property
+	commands [][]any
+}
+
+func newFakePlayer(t *testing.T, lab *MediaLab, screen Screen, props map[string]any) *fakePlayer {
+	t.Helper()
+	p := &fakePlayer{props: make(map[string]any), history: make(map[string][]any)}
+	for k, v := range props {
+		p.props[k] = v
+	}
+	ln, err := net.Listen("unix", lab.socketPath(screen))
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	return p
+}
+
+func (p *fakePlayer) serve(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		var req struct {
+			Command   []any           `json:"command"`
+			RequestID json.RawMessage `json:"request_id"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &req) != nil || len(req.Command) == 0 {
+			continue
+		}
+		resp := map[string]any{"request_id": req.RequestID, "error": "success"}
+		name, _ := req.Command[0].(string)
+		p.mu.Lock()
+		switch {
+		case name == "get_property" && len(req.Command) > 1:
+			if val, ok := p.props[fmt.Sprint(req.Command[1])]; ok {
+				resp["data"] = val
+			} else {
+				resp["error"] = "property unavailable"
+			}
+		case name == "set_property" && len(req.Command) > 2:
+			prop := fmt.Sprint(req.Command[1])
+			p.props[prop] = req.Command[2]
+			if p.history != nil {
+				p.history[prop] = append(p.history[prop], req.Command[2])
+			}
+		default:
+			p.commands = append(p.commands, req.Command)
+		}
+		p.mu.Unlock()
+		data, _ := json.Marshal(resp)
+		fmt.Fprintln(conn, string(data))
+	}
+}
+
+// prop returns a property as the player holds it
+func (p *fakePlayer) prop(name string) any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.props[name]
+}
+
+// set changes a property, as mpv does while it plays
+func (p *fakePlayer) set(name string, value any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.props[name] = value
+}
+
+// setValues returns the values name was set to over IPC, in order
+func (p *fakePlayer) setValues(name string) []any {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]any(nil), p.history[name]...)
+}
+
+// ran returns the names of the commands other than properties, in order
+func (p *fakePlayer) ran() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var names []string
+	for _, cmd := range p.commands {
+		names = append(names, fmt.Sprint(cmd[0]))
+	}
+	return names
+}
+
+// fakeMPVBinary returns an mpv that serves a fakePlayer on the socket of
+// its --input-ipc-server option until told to quit. It runs the test
+// binary again as TestFakeMPVProcess.
+func fakeMPVBinary(t *testing.T) string {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeScript(t, t.TempDir(), "mpv", "MEDIALAB_FAKE_MPV=1 exec '"+exe+"' -test.run='^TestFakeMPVProcess$' -- \"$@\"\n")
+}
+
+func TestFakeMPVProcess(t *testing.T) {
+	if os.Getenv("MEDIALAB_FAKE_MPV") == "" {
+		t.Skip("mpv stand-in started by fakeMPVBinary")
+	}
+	var socket string
+	for _, arg := range os.Args {
+		if s, ok := strings.CutPrefix(arg, "--input-ipc-server="); ok {
+			socket = s
+		}
+	}
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		os.Exit(2)
+	}
+	p := &fakePlayer{props: map[string]any{"pause": false, "volume": 100.0, "time-pos": 0.0}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+	for !slices.Contains(p.ran(), "quit") {
+		time.Sleep(10 * time.Millisecond)
+	}
+	ln.Close()
+	os.Exit(0)
+}
+
+// playerAlive reports whether the player on screen still answers
+func playerAlive(lab *MediaLab, screen Screen) bool {
+	_, err := lab.GetProperty(screen, "pause")
+	return err == nil
+}
+
+func TestLoopModes(t *testing.T) {
+	lab := newTestLab(t)
+	player := newFakePlayer(t, lab, Screen2, nil)
+
+	for _, tt := range []struct {
+		count int
+		want  string
+	}{
+		{LoopInfinite, "inf"},
+		{3, "3"},
+		{0, "no"},
+	} {
+		if err := lab.SetLoopFile(Screen2, tt.count); err != nil {
+			t.Fatalf("SetLoopFile(%d) error = %v", tt.count, err)
+		}
+		if got := player.prop("loop-file"); got != tt.want {
+			t.Errorf("SetLoopFile(%d): loop-file = %v, want %s", tt.count, got, tt.want)
+		}
+		if err := lab.SetLoopPlaylist(Screen2, tt.count); err != nil {
+			t.Fatalf("SetLoopPlaylist(%d) error = %v", tt.count, err)
+		}
+		if got := player.prop("loop-playlist"); got != tt.want {
+			t.Errorf("SetLoopPlaylist(%d): loop-playlist = %v, want %s", tt.count, got, tt.want)
+		}
+	}
+
+	if err := lab.SetABLoop(Screen2, 85, 70); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("SetABLoop(85, 70) error = %v, want ErrInvalidArgument", err)
+	}
+	if player.prop("ab-loop-a") != nil {
+		t.Error("a rejected A-B loop set ab-loop-a")
+	}
+	if err := lab.SetABLoop(Screen2, 70, 85); err != nil {
+		t.Fatalf("SetABLoop() error = %v", err)
+	}
+	if a, b := player.prop("ab-loop-a"), player.prop("ab-loop-b"); a != 70.0 || b != 85.0 {
+		t.Errorf("A-B loop = %v-%v, want 70-85", a, b)
+	}
+
+	lab.SetLoopFile(Screen2, LoopInfinite)
+	lab.SetLoopPlaylist(Screen2, 2)
+	if err := lab.ClearLoop(Screen2); err != nil {
+		t.Fatalf("ClearLoop() error = %v", err)
+	}
+	for _, prop := range []string{"loop-file", "loop-playlist", "ab-loop-a", "ab-loop-b"} {
+		if got := player.prop(prop); got != "no" {
+			t.Errorf("after ClearLoop %s = %v, want no", prop, got)
+		}
+	}
+
+	if err := lab.SetLoopFile(Screen3, 1); !errors.Is(err, ErrNoPlayer) {
+		t.Errorf("SetLoopFile() without a player error = %v, want ErrNoPlayer", err)
+	}
+}
+
+func TestSetSpeed(t *testing.T) {
+	lab := newTestLab(t)
+	player := newFakePlayer(t, lab, Screen1, map[string]any{"speed": 1.0, "audio-pitch-correction": true})
+
+	for _, speed := range []float64{0, 0.005, -1, 100.5} {
+		if err := lab.SetSpeed(Screen1, speed, true); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("SetSpeed(%g) error = %v, want ErrInvalidArgument", speed, err)
+		}
+	}
+	if got := player.prop("speed"); got != 1.0 {
+		t.Errorf("rejected speeds changed speed to %v", got)
+	}
+
+	if err := lab.SetSpeed(Screen1, 1.5, false); err != nil {
+		t.Fatalf("SetSpeed(1.5, false) error = %v", err)
+	}
+	if speed, pitch := player.prop("speed"), player.prop("audio-pitch-correction"); speed != 1.5 || pitch != false {
+		t.Errorf("speed = %v, pitch correction = %v; want 1.5, false", speed, pitch)
+	}
+	for _, speed := range []float64{0.01, 100} {
+		if err := lab.SetSpeed(Screen1, speed, true); err != nil {
+			t.Errorf("SetSpeed(%g) error = %v", speed, err)
+		}
+	}
+	if speed, pitch := player.prop("speed"), player.prop("audio-pitch-correction"); speed != 100.0 || pitch != true {
+		t.Errorf("speed = %v, pitch correction = %v; want 100, true", speed, pitch)
+	}
+}
+
+func TestFrameStep(t *testing.T) {
+	lab := newTestLab(t)
+	player := newFakePlayer(t, lab, ScreenSpeaker, nil)
+	if err := lab.FrameStep(ScreenSpeaker); err != nil {
+		t.Fatalf("FrameStep() error = %v", err)
+	}
+	if err := lab.FrameBackStep(ScreenSpeaker); err != nil {
+		t.Fatalf("FrameBackStep() error = %v", err)
+	}
+	if got := strings.Join(player.ran(), ","); got != "frame-step,frame-back-step" {
+		t.Errorf("commands = %s, want frame-step,frame-back-step", got)
+	}
+	if err := lab.FrameStep(Screen4); !errors.Is(err, ErrNoPlayer) {
+		t.Errorf("FrameStep() without a player error = %v, want ErrNoPlayer", err)
+	}
+}
+
+func TestLoopCount(t *testing.T) {
+	n := func(v int) *int { return &v }
+	for _, tt := range []struct {
+		count *int
+		want  int
+	}{
+		{nil, LoopInfinite},
+		{n(-1), LoopInfinite},
+		{n(0), 0},
+		{n(4), 4},
+	} {
+		if got := loopCount(tt.count); got != tt.want {
+			t.Errorf("loopCount(%v) = %d, want %d", tt.count, got, tt.want)
+		}
+	}
+}
+
+func TestGetPropertiesBatched(t *testing.T) {
+	socket := fakeMPV(t, 4, map[string]any{
+		"pause":       true,
+		"time-pos":    12.5,
+		"media-title": "Test",
+	})
+
+	lab := New(nil)
+	vals, err := lab.getProperties(socket, []string{"pause", "time-pos", "media-title", "chapter"})
+	if err != nil {
+		t.Fatalf("getProperties() error = %v", err)
+	}
+
+	if vals["pause"] != true {
+		t.Errorf("pause = %v, want true", vals["pause"])
+	}
+	if vals["time-pos"] != 12.5 {
+		t.Errorf("time-pos = %v, want 12.5", vals["time-pos"])
+	}
+	if vals["media-title"] != "Test" {
+		t.Errorf("media-title = %v, want %q", vals["media-title"], "Test")
+	}
+	if _, ok := vals["chapter"]; ok {
+		t.Error("unavailable property chapter should be omitted")
+	}
+}
+
+func TestGetPropertiesNoPlayer(t *testing.T) {
+	lab := New(nil)
+	_, err := lab.getProperties(filepath.Join(t.TempDir(), "missing.sock"), []string{"pause"})
+	if !errors.Is(err, ErrNoPlayer) {
+		t.Errorf("getProperties() on missing socket error = %v, want ErrNoPlayer", err)
+	}
+}
+
+func TestPlaybackState(t *testing.T) {
+	tests := []struct {
+		name string
+		vals map[string]any
+		want PlaybackState
+	}{
+		{"idle", map[string]any{"idle-active": true, "pause": false}, StateIdle},
+		{"ended", map[string]any{"eof-reached": true, "time-pos": 10.0}, StateEnded},
+		{"buffering", map[string]any{"paused-for-cache": true, "time-pos": 10.0}, StateBuffering},
+		{"loading", map[string]any{"pause": false}, StateLoading},
+		{"paused", map[string]any{"pause": true, "time-pos": 10.0}, StatePaused},
+		{"playing", map[string]any{"pause": false, "time-pos": 10.0}, StatePlaying},
+	}
+
+	for _, tt := range tests {
+		if got := playbackState(tt.vals); got != tt.want {
+			t.Errorf("playbackState(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("wrapped: %w", ErrNoPlayer), "no_player"},
+		{fmt.Errorf("wrapped: %w", ErrIPCTimeout), "ipc_timeout"},
+		{fmt.Errorf("time-pos: %w", ErrPropertyUnavailable), "property_unavailable"},
+		{fmt.Errorf("%w: bad signature", ErrUnauthorized), "unauthorized"},
+		{fmt.Errorf("%w: missing scope", ErrForbidden), "forbidden"},
+		{fmt.Errorf("%w: entries 1 and 2 overlap", ErrConflict), "conflict"},
+		{errors.New("boom"), "error"},
+	}
+
+	for _, tt := range tests {
+		if got := errorCode(tt.err); got != tt.want {
+			t.Errorf("errorCode(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
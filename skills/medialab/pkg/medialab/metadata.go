@@ -0,0 +1,65 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// maxMetadataEntries bounds the yt-dlp metadata cache
+const maxMetadataEntries = 256
+
+// MediaMetadata holds yt-dlp metadata mpv does not expose as properties
+type MediaMetadata struct {
+	Uploader   string `json:"uploader"`
+	UploadDate string `json:"upload_date"`
+	Thumbnail  string `json:"thumbnail"`
+	IsLive     bool   `json:"is_live"`
+}
+
+// FetchMetadata queries yt-dlp for a URL's metadata without downloading it
+func (m *MediaLab) FetchMetadata(ctx context.Context, url string) (*MediaMetadata, error) {
+	cmd := exec.CommandContext(ctx, m.config.YTDLPBinary, "-J", "--no-playlist", "--skip-download", "--", url)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp metadata failed: %w", err)
+	}
+	var meta MediaMetadata
+	if err := json.Unmarshal(output, &meta); err != nil {
+		return nil, fmt.Errorf("invalid yt-dlp output: %w", err)
+	}
+	return &meta, nil
+}
+
+// prefetchMetadata fetches and caches metadata for a remote URL in the
+// background so GetPlaybackInfo never waits on yt-dlp
+func (m *MediaLab) prefetchMetadata(url string) {
+	if !isRemoteURL(url) || m.cachedMetadata(url) != nil {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		meta, err := m.FetchMetadata(ctx, url)
+		if err != nil {
+			return
+		}
+		m.metaMu.Lock()
+		defer m.metaMu.Unlock()
+		if len(m.metadata) >= maxMetadataEntries {
+			m.metadata = make(map[string]*MediaMetadata)
+		}
+		m.metadata[url] = meta
+	}()
+}
+
+func (m *MediaLab) cachedMetadata(url string) *MediaMetadata {
+	if url == "" {
+		return nil
+	}
+	m.metaMu.RLock()
+	defer m.metaMu.RUnlock()
+	return m.metadata[url]
+}
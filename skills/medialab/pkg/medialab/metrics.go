@@ -0,0 +1,310 @@
+package medialab
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the histogram bounds, in seconds, of IPC, search and
+// HTTP latencies
+var latencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+// metricInfo is the TYPE and HELP of a metric family
+type metricInfo struct {
+	typ, help string
+}
+
+var metricInfos = map[string]metricInfo{
+	"medialab_ipc_duration_seconds":          {"histogram", "mpv IPC round trip time by command"},
+	"medialab_ipc_errors_total":              {"counter", "mpv IPC failures by command and error code"},
+	"medialab_mpv_starts_total":              {"counter", "mpv processes started"},
+	"medialab_mpv_restarts_total":            {"counter", "mpv processes started in place of a running one"},
+	"medialab_mpv_start_failures_total":      {"counter", "mpv processes that failed to start or open their IPC socket"},
+	"medialab_stream_reconnects_total":       {"counter", "Live stream reloads by the stall watchdog"},
+	"medialab_search_duration_seconds":       {"histogram", "Search latency by provider, excluding cache hits"},
+	"medialab_search_errors_total":           {"counter", "Failed searches by provider"},
+	"medialab_http_requests_total":           {"counter", "HTTP requests by method, route and status code"},
+	"medialab_http_request_duration_seconds": {"histogram", "HTTP request latency by route"},
+
+	"medialab_screen_up":                     {"gauge", "Whether mpv answers on the screen's IPC socket"},
+	"medialab_screen_playing":                {"gauge", "Whether the screen plays media: loaded and not paused"},
+	"medialab_screen_volume":                 {"gauge", "Player volume, 0-100"},
+	"medialab_screen_position_seconds":       {"gauge", "Position in the current media"},
+	"medialab_screen_duration_seconds":       {"gauge", "Duration of the current media; absent for live streams"},
+	"medialab_screen_cache_seconds":          {"gauge", "Media buffered ahead of the position"},
+	"medialab_screen_dropped_frames":         {"gauge", "Frames dropped by the video output in the current media"},
+	"medialab_screen_decoder_dropped_frames": {"gauge", "Frames dropped by the decoder in the current media"},
+}
+
+// histogram counts observations into latencyBuckets
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// metrics holds the counters and histograms of a MediaLab and its
+// servers, keyed by family name and then by rendered label set
+type metrics struct {
+	mu         sync.Mutex
+	counters   map[string]map[string]float64
+	histograms map[string]map[string]*histogram
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		counters:   make(map[string]map[string]float64),
+		histograms: make(map[string]map[string]*histogram),
+	}
+}
+
+// labelString renders name/value pairs as {a="x",b="y"}
+func labelString(pairs ...string) string {
+	if len(pairs) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// inc adds 1 to a counter
+func (m *metrics) inc(name string, labels ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	vec, ok := m.counters[name]
+	if !ok {
+		vec = make(map[string]float64)
+		m.counters[name] = vec
+	}
+	vec[labelString(labels...)]++
+}
+
+// observe records a latency in a histogram
+func (m *metrics) observe(name string, d time.Duration, labels ...string) {
+	seconds := d.Seconds()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	vec, ok := m.histograms[name]
+	if !ok {
+		vec = make(map[string]*histogram)
+		m.histograms[name] = vec
+	}
+	key := labelString(labels...)
+	h, ok := vec[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(latencyBuckets)+1)}
+		vec[key] = h
+	}
+	i := sort.SearchFloat64s(latencyBuckets, seconds)
+	h.counts[i]++
+	h.sum += seconds
+	h.count++
+}
+
+// write renders the counters and histograms in the Prometheus text format
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.counters)+len(m.histograms))
+	for name := range m.counters {
+		names = append(names, name)
+	}
+	for name := range m.histograms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		writeMetricHeader(w, name)
+		if vec, ok := m.counters[name]; ok {
+			for _, labels := range sortedKeys(vec) {
+				fmt.Fprintf(w, "%s%s %s\n", name, labels, formatMetric(vec[labels]))
+			}
+			continue
+		}
+		vec := m.histograms[name]
+		for _, labels := range sortedKeys(vec) {
+			h := vec[labels]
+			var cumulative uint64
+			for i, bound := range latencyBuckets {
+				cumulative += h.counts[i]
+				fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", formatMetric(bound)), cumulative)
+			}
+			fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), h.count)
+			fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatMetric(h.sum))
+			fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count)
+		}
+	}
+}
+
+func writeMetricHeader(w io.Writer, name string) {
+	info := metricInfos[name]
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, info.help, name, info.typ)
+}
+
+// withLabel appends name="value" to a rendered label set
+func withLabel(labels, name, value string) string {
+	extra := labelString(name, value)
+	if labels == "" {
+		return extra
+	}
+	return labels[:len(labels)-1] + "," + extra[1:]
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatMetric(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// metricScreen is the screen label value: 1-4 or speaker
+func metricScreen(screen Screen) string {
+	return fmt.Sprint(screenID(screen))
+}
+
+// ipcCommandName returns the mpv command of an IPC request for metrics
+func ipcCommandName(command map[string]any) string {
+	switch args := command["command"].(type) {
+	case []any:
+		if len(args) > 0 {
+			return fmt.Sprint(args[0])
+		}
+	case []string:
+		if len(args) > 0 {
+			return args[0]
+		}
+	}
+	return "unknown"
+}
+
+// observeIPC records an IPC round trip that started at start
+func (m *metrics) observeIPC(command string, start time.Time, err error) {
+	m.observe("medialab_ipc_duration_seconds", time.Since(start), "command", command)
+	if err != nil {
+		m.inc("medialab_ipc_errors_total", "command", command, "code", errorCode(err))
+	}
+}
+
+// screenMetricProps are the mpv properties behind the per-screen gauges
+var screenMetricProps = []string{
+	"idle-active", "pause", "volume", "time-pos", "duration",
+	"demuxer-cache-duration", "frame-drop-count", "decoder-frame-drop-count",
+}
+
+// writeScreenMetrics probes every screen's player and renders the
+// per-screen gauges. Probes bypass the IPC metrics so scrapes of dark
+// screens do not count as errors.
+func (m *MediaLab) writeScreenMetrics(w io.Writer) {
+	screens := []Screen{Screen1, Screen2, Screen3, Screen4, ScreenSpeaker}
+	vals := make([]map[string]any, len(screens))
+	var wg sync.WaitGroup
+	for i, screen := range screens {
+		wg.Add(1)
+		go func(i int, screen Screen) {
+			defer wg.Done()
+			vals[i], _ = m.getProperties(m.socketPath(screen), screenMetricProps)
+		}(i, screen)
+	}
+	wg.Wait()
+
+	gauge := func(name string, value func(map[string]any) (float64, bool)) {
+		writeMetricHeader(w, name)
+		for i, screen := range screens {
+			if v, ok := value(vals[i]); ok {
+				fmt.Fprintf(w, "%s%s %s\n", name, labelString("screen", metricScreen(screen)), formatMetric(v))
+			}
+		}
+	}
+	property := func(prop string) func(map[string]any) (float64, bool) {
+		return func(vals map[string]any) (float64, bool) {
+			v, ok := vals[prop].(float64)
+			return v, ok
+		}
+	}
+	boolGauge := func(b bool) (float64, bool) {
+		if b {
+			return 1, true
+		}
+		return 0, true
+	}
+
+	gauge("medialab_screen_up", func(vals map[string]any) (float64, bool) {
+		return boolGauge(vals != nil)
+	})
+	gauge("medialab_screen_playing", func(vals map[string]any) (float64, bool) {
+		idle, _ := vals["idle-active"].(bool)
+		paused, _ := vals["pause"].(bool)
+		return boolGauge(vals != nil && !idle && !paused)
+	})
+	gauge("medialab_screen_volume", property("volume"))
+	gauge("medialab_screen_position_seconds", property("time-pos"))
+	gauge("medialab_screen_duration_seconds", property("duration"))
+	gauge("medialab_screen_cache_seconds", property("demuxer-cache-duration"))
+	gauge("medialab_screen_dropped_frames", property("frame-drop-count"))
+	gauge("medialab_screen_decoder_dropped_frames", property("decoder-frame-drop-count"))
+}
+
+// handleMetrics serves the Prometheus text exposition format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.lab.writeScreenMetrics(w)
+	s.lab.metrics.write(w)
+}
+
+// statusRecorder captures the status code a handler writes
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// metricMethod bounds the method label to the standard verbs; clients
+// choose the method, and each new one would add series
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "other"
+}
+
+// instrument counts and times requests to route
+func (s *Server) instrument(route string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r)
+		s.lab.metrics.observe("medialab_http_request_duration_seconds", time.Since(start), "route", route)
+		s.lab.metrics.inc("medialab_http_requests_total", "method", metricMethod(r.Method), "route", route, "code", strconv.Itoa(rec.status))
+	}
+}
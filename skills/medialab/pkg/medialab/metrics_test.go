@@ -0,0 +1,106 @@
+package medialab
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsWrite(t *testing.T) {
+	m := newMetrics()
+	m.inc("medialab_ipc_errors_total", "command", "set_property", "code", "no_player")
+	m.inc("medialab_ipc_errors_total", "command", "set_property", "code", "no_player")
+	m.observe("medialab_ipc_duration_seconds", 3*time.Millisecond, "command", "get_property")
+	m.observe("medialab_ipc_duration_seconds", 2*time.Second, "command", "get_property")
+	m.inc("medialab_search_errors_total", "provider", `we"ird`)
+
+	var b strings.Builder
+	m.write(&b)
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE medialab_ipc_errors_total counter\n",
+		`medialab_ipc_errors_total{command="set_property",code="no_player"} 2` + "\n",
+		"# TYPE medialab_ipc_duration_seconds histogram\n",
+		`medialab_ipc_duration_seconds_bucket{command="get_property",le="0.0025"} 0` + "\n",
+		`medialab_ipc_duration_seconds_bucket{command="get_property",le="0.005"} 1` + "\n",
+		`medialab_ipc_duration_seconds_bucket{command="get_property",le="2.5"} 2` + "\n",
+		`medialab_ipc_duration_seconds_bucket{command="get_property",le="+Inf"} 2` + "\n",
+		`medialab_ipc_duration_seconds_sum{command="get_property"} 2.003` + "\n",
+		`medialab_ipc_duration_seconds_count{command="get_property"} 2` + "\n",
+		`medialab_search_errors_total{provider="we\"ird"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestIPCMetrics(t *testing.T) {
+	lab := New(nil)
+	socket := fakeMPV(t, 1, map[string]any{"volume": 80.0})
+	if _, err := lab.sendIPCCommand(socket, map[string]any{"command": []any{"get_property", "volume"}}); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(t.TempDir(), "missing.sock")
+	if _, err := lab.sendIPCCommand(missing, map[string]any{"command": []string{"quit"}}); err == nil {
+		t.Fatal("sendIPCCommand() to a missing socket succeeded")
+	}
+
+	var b strings.Builder
+	lab.metrics.write(&b)
+	out := b.String()
+	for _, want := range []string{
+		`medialab_ipc_duration_seconds_count{command="get_property"} 1`,
+		`medialab_ipc_duration_seconds_count{command="quit"} 1`,
+		`medialab_ipc_errors_total{command="quit",code="no_player"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output lacks %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	s := newAuthServer(AuthConfig{})
+	serveRequest(s, httptest.NewRequest("GET", "/v1/stations", nil))
+	serveRequest(s, httptest.NewRequest("GET", "/v1/nope", nil))
+	serveRequest(s, httptest.NewRequest("X-RANDOM-1", "/v1/stations", nil))
+	serveRequest(s, httptest.NewRequest("X-RANDOM-2", "/v1/stations", nil))
+
+	w := serveRequest(s, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Fatalf("GET /metrics = %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	out := w.Body.String()
+	for _, want := range []string{
+		`medialab_http_requests_total{method="GET",route="/v1/stations",code="200"} 1`,
+		`medialab_http_requests_total{method="GET",route="/v1/",code="404"} 1`,
+		`medialab_http_requests_total{method="other",route="/v1/stations",code="405"} 2`,
+		`medialab_http_request_duration_seconds_count{route="/v1/stations"} 3`,
+		"# TYPE medialab_screen_up gauge\n",
+		`medialab_screen_up{screen="speaker"} `,
+		"# TYPE medialab_screen_playing gauge\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output lacks %q", want)
+		}
+	}
+	if strings.Contains(out, "X-RANDOM") {
+		t.Error("metrics output has a label for a made-up method")
+	}
+
+	s = newAuthServer(AuthConfig{Clients: []APIClient{{Name: "prom", Token: "prom-token", Scopes: []Scope{ScopeRead}}}})
+	if w := serveRequest(s, httptest.NewRequest("GET", "/metrics", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /metrics without credentials = %d, want 401", w.Code)
+	}
+	s = newAuthServer(AuthConfig{
+		Clients:       []APIClient{{Name: "prom", Token: "prom-token", Scopes: []Scope{ScopeRead}}},
+		PublicMetrics: true,
+	})
+	if w := serveRequest(s, httptest.NewRequest("GET", "/metrics", nil)); w.Code != http.StatusOK {
+		t.Errorf("GET /metrics with PublicMetrics = %d, want 200", w.Code)
+	}
+}
@@ -0,0 +1,192 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// DefaultPlaylistLimit caps the entries of a playlist expanded without a
+// Limit. Channels list thousands of uploads, each listed by yt-dlp and
+// loaded into mpv.
+const DefaultPlaylistLimit = 200
+
+// PlaylistOptions selects which entries of a playlist or channel to use
+type PlaylistOptions struct {
+	Start   int  // 1-based index of the first entry (default 1)
+	Limit   int  // maximum entries, 0 = DefaultPlaylistLimit
+	All     bool // every entry, ignoring Limit
+	Shuffle bool // shuffle entries (after Start, before Limit)
+
+	Quality *Quality // format for PlayPlaylist, nil = the screen's default
+}
+
+// Playlist is an expanded playlist or channel
+type Playlist struct {
+	ID      string          `json:"id"`
+	Title   string          `json:"title"`
+	Channel string          `json:"channel,omitempty"`
+	URL     string          `json:"url"`
+	Count   int             `json:"count"` // entries in the source, may exceed len(Entries)
+	Entries []PlaylistEntry `json:"entries"`
+}
+
+// PlaylistEntry is one item of a playlist
+type PlaylistEntry struct {
+	Index int `json:"index"` // 1-based position in the source playlist
+	SearchResult
+}
+
+// IsPlaylistURL reports whether url names a YouTube playlist or channel
+// rather than a single video
+func IsPlaylistURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.Contains(u.Host, "youtube.com") {
+		return false
+	}
+	if u.Path == "/playlist" {
+		return u.Query().Get("list") != ""
+	}
+	return isChannelPath(u.Path)
+}
+
+func isChannelPath(path string) bool {
+	for _, prefix := range []string{"/@", "/channel/", "/c/", "/user/"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// channelVideosURL points a bare channel URL at its videos tab; yt-dlp
+// otherwise lists the channel's tabs (videos, shorts, live) as entries
+func channelVideosURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.Contains(u.Host, "youtube.com") || !isChannelPath(u.Path) {
+		return rawURL
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	// "@name" is one segment, "channel/ID" and friends are two
+	if (strings.HasPrefix(parts[0], "@") && len(parts) == 1) || (!strings.HasPrefix(parts[0], "@") && len(parts) == 2) {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/videos"
+	}
+	return u.String()
+}
+
+// ExpandPlaylist lists the entries of a playlist or channel with yt-dlp
+// --flat-playlist, without resolving each video
+func (m *MediaLab) ExpandPlaylist(ctx context.Context, rawURL string, opts PlaylistOptions) (*Playlist, error) {
+	if opts.Start < 1 {
+		opts.Start = 1
+	}
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("%w: negative limit", ErrInvalidArgument)
+	}
+	switch {
+	case opts.All:
+		opts.Limit = 0
+	case opts.Limit == 0:
+		opts.Limit = DefaultPlaylistLimit
+	}
+
+	// Without shuffling only the requested window needs listing
+	items := strconv.Itoa(opts.Start) + ":"
+	if !opts.Shuffle && opts.Limit > 0 {
+		items += strconv.Itoa(opts.Start + opts.Limit - 1)
+	}
+	args := []string{"-J", "--flat-playlist", "--playlist-items", items, "--", channelVideosURL(rawURL)}
+	cmd := exec.CommandContext(ctx, m.config.YTDLPBinary, args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp playlist expansion failed: %w", err)
+	}
+
+	playlist, err := parsePlaylist(output, opts.Start)
+	if err != nil {
+		return nil, err
+	}
+	playlist.URL = rawURL
+
+	if opts.Shuffle {
+		rand.Shuffle(len(playlist.Entries), func(i, j int) {
+			playlist.Entries[i], playlist.Entries[j] = playlist.Entries[j], playlist.Entries[i]
+		})
+	}
+	if opts.Limit > 0 && len(playlist.Entries) > opts.Limit {
+		playlist.Entries = playlist.Entries[:opts.Limit]
+	}
+	if len(playlist.Entries) == 0 {
+		return nil, errors.New("playlist has no entries")
+	}
+	return playlist, nil
+}
+
+// parsePlaylist decodes yt-dlp -J output; start is the index of the first
+// listed entry
+func parsePlaylist(data []byte, start int) (*Playlist, error) {
+	var raw struct {
+		Type          string       `json:"_type"`
+		ID            string       `json:"id"`
+		Title         string       `json:"title"`
+		Channel       string       `json:"channel"`
+		Uploader      string       `json:"uploader"`
+		Extractor     string       `json:"extractor"` // e.g. "youtube:tab", "soundcloud:set"
+		PlaylistCount int          `json:"playlist_count"`
+		Entries       []ytdlpEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid yt-dlp playlist output: %w", err)
+	}
+	if raw.Type != "playlist" {
+		return nil, fmt.Errorf("%w: not a playlist", ErrInvalidArgument)
+	}
+
+	playlist := &Playlist{
+		ID:      raw.ID,
+		Title:   raw.Title,
+		Channel: raw.Channel,
+		Count:   raw.PlaylistCount,
+		Entries: make([]PlaylistEntry, 0, len(raw.Entries)),
+	}
+	if playlist.Channel == "" {
+		playlist.Channel = raw.Uploader
+	}
+	provider, _, _ := strings.Cut(raw.Extractor, ":")
+	for i := range raw.Entries {
+		r := raw.Entries[i].result()
+		r.Provider = provider
+		if r.URL == "" {
+			continue
+		}
+		playlist.Entries = append(playlist.Entries, PlaylistEntry{Index: start + i, SearchResult: r})
+	}
+	if playlist.Count == 0 {
+		playlist.Count = start - 1 + len(raw.Entries)
+	}
+	return playlist, nil
+}
+
+// PlayPlaylist expands a playlist or channel and loads the selected
+// entries into mpv's playlist on screen
+func (m *MediaLab) PlayPlaylist(ctx context.Context, rawURL string, opts PlaylistOptions, screen Screen) (*PlayerInstance, *Playlist, error) {
+	playlist, err := m.ExpandPlaylist(ctx, rawURL, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	urls := make([]string, len(playlist.Entries))
+	for i, e := range playlist.Entries {
+		urls[i] = e.URL
+	}
+	instance, err := m.start(ctx, screen, PlayOptions{Quality: opts.Quality}, rawURL, urls...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return instance, playlist, nil
+}
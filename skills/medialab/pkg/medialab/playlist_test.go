@@ -0,0 +1,105 @@
+package medialab
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsPlaylistURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://www.youtube.com/playlist?list=PL123", true},
+		{"https://www.youtube.com/@lofigirl", true},
+		{"https://youtube.com/channel/UC123/videos", true},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", false},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&list=PL123", false},
+		{"https://www.youtube.com/playlist", false},
+		{"https://soundcloud.com/artist/sets/album", false},
+		{"/home/user/video.mp4", false},
+	}
+	for _, tt := range tests {
+		if got := IsPlaylistURL(tt.url); got != tt.want {
+			t.Errorf("IsPlaylistURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestChannelVideosURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.youtube.com/@lofigirl", "https://www.youtube.com/@lofigirl/videos"},
+		{"https://www.youtube.com/channel/UC123/", "https://www.youtube.com/channel/UC123/videos"},
+		{"https://www.youtube.com/@lofigirl/streams", "https://www.youtube.com/@lofigirl/streams"},
+		{"https://www.youtube.com/playlist?list=PL123", "https://www.youtube.com/playlist?list=PL123"},
+	}
+	for _, tt := range tests {
+		if got := channelVideosURL(tt.url); got != tt.want {
+			t.Errorf("channelVideosURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParsePlaylist(t *testing.T) {
+	data := []byte(`{
+		"_type": "playlist", "id": "PL123", "title": "Mix", "uploader": "Someone", "extractor": "youtube:tab", "playlist_count": 40,
+		"entries": [
+			{"id": "aaaaaaaaaaa", "title": "One", "duration": 61, "url": "https://www.youtube.com/watch?v=aaaaaaaaaaa"},
+			{"id": "bbbbbbbbbbb", "title": "[Private video]"},
+			{"id": "ccccccccccc", "title": "Three", "url": "https://www.youtube.com/watch?v=ccccccccccc"}
+		]
+	}`)
+	playlist, err := parsePlaylist(data, 5)
+	if err != nil {
+		t.Fatalf("parsePlaylist() error = %v", err)
+	}
+	if playlist.Title != "Mix" || playlist.Channel != "Someone" || playlist.Count != 40 {
+		t.Errorf("playlist = %+v", playlist)
+	}
+	if len(playlist.Entries) != 2 {
+		t.Fatalf("got %d entries, want 2 (entry without URL skipped)", len(playlist.Entries))
+	}
+	if e := playlist.Entries[1]; e.Index != 7 || e.Title != "Three" {
+		t.Errorf("Entries[1] = index %d %q, want index 7 \"Three\"", e.Index, e.Title)
+	}
+	if e := playlist.Entries[0]; e.Duration != "1:01" || e.Provider != "youtube" {
+		t.Errorf("Entries[0] = duration %q, provider %q, want 1:01 and youtube", e.Duration, e.Provider)
+	}
+
+	if _, err := parsePlaylist([]byte(`{"_type": "video", "id": "x"}`), 1); err == nil {
+		t.Error("parsePlaylist(video) = nil error, want error")
+	}
+}
+
+func TestExpandPlaylistLimit(t *testing.T) {
+	lab := newTestLab(t)
+	argsFile := filepath.Join(t.TempDir(), "args")
+	lab.config.YTDLPBinary = writeScript(t, t.TempDir(), "yt-dlp", `printf '%s\n' "$@" > `+argsFile+`
+echo '{"_type": "playlist", "id": "PL1", "title": "Uploads", "entries": [{"id": "a", "url": "https://example.com/a"}]}'
+`)
+
+	for _, tt := range []struct {
+		opts  PlaylistOptions
+		items string
+	}{
+		{PlaylistOptions{}, "1:200"},
+		{PlaylistOptions{Start: 5, Limit: 10}, "5:14"},
+		{PlaylistOptions{Limit: 1000}, "1:1000"},
+		{PlaylistOptions{Limit: 10, All: true}, "1:"},
+		{PlaylistOptions{Shuffle: true}, "1:"},
+	} {
+		if _, err := lab.ExpandPlaylist(context.Background(), "https://youtube.com/playlist?list=PL1", tt.opts); err != nil {
+			t.Fatalf("ExpandPlaylist(%+v) error = %v", tt.opts, err)
+		}
+		data, _ := os.ReadFile(argsFile)
+		if !strings.Contains(string(data), "--playlist-items\n"+tt.items+"\n") {
+			t.Errorf("ExpandPlaylist(%+v) args = %q, want --playlist-items %s", tt.opts, data, tt.items)
+		}
+	}
+}
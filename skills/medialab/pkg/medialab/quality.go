@@ -0,0 +1,225 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Quality selects the yt-dlp format mpv plays. The zero value leaves the
+// choice to mpv (bestvideo+bestaudio).
+type Quality struct {
+	MaxHeight int    // 0 = no limit
+	Codec     string // preferred video codec: av1, vp9, h264 or h265
+	AudioOnly bool   // best audio stream, video disabled
+	Format    string // raw ytdl-format, overrides the fields above
+}
+
+// PlayOptions tunes a single Play call
+type PlayOptions struct {
+	Quality *Quality // nil = the screen's default (Config.ScreenQuality, then Config.Quality)
+
+	mpvArgs []string // extra options after the defaults, e.g. a scene's saved state
+}
+
+// yt-dlp format-sort names for the accepted codecs
+var codecSortNames = map[string]string{
+	"av1":  "av01",
+	"av01": "av01",
+	"vp9":  "vp9",
+	"h265": "h265",
+	"hevc": "h265",
+	"h264": "h264",
+	"avc":  "h264",
+}
+
+// ParseQuality parses a quality preset: "best", "audio", a height such as
+// "1080p" or "720", a codec such as "vp9", or a height and codec joined by
+// a colon ("1080p:av1"). Anything containing format selector syntax
+// ("bestvideo[height<=720]+bestaudio") is used as a raw ytdl-format.
+func ParseQuality(spec string) (Quality, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.ContainsAny(spec, "[]+/") {
+		return Quality{Format: spec}, nil
+	}
+
+	s := strings.ToLower(spec)
+	switch s {
+	case "", "best":
+		return Quality{}, nil
+	case "audio", "audio-only", "bestaudio":
+		return Quality{AudioOnly: true}, nil
+	}
+
+	var q Quality
+	for _, part := range strings.Split(s, ":") {
+		if codec, ok := codecSortNames[part]; ok && q.Codec == "" {
+			q.Codec = codec
+			continue
+		}
+		height, err := strconv.Atoi(strings.TrimSuffix(part, "p"))
+		if err != nil || height <= 0 || q.MaxHeight != 0 {
+			return Quality{}, fmt.Errorf("%w: quality %q (want best, audio, 1080p, vp9, 1080p:av1 or a ytdl-format)", ErrInvalidArgument, spec)
+		}
+		q.MaxHeight = height
+	}
+	return q, nil
+}
+
+// String returns the preset form accepted by ParseQuality
+func (q Quality) String() string {
+	switch {
+	case q.Format != "":
+		return q.Format
+	case q.AudioOnly:
+		return "audio"
+	}
+	var parts []string
+	if q.MaxHeight > 0 {
+		parts = append(parts, strconv.Itoa(q.MaxHeight)+"p")
+	}
+	if q.Codec != "" {
+		parts = append(parts, q.Codec)
+	}
+	if len(parts) == 0 {
+		return "best"
+	}
+	return strings.Join(parts, ":")
+}
+
+// mpvArgs returns the mpv options selecting q
+func (q Quality) mpvArgs() []string {
+	switch {
+	case q.Format != "":
+		return []string{"--ytdl-format=" + q.Format}
+	case q.AudioOnly:
+		return []string{"--ytdl-format=bestaudio/best", "--vid=no"}
+	}
+	var args []string
+	if q.MaxHeight > 0 {
+		h := strconv.Itoa(q.MaxHeight)
+		args = append(args, "--ytdl-format=bestvideo[height<=?"+h+"]+bestaudio/best[height<=?"+h+"]")
+	}
+	if q.Codec != "" {
+		// A preference, not a filter: other codecs still play if needed
+		args = append(args, "--ytdl-raw-options-append=format-sort=vcodec:"+q.Codec)
+	}
+	return args
+}
+
+// ytdlpArgs returns the yt-dlp options selecting q for downloads
+func (q Quality) ytdlpArgs() []string {
+	switch {
+	case q.Format != "":
+		return []string{"-f", q.Format}
+	case q.AudioOnly:
+		return []string{"-f", "bestaudio/best"}
+	}
+	var args []string
+	if q.MaxHeight > 0 {
+		h := strconv.Itoa(q.MaxHeight)
+		args = append(args, "-f", "bestvideo[height<=?"+h+"]+bestaudio/best[height<=?"+h+"]")
+	}
+	if q.Codec != "" {
+		args = append(args, "-S", "vcodec:"+q.Codec)
+	}
+	return args
+}
+
+// qualityFor resolves the quality for a play request on screen
+func (m *MediaLab) qualityFor(screen Screen, opts PlayOptions) Quality {
+	if opts.Quality != nil {
+		return *opts.Quality
+	}
+	if q, ok := m.config.ScreenQuality[screen]; ok {
+		return q
+	}
+	return m.config.Quality
+}
+
+// cachedQuality is the quality an offline copy must have to play on
+// screen. The speaker plays only the audio of any copy.
+func (m *MediaLab) cachedQuality(screen Screen, opts PlayOptions) *Quality {
+	if screen == ScreenSpeaker {
+		return nil
+	}
+	q := m.qualityFor(screen, opts)
+	return &q
+}
+
+// Format is one stream format offered for a URL
+type Format struct {
+	ID         string  `json:"id"`
+	Ext        string  `json:"ext"`
+	Resolution string  `json:"resolution"`
+	Height     int     `json:"height,omitempty"`
+	FPS        float64 `json:"fps,omitempty"`
+	VideoCodec string  `json:"vcodec,omitempty"`
+	AudioCodec string  `json:"acodec,omitempty"`
+	Bitrate    float64 `json:"tbr,omitempty"` // kbit/s
+	Filesize   int64   `json:"filesize,omitempty"`
+	Note       string  `json:"note,omitempty"`
+}
+
+// ListFormats returns the formats yt-dlp offers for url, as listed by
+// yt-dlp -F, worst first
+func (m *MediaLab) ListFormats(ctx context.Context, url string) ([]Format, error) {
+	cmd := exec.CommandContext(ctx, m.config.YTDLPBinary, "-J", "--no-playlist", "--", url)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("yt-dlp format listing failed: %w", err)
+	}
+	return parseFormats(output)
+}
+
+func parseFormats(data []byte) ([]Format, error) {
+	var raw struct {
+		Formats []struct {
+			FormatID       string  `json:"format_id"`
+			Ext            string  `json:"ext"`
+			Resolution     string  `json:"resolution"`
+			Height         int     `json:"height"`
+			FPS            float64 `json:"fps"`
+			VCodec         string  `json:"vcodec"`
+			ACodec         string  `json:"acodec"`
+			TBR            float64 `json:"tbr"`
+			Filesize       int64   `json:"filesize"`
+			FilesizeApprox int64   `json:"filesize_approx"`
+			FormatNote     string  `json:"format_note"`
+		} `json:"formats"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid yt-dlp output: %w", err)
+	}
+
+	formats := make([]Format, 0, len(raw.Formats))
+	for _, f := range raw.Formats {
+		format := Format{
+			ID:         f.FormatID,
+			Ext:        f.Ext,
+			Resolution: f.Resolution,
+			Height:     f.Height,
+			FPS:        f.FPS,
+			VideoCodec: f.VCodec,
+			AudioCodec: f.ACodec,
+			Bitrate:    f.TBR,
+			Filesize:   f.Filesize,
+			Note:       f.FormatNote,
+		}
+		if format.Filesize == 0 {
+			format.Filesize = f.FilesizeApprox
+		}
+		// yt-dlp reports absent streams as "none"
+		if format.VideoCodec == "none" {
+			format.VideoCodec = ""
+		}
+		if format.AudioCodec == "none" {
+			format.AudioCodec = ""
+		}
+		formats = append(formats, format)
+	}
+	return formats, nil
+}
@@ -0,0 +1,91 @@
+package medialab
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseQuality(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Quality
+	}{
+		{"", Quality{}},
+		{"best", Quality{}},
+		{"audio", Quality{AudioOnly: true}},
+		{"1080p", Quality{MaxHeight: 1080}},
+		{"720", Quality{MaxHeight: 720}},
+		{"VP9", Quality{Codec: "vp9"}},
+		{"1080p:av1", Quality{MaxHeight: 1080, Codec: "av01"}},
+		{"h264:480p", Quality{MaxHeight: 480, Codec: "h264"}},
+		{"bestvideo[height<=720]+bestaudio", Quality{Format: "bestvideo[height<=720]+bestaudio"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuality(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseQuality(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"hd", "1080p:720p", "-1", "vp9:h264"} {
+		if _, err := ParseQuality(bad); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("ParseQuality(%q) error = %v, want ErrInvalidArgument", bad, err)
+		}
+	}
+}
+
+func TestQualityMPVArgs(t *testing.T) {
+	tests := []struct {
+		q    Quality
+		want []string
+	}{
+		{Quality{}, nil},
+		{Quality{AudioOnly: true}, []string{"--ytdl-format=bestaudio/best", "--vid=no"}},
+		{Quality{MaxHeight: 1080, Codec: "vp9"}, []string{
+			"--ytdl-format=bestvideo[height<=?1080]+bestaudio/best[height<=?1080]",
+			"--ytdl-raw-options-append=format-sort=vcodec:vp9",
+		}},
+		{Quality{Format: "18", MaxHeight: 1080}, []string{"--ytdl-format=18"}},
+	}
+	for _, tt := range tests {
+		if got := tt.q.mpvArgs(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v.mpvArgs() = %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestQualityFor(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Quality = Quality{MaxHeight: 1080}
+	cfg.ScreenQuality[Screen2] = Quality{AudioOnly: true}
+	lab := New(cfg)
+
+	if got := lab.qualityFor(Screen1, PlayOptions{}); got.MaxHeight != 1080 {
+		t.Errorf("screen 1 default = %+v, want Config.Quality", got)
+	}
+	if got := lab.qualityFor(Screen2, PlayOptions{}); !got.AudioOnly {
+		t.Errorf("screen 2 default = %+v, want ScreenQuality override", got)
+	}
+	req := Quality{MaxHeight: 480}
+	if got := lab.qualityFor(Screen2, PlayOptions{Quality: &req}); got != req {
+		t.Errorf("per-request quality = %+v, want %+v", got, req)
+	}
+}
+
+func TestParseFormats(t *testing.T) {
+	data := []byte(`{"formats": [
+		{"format_id": "140", "ext": "m4a", "resolution": "audio only", "vcodec": "none", "acodec": "mp4a.40.2", "filesize": 3145728, "format_note": "medium"},
+		{"format_id": "248", "ext": "webm", "resolution": "1920x1080", "height": 1080, "fps": 30, "vcodec": "vp9", "acodec": "none", "filesize_approx": 1024}
+	]}`)
+	formats, err := parseFormats(data)
+	if err != nil || len(formats) != 2 {
+		t.Fatalf("parseFormats() = %v, %v", formats, err)
+	}
+	if f := formats[0]; f.VideoCodec != "" || f.AudioCodec != "mp4a.40.2" || f.Filesize != 3145728 {
+		t.Errorf("audio format = %+v", f)
+	}
+	if f := formats[1]; f.Height != 1080 || f.AudioCodec != "" || f.Filesize != 1024 {
+		t.Errorf("video format = %+v", f)
+	}
+}
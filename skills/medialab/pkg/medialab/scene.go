@@ -0,0 +1,337 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scene is a saved state of every screen, restored with LoadScene
+type Scene struct {
+	Name    string        `json:"name"`
+	SavedAt time.Time     `json:"saved_at"`
+	Screens []SceneScreen `json:"screens"` // screens without a player are stopped on load
+}
+
+// SceneScreen is the saved state of one player
+type SceneScreen struct {
+	Screen       Screen   `json:"screen"`
+	URL          string   `json:"url"`          // what the player was started with
+	Playlist     []string `json:"playlist"`     // entries as mpv holds them
+	PlaylistPos  int      `json:"playlist_pos"` // 1-based
+	Position     float64  `json:"position"`     // seconds into the current entry
+	Volume       float64  `json:"volume"`
+	Mute         bool     `json:"mute"`
+	Paused       bool     `json:"paused"`
+	Fullscreen   bool     `json:"fullscreen"`
+	Speed        float64  `json:"speed"`
+	LoopFile     string   `json:"loop_file"`                // "no", "inf" or a count
+	LoopPlaylist string   `json:"loop_playlist"`            // "no", "inf" or a count
+	AudioTrack   string   `json:"audio_track,omitempty"`    // mpv aid: track ID or "no"
+	VideoTrack   string   `json:"video_track,omitempty"`    // mpv vid
+	SubTrack     string   `json:"subtitle_track,omitempty"` // mpv sid
+}
+
+var sceneProps = []string{
+	"playlist", "playlist-pos", "time-pos", "volume", "mute", "pause", "fullscreen",
+	"speed", "loop-file", "loop-playlist", "aid", "vid", "sid", "path",
+}
+
+// scenePath returns the file of the named scene, rejecting names that
+// would escape Config.SceneDir
+func (m *MediaLab) scenePath(name string) (string, error) {
+	if !validSceneName(name) {
+		return "", fmt.Errorf("%w: invalid scene name %q", ErrInvalidArgument, name)
+	}
+	return filepath.Join(m.config.SceneDir, strings.TrimSpace(name)+".json"), nil
+}
+
+func validSceneName(name string) bool {
+	name = strings.TrimSpace(name)
+	return name != "" && !strings.ContainsAny(name, `/\`) && !strings.HasPrefix(name, ".")
+}
+
+// SaveScene snapshots every running player (media, playlist, position,
+// volume, fullscreen and track selection) as the named scene, replacing
+// any scene of that name
+func (m *MediaLab) SaveScene(name string) (*Scene, error) {
+	path, err := m.scenePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Probe every socket rather than ListPlayers, so players started by
+	// other processes (the CLI) are saved too
+	scene := &Scene{Name: strings.TrimSpace(name), SavedAt: time.Now().UTC(), Screens: []SceneScreen{}}
+	for screen := Screen1; screen <= ScreenSpeaker; screen++ {
+		vals, err := m.GetProperties(screen, sceneProps)
+		if errors.Is(err, ErrNoPlayer) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", screen, err)
+		}
+		var url string
+		if p, ok := m.GetPlayer(screen); ok {
+			url = p.URL
+		}
+		if sc, ok := sceneScreen(screen, url, vals); ok {
+			scene.Screens = append(scene.Screens, sc)
+		}
+	}
+
+	data, err := json.MarshalIndent(scene, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(m.config.SceneDir, 0o755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, err
+	}
+	return scene, nil
+}
+
+// sceneScreen builds the saved state of screen from its player's
+// properties; idle players have nothing to restore
+func sceneScreen(screen Screen, url string, vals map[string]any) (SceneScreen, bool) {
+	sc := SceneScreen{
+		Screen:       screen,
+		URL:          url,
+		LoopFile:     loopString(vals["loop-file"]),
+		LoopPlaylist: loopString(vals["loop-playlist"]),
+		AudioTrack:   trackString(vals["aid"]),
+		VideoTrack:   trackString(vals["vid"]),
+		SubTrack:     trackString(vals["sid"]),
+	}
+	sc.Position, _ = vals["time-pos"].(float64)
+	sc.Volume, _ = vals["volume"].(float64)
+	sc.Mute, _ = vals["mute"].(bool)
+	sc.Paused, _ = vals["pause"].(bool)
+	sc.Fullscreen, _ = vals["fullscreen"].(bool)
+	sc.Speed, _ = vals["speed"].(float64)
+	if pos, ok := vals["playlist-pos"].(float64); ok && pos >= 0 {
+		sc.PlaylistPos = int(pos) + 1
+	}
+
+	entries, _ := vals["playlist"].([]any)
+	for _, e := range entries {
+		if entry, ok := e.(map[string]any); ok {
+			if filename, _ := entry["filename"].(string); filename != "" {
+				sc.Playlist = append(sc.Playlist, filename)
+			}
+		}
+	}
+	if len(sc.Playlist) == 0 {
+		path, _ := vals["path"].(string)
+		if path == "" {
+			return sc, false
+		}
+		sc.Playlist = []string{path}
+		sc.PlaylistPos = 1
+	}
+	if sc.URL == "" {
+		sc.URL = sc.Playlist[0]
+	}
+	return sc, true
+}
+
+// trackString normalizes mpv's aid/vid/sid values (ID, false or "auto")
+func trackString(val any) string {
+	switch v := val.(type) {
+	case float64:
+		return strconv.Itoa(int(v))
+	case bool:
+		if !v {
+			return "no"
+		}
+	case string:
+		return v
+	}
+	return ""
+}
+
+// GetScene reads the named scene
+func (m *MediaLab) GetScene(name string) (*Scene, error) {
+	path, err := m.scenePath(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: scene %q", ErrNotFound, name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var scene Scene
+	if err := json.Unmarshal(data, &scene); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return &scene, nil
+}
+
+// Scenes returns the saved scenes sorted by name
+func (m *MediaLab) Scenes() ([]Scene, error) {
+	files, err := filepath.Glob(filepath.Join(m.config.SceneDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	scenes := make([]Scene, 0, len(files))
+	for _, file := range files {
+		scene, err := m.GetScene(strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			return nil, err
+		}
+		scenes = append(scenes, *scene)
+	}
+	sort.Slice(scenes, func(i, j int) bool { return scenes[i].Name < scenes[j].Name })
+	return scenes, nil
+}
+
+// DeleteScene removes the named scene
+func (m *MediaLab) DeleteScene(name string) error {
+	path, err := m.scenePath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); os.IsNotExist(err) {
+		return fmt.Errorf("%w: scene %q", ErrNotFound, name)
+	} else if err != nil {
+		return err
+	}
+	return nil
+}
+
+// LoadScene restores the named scene: every player is stopped and the
+// scene's screens restarted at the saved entry and position with the
+// saved volume, fullscreen and tracks. Screens that fail to restore do
+// not stop the others; their errors are joined. ctx bounds the restore,
+// not the restored players.
+func (m *MediaLab) LoadScene(ctx context.Context, name string) (*Scene, error) {
+	scene, err := m.GetScene(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sc := range scene.Screens {
+		if !sc.Screen.Valid() || len(sc.Playlist) == 0 {
+			return nil, fmt.Errorf("%w: scene %q has an invalid entry for %s", ErrInvalidArgument, name, sc.Screen)
+		}
+	}
+	for screen := Screen1; screen <= ScreenSpeaker; screen++ {
+		m.stopAny(screen)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(scene.Screens))
+	for i, sc := range scene.Screens {
+		wg.Add(1)
+		go func(i int, sc SceneScreen) {
+			defer wg.Done()
+			if err := m.restoreScreen(ctx, sc); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", sc.Screen, err)
+			}
+		}(i, sc)
+	}
+	wg.Wait()
+	return scene, errors.Join(errs...)
+}
+
+// stopAny stops screen's player, including one started by another process
+func (m *MediaLab) stopAny(screen Screen) {
+	if _, ok := m.GetPlayer(screen); ok {
+		m.Stop(screen)
+		return
+	}
+	m.sendIPCCommand(m.socketPath(screen), map[string]any{"command": []string{"quit"}})
+}
+
+// restoreScreen starts sc's playlist with the player-wide state as mpv
+// options, then seeks and selects tracks once the entry has loaded, since
+// those apply to that file only
+func (m *MediaLab) restoreScreen(ctx context.Context, sc SceneScreen) error {
+	opts := PlayOptions{mpvArgs: []string{
+		"--volume=" + strconv.FormatFloat(sc.Volume, 'f', -1, 64),
+		"--mute=" + yesNo(sc.Mute),
+		"--pause=" + yesNo(sc.Paused),
+	}}
+	if sc.Screen != ScreenSpeaker {
+		opts.mpvArgs = append(opts.mpvArgs, "--fullscreen="+yesNo(sc.Fullscreen))
+	}
+	if sc.Speed > 0 {
+		opts.mpvArgs = append(opts.mpvArgs, "--speed="+strconv.FormatFloat(sc.Speed, 'f', -1, 64))
+	}
+	if sc.LoopFile != "" {
+		opts.mpvArgs = append(opts.mpvArgs, "--loop-file="+sc.LoopFile)
+	}
+	if sc.LoopPlaylist != "" {
+		opts.mpvArgs = append(opts.mpvArgs, "--loop-playlist="+sc.LoopPlaylist)
+	}
+	if sc.PlaylistPos > 1 && sc.PlaylistPos <= len(sc.Playlist) {
+		opts.mpvArgs = append(opts.mpvArgs, "--playlist-start="+strconv.Itoa(sc.PlaylistPos-1))
+	}
+
+	if _, err := m.start(ctx, sc.Screen, opts, sc.URL, sc.Playlist...); err != nil {
+		return err
+	}
+	if sc.Position < 1 && sc.AudioTrack == "" && sc.VideoTrack == "" && sc.SubTrack == "" {
+		return nil
+	}
+	if err := m.waitForLoad(ctx, sc.Screen); err != nil {
+		return err
+	}
+
+	var errs []error
+	for prop, val := range map[string]string{"aid": sc.AudioTrack, "vid": sc.VideoTrack, "sid": sc.SubTrack} {
+		if val == "" {
+			continue
+		}
+		if _, err := m.IPCCommand(sc.Screen, map[string]any{"command": []any{"set_property", prop, val}}); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", prop, err))
+		}
+	}
+	if sc.Position >= 1 {
+		if err := m.Seek(sc.Screen, sc.Position, false); err != nil {
+			errs = append(errs, fmt.Errorf("seek: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// waitForLoad waits until screen's current entry has loaded (time-pos is
+// available), for up to 20 seconds
+func (m *MediaLab) waitForLoad(ctx context.Context, screen Screen) error {
+	deadline := time.Now().Add(20 * time.Second)
+	for time.Now().Before(deadline) {
+		_, err := m.GetProperty(screen, "time-pos")
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, ErrPropertyUnavailable) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	return fmt.Errorf("%w: media did not load", ErrIPCTimeout)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
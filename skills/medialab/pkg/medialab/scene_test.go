@@ -0,0 +1,137 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSceneScreen(t *testing.T) {
+	vals := map[string]any{
+		"playlist": []any{
+			map[string]any{"filename": "https://youtube.com/watch?v=a"},
+			map[string]any{"filename": "https://youtube.com/watch?v=b", "current": true},
+		},
+		"playlist-pos":  1.0,
+		"time-pos":      83.5,
+		"volume":        45.0,
+		"mute":          true,
+		"pause":         true,
+		"fullscreen":    true,
+		"speed":         1.25,
+		"loop-file":     false,
+		"loop-playlist": "inf",
+		"aid":           2.0,
+		"vid":           1.0,
+		"sid":           false,
+	}
+	sc, ok := sceneScreen(Screen3, "https://youtube.com/playlist?list=x", vals)
+	if !ok {
+		t.Fatal("sceneScreen() skipped a playing screen")
+	}
+	if sc.Screen != Screen3 || len(sc.Playlist) != 2 || sc.PlaylistPos != 2 || sc.Position != 83.5 {
+		t.Errorf("playlist state = %+v", sc)
+	}
+	if sc.Volume != 45 || !sc.Mute || !sc.Paused || !sc.Fullscreen || sc.Speed != 1.25 {
+		t.Errorf("player state = %+v", sc)
+	}
+	if sc.LoopFile != "no" || sc.LoopPlaylist != "inf" {
+		t.Errorf("loops = %q, %q", sc.LoopFile, sc.LoopPlaylist)
+	}
+	if sc.AudioTrack != "2" || sc.VideoTrack != "1" || sc.SubTrack != "no" {
+		t.Errorf("tracks = %q, %q, %q", sc.AudioTrack, sc.VideoTrack, sc.SubTrack)
+	}
+
+	// A player started by another process has no known URL
+	single, ok := sceneScreen(Screen1, "", map[string]any{"path": "/music/a.flac"})
+	if !ok || single.URL != "/music/a.flac" || single.PlaylistPos != 1 {
+		t.Errorf("path-only screen = %+v, %v", single, ok)
+	}
+	if _, ok := sceneScreen(Screen1, "", map[string]any{"idle-active": true}); ok {
+		t.Error("idle player saved")
+	}
+}
+
+func TestSceneStore(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.SceneDir = filepath.Join(t.TempDir(), "scenes")
+	lab := New(cfg)
+
+	for _, name := range []string{"", "../escape", ".hidden", `a\b`} {
+		if _, err := lab.SaveScene(name); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("SaveScene(%q) error = %v, want ErrInvalidArgument", name, err)
+		}
+	}
+
+	scenes, err := lab.Scenes()
+	if err != nil || len(scenes) != 0 {
+		t.Fatalf("Scenes() on a missing dir = %v, %v", scenes, err)
+	}
+
+	if _, err := lab.SaveScene("incident dashboard"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lab.SaveScene("standup"); err != nil {
+		t.Fatal(err)
+	}
+	scenes, err = lab.Scenes()
+	if err != nil || len(scenes) != 2 || scenes[0].Name != "incident dashboard" {
+		t.Errorf("Scenes() = %+v, %v", scenes, err)
+	}
+
+	if err := lab.DeleteScene("standup"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lab.GetScene("standup"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetScene(deleted) error = %v, want ErrNotFound", err)
+	}
+	if err := lab.DeleteScene("standup"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteScene(deleted) error = %v, want ErrNotFound", err)
+	}
+	if _, err := lab.LoadScene(context.Background(), "standup"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("LoadScene(deleted) error = %v, want ErrNotFound", err)
+	}
+
+	broken := `{"name": "broken", "screens": [{"screen": 7, "playlist": ["a.mp4"]}]}`
+	if err := os.WriteFile(filepath.Join(cfg.SceneDir, "broken.json"), []byte(broken), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := lab.LoadScene(context.Background(), "broken"); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("LoadScene(broken) error = %v, want ErrInvalidArgument", err)
+	}
+}
+
+func TestLoadScenePlayersOutliveContext(t *testing.T) {
+	lab := newTestLab(t)
+	lab.config.MPVBinary = fakeMPVBinary(t)
+	video := filepath.Join(t.TempDir(), "a.mp4")
+	os.WriteFile(video, nil, 0o644)
+	t.Cleanup(func() { lab.Stop(Screen1); lab.Stop(ScreenSpeaker) })
+
+	scene := Scene{Name: "wall", Screens: []SceneScreen{
+		{Screen: Screen1, URL: video, Playlist: []string{video}, Volume: 40, Position: 30},
+		{Screen: ScreenSpeaker, URL: video, Playlist: []string{video}, Volume: 60},
+	}}
+	data, _ := json.Marshal(scene)
+	os.MkdirAll(lab.config.SceneDir, 0o755)
+	if err := os.WriteFile(filepath.Join(lab.config.SceneDir, "wall.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	_, err := lab.LoadScene(ctx, "wall")
+	cancel()
+	if err != nil {
+		t.Fatalf("LoadScene() error = %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	for _, screen := range []Screen{Screen1, ScreenSpeaker} {
+		if !playerAlive(lab, screen) {
+			t.Errorf("%s died with LoadScene's context", screen)
+		}
+	}
+}
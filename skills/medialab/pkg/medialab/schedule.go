@@ -0,0 +1,598 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ScheduleEntry runs batch steps or loads a scene at the times given by a
+// cron expression, or once at a calendar time. With Until, the entry holds
+// its screens until that time of day and stops them then.
+type ScheduleEntry struct {
+	ID    string     `json:"id"`
+	Name  string     `json:"name,omitempty"`
+	Cron  string     `json:"cron,omitempty"`  // "minute hour day month weekday", local time
+	At    *time.Time `json:"at,omitempty"`    // one-off start instead of Cron
+	Until string     `json:"until,omitempty"` // "15:04": end of the window, "" = instant
+
+	Steps []BatchStep `json:"steps,omitempty"` // run with RunBatch, or
+	Scene string      `json:"scene,omitempty"` // loaded with LoadScene
+
+	AllowOverlap bool `json:"allow_overlap,omitempty"` // skip conflict detection for this entry
+	Disabled     bool `json:"disabled,omitempty"`
+
+	LastRun   *time.Time `json:"last_run,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// ScheduleConflict is an overlap of two entries on a screen
+type ScheduleConflict struct {
+	A      string    `json:"a"` // entry IDs
+	B      string    `json:"b"`
+	Screen Screen    `json:"screen"`
+	At     time.Time `json:"at"` // start of the first overlap
+}
+
+func (c ScheduleConflict) String() string {
+	return fmt.Sprintf("entries %s and %s overlap on %s at %s", c.A, c.B, c.Screen, c.At.Format("Mon 2006-01-02 15:04"))
+}
+
+// conflictHorizon is how far ahead recurring entries are compared
+const conflictHorizon = 14 * 24 * time.Hour
+
+// validate checks e and parses its timing
+func (e *ScheduleEntry) validate() (*cronSpec, error) {
+	var spec *cronSpec
+	switch {
+	case e.Cron != "" && e.At != nil:
+		return nil, fmt.Errorf("%w: set cron or at, not both", ErrInvalidArgument)
+	case e.Cron != "":
+		var err error
+		if spec, err = parseCron(e.Cron); err != nil {
+			return nil, err
+		}
+	case e.At == nil:
+		return nil, fmt.Errorf("%w: cron or at required", ErrInvalidArgument)
+	}
+	if e.Until != "" {
+		if _, err := time.Parse("15:04", e.Until); err != nil {
+			return nil, fmt.Errorf("%w: until must be HH:MM: %q", ErrInvalidArgument, e.Until)
+		}
+	}
+
+	switch {
+	case len(e.Steps) > 0 && e.Scene != "":
+		return nil, fmt.Errorf("%w: set steps or scene, not both", ErrInvalidArgument)
+	case e.Scene != "":
+		if !validSceneName(e.Scene) {
+			return nil, fmt.Errorf("%w: invalid scene name %q", ErrInvalidArgument, e.Scene)
+		}
+	case len(e.Steps) == 0:
+		return nil, fmt.Errorf("%w: steps or scene required", ErrInvalidArgument)
+	}
+	for i, step := range e.Steps {
+		if err := step.validate(); err != nil {
+			return nil, fmt.Errorf("step %d: %w", i+1, err)
+		}
+	}
+	return spec, nil
+}
+
+// next returns the entry's first start after t, false when there is none
+func (e *ScheduleEntry) next(t time.Time) (time.Time, bool) {
+	if e.At != nil {
+		return *e.At, e.At.After(t)
+	}
+	spec, err := parseCron(e.Cron)
+	if err != nil {
+		return time.Time{}, false
+	}
+	at := spec.next(t.In(time.Local))
+	return at, !at.IsZero()
+}
+
+// end returns when a window starting at start ends: the next Until after
+// start, or one minute later for instant entries
+func (e *ScheduleEntry) end(start time.Time) time.Time {
+	until, err := time.Parse("15:04", e.Until)
+	if err != nil {
+		return start.Add(time.Minute)
+	}
+	end := time.Date(start.Year(), start.Month(), start.Day(), until.Hour(), until.Minute(), 0, 0, start.Location())
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end
+}
+
+// screens returns the screens e acts on; scenes touch every screen
+func (e *ScheduleEntry) screens(defaultScreen Screen) []Screen {
+	all := []Screen{Screen1, Screen2, Screen3, Screen4, ScreenSpeaker}
+	if e.Scene != "" {
+		return all
+	}
+	var screens []Screen
+	for _, step := range e.Steps {
+		switch {
+		case step.Action == "wait":
+		case step.AllScreens:
+			return all
+		case len(step.Screens) == 0:
+			screens = append(screens, defaultScreen)
+		default:
+			screens = append(screens, step.Screens...)
+		}
+	}
+	slices.Sort(screens)
+	return slices.Compact(screens)
+}
+
+// Scheduler runs schedule entries kept in a JSON file. Entries can be
+// changed by any process; Run picks up changes to the file.
+type Scheduler struct {
+	lab  *MediaLab
+	path string
+
+	mu      sync.Mutex
+	entries []ScheduleEntry
+	modTime time.Time // of the file when last read
+	size    int64
+	changed chan struct{}
+}
+
+// Scheduler returns the scheduler of Config.ScheduleFile
+func (m *MediaLab) Scheduler() *Scheduler {
+	return m.scheduler
+}
+
+func newScheduler(m *MediaLab, path string) *Scheduler {
+	return &Scheduler{lab: m, path: path, changed: make(chan struct{}, 1)}
+}
+
+// loadLocked rereads the file when it changed since the last read
+func (s *Scheduler) loadLocked() error {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.entries, s.modTime, s.size = nil, time.Time{}, 0
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return nil
+	}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	var entries []ScheduleEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("parsing %s: %w", s.path, err)
+	}
+	s.entries, s.modTime, s.size = entries, info.ModTime(), info.Size()
+	return nil
+}
+
+func (s *Scheduler) saveLocked() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write schedule: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.modTime, s.size = info.ModTime(), info.Size()
+	}
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Entries returns the schedule ordered by ID
+func (s *Scheduler) Entries() ([]ScheduleEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	return slices.Clone(s.entries), nil
+}
+
+// Get returns the entry with id
+func (s *Scheduler) Get(id string) (ScheduleEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return ScheduleEntry{}, err
+	}
+	i := s.indexLocked(id)
+	if i < 0 {
+		return ScheduleEntry{}, fmt.Errorf("%w: schedule entry %q", ErrNotFound, id)
+	}
+	return s.entries[i], nil
+}
+
+func (s *Scheduler) indexLocked(id string) int {
+	return slices.IndexFunc(s.entries, func(e ScheduleEntry) bool { return e.ID == id })
+}
+
+// Add validates e, assigns its ID and saves it. Entries overlapping
+// another on a screen are rejected with ErrConflict unless one of them
+// sets AllowOverlap.
+func (s *Scheduler) Add(e ScheduleEntry) (ScheduleEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return ScheduleEntry{}, err
+	}
+	if _, err := e.validate(); err != nil {
+		return ScheduleEntry{}, err
+	}
+
+	nextID := 1
+	for _, existing := range s.entries {
+		if n, err := strconv.Atoi(existing.ID); err == nil && n >= nextID {
+			nextID = n + 1
+		}
+	}
+	e.ID = strconv.Itoa(nextID)
+	e.LastRun, e.LastError = nil, ""
+	if err := s.checkConflictsLocked(e); err != nil {
+		return ScheduleEntry{}, err
+	}
+
+	s.entries = append(s.entries, e)
+	return e, s.saveLocked()
+}
+
+// Update replaces the entry with id, keeping its run history
+func (s *Scheduler) Update(id string, e ScheduleEntry) (ScheduleEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return ScheduleEntry{}, err
+	}
+	i := s.indexLocked(id)
+	if i < 0 {
+		return ScheduleEntry{}, fmt.Errorf("%w: schedule entry %q", ErrNotFound, id)
+	}
+	if _, err := e.validate(); err != nil {
+		return ScheduleEntry{}, err
+	}
+	e.ID, e.LastRun, e.LastError = id, s.entries[i].LastRun, s.entries[i].LastError
+	if err := s.checkConflictsLocked(e); err != nil {
+		return ScheduleEntry{}, err
+	}
+
+	s.entries[i] = e
+	return e, s.saveLocked()
+}
+
+// SetDisabled disables or re-enables the entry with id
+func (s *Scheduler) SetDisabled(id string, disabled bool) (ScheduleEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return ScheduleEntry{}, err
+	}
+	i := s.indexLocked(id)
+	if i < 0 {
+		return ScheduleEntry{}, fmt.Errorf("%w: schedule entry %q", ErrNotFound, id)
+	}
+	if !disabled {
+		e := s.entries[i]
+		e.Disabled = false
+		if err := s.checkConflictsLocked(e); err != nil {
+			return ScheduleEntry{}, err
+		}
+	}
+	s.entries[i].Disabled = disabled
+	return s.entries[i], s.saveLocked()
+}
+
+// Remove deletes the entry with id
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return err
+	}
+	i := s.indexLocked(id)
+	if i < 0 {
+		return fmt.Errorf("%w: schedule entry %q", ErrNotFound, id)
+	}
+	s.entries = slices.Delete(s.entries, i, i+1)
+	return s.saveLocked()
+}
+
+// Conflicts returns the overlaps between enabled entries within the next
+// two weeks, including those allowed with AllowOverlap
+func (s *Scheduler) Conflicts() ([]ScheduleConflict, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.loadLocked(); err != nil {
+		return nil, err
+	}
+	var conflicts []ScheduleConflict
+	now := time.Now()
+	for i := range s.entries {
+		for j := i + 1; j < len(s.entries); j++ {
+			if c, ok := s.lab.entryConflict(s.entries[i], s.entries[j], now); ok {
+				conflicts = append(conflicts, c)
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+func (s *Scheduler) checkConflictsLocked(e ScheduleEntry) error {
+	if e.AllowOverlap || e.Disabled {
+		return nil
+	}
+	var errs []error
+	now := time.Now()
+	for _, other := range s.entries {
+		if other.ID == e.ID || other.AllowOverlap {
+			continue
+		}
+		if c, ok := s.lab.entryConflict(e, other, now); ok {
+			errs = append(errs, errors.New(c.String()))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrConflict, errors.Join(errs...))
+	}
+	return nil
+}
+
+// scheduleWindow is one occurrence of an entry
+type scheduleWindow struct {
+	start, end time.Time
+}
+
+// windows returns e's occurrences starting between from and to
+func (e *ScheduleEntry) windows(from, to time.Time) []scheduleWindow {
+	var windows []scheduleWindow
+	t := from
+	for len(windows) < 1000 {
+		start, ok := e.next(t)
+		if !ok || start.After(to) {
+			break
+		}
+		windows = append(windows, scheduleWindow{start, e.end(start)})
+		if e.At != nil {
+			break
+		}
+		t = start
+	}
+	return windows
+}
+
+// entryConflict reports the first overlap of a and b on a shared screen
+func (m *MediaLab) entryConflict(a, b ScheduleEntry, now time.Time) (ScheduleConflict, bool) {
+	if a.Disabled || b.Disabled {
+		return ScheduleConflict{}, false
+	}
+	var shared []Screen
+	bScreens := b.screens(m.config.DefaultScreen)
+	for _, screen := range a.screens(m.config.DefaultScreen) {
+		if slices.Contains(bScreens, screen) {
+			shared = append(shared, screen)
+		}
+	}
+	if len(shared) == 0 {
+		return ScheduleConflict{}, false
+	}
+
+	// Windows that started up to a day ago may still be running
+	from, to := now.Add(-24*time.Hour), now.Add(conflictHorizon)
+	bWindows := b.windows(from, to)
+	for _, wa := range a.windows(from, to) {
+		for _, wb := range bWindows {
+			if wa.start.Before(wb.end) && wb.start.Before(wa.end) && wa.end.After(now) && wb.end.After(now) {
+				at := wa.start
+				if wb.start.After(at) {
+					at = wb.start
+				}
+				return ScheduleConflict{A: a.ID, B: b.ID, Screen: shared[0], At: at}, true
+			}
+		}
+	}
+	return ScheduleConflict{}, false
+}
+
+// Run executes entries as they come due until ctx is done, calling onRun
+// (if non-nil) after each. Entries due while Run was not running are
+// skipped, not caught up. Windows ending while Run is running stop the
+// entry's screens.
+func (s *Scheduler) Run(ctx context.Context, onRun func(ScheduleEntry, error)) error {
+	s.mu.Lock()
+	err := s.loadLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	type window struct {
+		entry ScheduleEntry
+		end   time.Time
+	}
+	running := make(map[string]window) // by entry ID, for entries with Until
+	last := time.Now()
+	for {
+		wake := last.Add(30 * time.Second) // recheck the file for other writers
+		s.mu.Lock()
+		s.loadLocked()
+		for _, e := range s.entries {
+			if e.Disabled {
+				continue
+			}
+			if at, ok := e.next(last); ok && at.Before(wake) {
+				wake = at
+			}
+		}
+		for _, w := range running {
+			if w.end.Before(wake) {
+				wake = w.end
+			}
+		}
+		s.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.changed:
+			continue
+		case <-time.After(time.Until(wake)):
+		}
+
+		now := time.Now()
+		for id, w := range running {
+			if !w.end.After(now) {
+				delete(running, id)
+				s.lab.endEntry(w.entry)
+				s.lab.auditEntry(w.entry, "end", now, nil)
+			}
+		}
+
+		entries, _ := s.Entries()
+		for _, e := range entries {
+			if e.Disabled {
+				continue
+			}
+			if at, ok := e.next(last); ok && !at.After(now) {
+				err := s.lab.runEntry(ctx, e)
+				s.recordRun(e.ID, now, err)
+				s.lab.auditEntry(e, "run", now, err)
+				if onRun != nil {
+					onRun(e, err)
+				}
+				if e.Until != "" {
+					running[e.ID] = window{e, e.end(at)}
+				}
+			}
+		}
+		last = now
+	}
+}
+
+func (s *Scheduler) recordRun(id string, at time.Time, runErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.loadLocked() != nil {
+		return
+	}
+	if i := s.indexLocked(id); i >= 0 {
+		s.entries[i].LastRun = &at
+		s.entries[i].LastError = ""
+		if runErr != nil {
+			s.entries[i].LastError = runErr.Error()
+		}
+		s.saveLocked()
+	}
+}
+
+// runEntry executes e's steps or loads its scene. The timeout bounds
+// resolving and starting players, which keep playing after it returns.
+func (m *MediaLab) runEntry(ctx context.Context, e ScheduleEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	if e.Scene != "" {
+		_, err := m.LoadScene(ctx, e.Scene)
+		return err
+	}
+	result, err := m.RunBatch(ctx, e.Steps, BatchOptions{})
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		var errs []error
+		for _, step := range result.Steps {
+			if step.Error != "" {
+				errs = append(errs, fmt.Errorf("step %d (%s): %s", step.Index, step.Action, step.Error))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// auditEntry records a run or end of an entry that started at start in
+// the audit log
+func (m *MediaLab) auditEntry(e ScheduleEntry, action string, start time.Time, err error) {
+	entry := AuditEntry{
+		Actor:      "schedule:" + e.ID,
+		Source:     "scheduler",
+		Action:     action,
+		Args:       auditArgs(e),
+		DurationMS: time.Since(start).Milliseconds(),
+	}
+	if screens := e.screens(m.config.DefaultScreen); len(screens) == 1 {
+		entry.Screen = auditScreen(screens[0])
+	}
+	if err != nil {
+		entry.Result, entry.Error = errorCode(err), err.Error()
+	}
+	m.Audit(entry)
+}
+
+// endEntry stops the screens of an entry whose window is over
+func (m *MediaLab) endEntry(e ScheduleEntry) {
+	for _, screen := range e.screens(m.config.DefaultScreen) {
+		m.stopAny(screen)
+	}
+}
+
+// NextRuns returns the next start of each enabled entry after t, soonest
+// first
+func (s *Scheduler) NextRuns(t time.Time) ([]ScheduledRun, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return nil, err
+	}
+	var runs []ScheduledRun
+	for _, e := range entries {
+		if e.Disabled {
+			continue
+		}
+		if at, ok := e.next(t); ok {
+			run := ScheduledRun{ID: e.ID, Name: e.Name, Start: at}
+			if e.Until != "" {
+				end := e.end(at)
+				run.End = &end
+			}
+			runs = append(runs, run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Start.Before(runs[j].Start) })
+	return runs, nil
+}
+
+// ScheduledRun is an upcoming start of an entry
+type ScheduledRun struct {
+	ID    string     `json:"id"`
+	Name  string     `json:"name,omitempty"`
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"`
+}
@@ -0,0 +1,214 @@
+package medialab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScheduleEntryValidate(t *testing.T) {
+	at := time.Date(2026, 10, 20, 12, 0, 0, 0, time.Local)
+	play := []BatchStep{{Action: "play", Screens: []Screen{Screen1}, URL: "https://example.com/news.m3u8"}}
+
+	valid := []ScheduleEntry{
+		{Cron: "0 9 * * mon-fri", Until: "09:30", Steps: play},
+		{At: &at, Scene: "standup"},
+		{Cron: "0 22 * * *", Steps: []BatchStep{{Action: "stop", AllScreens: true}}},
+	}
+	for _, e := range valid {
+		if _, err := e.validate(); err != nil {
+			t.Errorf("validate(%+v) error = %v", e, err)
+		}
+	}
+
+	invalid := []ScheduleEntry{
+		{Steps: play},
+		{Cron: "0 9 * * *", At: &at, Steps: play},
+		{Cron: "0 9 * *", Steps: play},
+		{Cron: "0 9 * * *"},
+		{Cron: "0 9 * * *", Steps: play, Scene: "standup"},
+		{Cron: "0 9 * * *", Scene: "../escape"},
+		{Cron: "0 9 * * *", Until: "9h30", Steps: play},
+		{Cron: "0 9 * * *", Steps: []BatchStep{{Action: "explode"}}},
+	}
+	for _, e := range invalid {
+		if _, err := e.validate(); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("validate(%+v) error = %v, want ErrInvalidArgument", e, err)
+		}
+	}
+}
+
+func TestScheduleEntryWindow(t *testing.T) {
+	start := time.Date(2026, 10, 19, 23, 0, 0, 0, time.UTC)
+	e := ScheduleEntry{Until: "01:00"}
+	if got, want := e.end(start), start.Add(2*time.Hour); !got.Equal(want) {
+		t.Errorf("end() past midnight = %s, want %s", got, want)
+	}
+	if got, want := (&ScheduleEntry{}).end(start), start.Add(time.Minute); !got.Equal(want) {
+		t.Errorf("end() without until = %s, want %s", got, want)
+	}
+
+	e = ScheduleEntry{Steps: []BatchStep{
+		{Action: "play", Screens: []Screen{Screen3, Screen1}},
+		{Action: "wait", Seconds: 5},
+		{Action: "volume"},
+	}}
+	if got := e.screens(Screen2); len(got) != 3 || got[0] != Screen1 || got[1] != Screen2 || got[2] != Screen3 {
+		t.Errorf("screens() = %v, want [1 2 3] (0-based)", got)
+	}
+	if got := (&ScheduleEntry{Scene: "x"}).screens(Screen1); len(got) != 5 {
+		t.Errorf("scene screens() = %v, want all 5", got)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	lab := newTestLab(t)
+	scheduler := lab.Scheduler()
+
+	news, err := scheduler.Add(ScheduleEntry{
+		Name: "news", Cron: "0 9 * * *", Until: "09:30",
+		Steps: []BatchStep{{Action: "play", Screens: []Screen{Screen1}, URL: "https://example.com/news.m3u8"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if news.ID != "1" {
+		t.Errorf("first ID = %q, want 1", news.ID)
+	}
+
+	// Inside the news window on the same screen
+	clash := ScheduleEntry{Cron: "15 9 * * *", Steps: []BatchStep{{Action: "volume", Screens: []Screen{Screen1}, Volume: 20}}}
+	if _, err := scheduler.Add(clash); !errors.Is(err, ErrConflict) {
+		t.Errorf("Add(overlapping) error = %v, want ErrConflict", err)
+	}
+	if _, err := scheduler.Add(ScheduleEntry{Cron: "10 9 * * *", Scene: "standup"}); !errors.Is(err, ErrConflict) {
+		t.Errorf("Add(scene during window) error = %v, want ErrConflict", err)
+	}
+
+	// Another screen, or after the window, is fine
+	other := clash
+	other.Steps = []BatchStep{{Action: "volume", Screens: []Screen{Screen2}, Volume: 20}}
+	if _, err := scheduler.Add(other); err != nil {
+		t.Errorf("Add(other screen) error = %v", err)
+	}
+	later := clash
+	later.Cron = "30 9 * * *"
+	if _, err := scheduler.Add(later); err != nil {
+		t.Errorf("Add(after window) error = %v", err)
+	}
+
+	clash.AllowOverlap = true
+	allowed, err := scheduler.Add(clash)
+	if err != nil {
+		t.Fatalf("Add(allow_overlap) error = %v", err)
+	}
+	conflicts, err := scheduler.Conflicts()
+	if err != nil || len(conflicts) != 1 || conflicts[0].A != news.ID || conflicts[0].B != allowed.ID || conflicts[0].Screen != Screen1 {
+		t.Errorf("Conflicts() = %+v, %v", conflicts, err)
+	}
+
+	if _, err := scheduler.SetDisabled(news.ID, true); err != nil {
+		t.Fatal(err)
+	}
+	if conflicts, _ := scheduler.Conflicts(); len(conflicts) != 0 {
+		t.Errorf("Conflicts() with news disabled = %+v", conflicts)
+	}
+
+	// Another process reading the same file sees the entries
+	reread := New(&Config{ScheduleFile: lab.config.ScheduleFile}).Scheduler()
+	entries, err := reread.Entries()
+	if err != nil || len(entries) != 4 || !entries[0].Disabled {
+		t.Fatalf("Entries() from file = %+v, %v", entries, err)
+	}
+
+	updated, err := reread.Update(news.ID, ScheduleEntry{Name: "news", Cron: "0 18 * * *", Steps: news.Steps})
+	if err != nil || updated.Cron != "0 18 * * *" || updated.Disabled {
+		t.Errorf("Update() = %+v, %v", updated, err)
+	}
+	if got, _ := scheduler.Get(news.ID); got.Cron != "0 18 * * *" {
+		t.Errorf("Get() after update elsewhere = %+v", got)
+	}
+
+	if err := scheduler.Remove(news.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := scheduler.Get(news.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(removed) error = %v, want ErrNotFound", err)
+	}
+	if err := scheduler.Remove(news.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Remove(removed) error = %v, want ErrNotFound", err)
+	}
+	if e, _ := scheduler.Add(ScheduleEntry{Cron: "0 3 * * *", Steps: later.Steps}); e.ID != "5" {
+		t.Errorf("ID after removal = %q, want 5", e.ID)
+	}
+
+	next, err := scheduler.NextRuns(time.Now())
+	if err != nil || len(next) != 4 || next[0].Start.After(next[len(next)-1].Start) {
+		t.Errorf("NextRuns() = %+v, %v", next, err)
+	}
+}
+
+func TestRunEntryPlayerOutlivesRun(t *testing.T) {
+	lab := newTestLab(t)
+	lab.config.MPVBinary = fakeMPVBinary(t)
+	video := filepath.Join(t.TempDir(), "a.mp4")
+	os.WriteFile(video, nil, 0o644)
+	t.Cleanup(func() { lab.Stop(Screen3) })
+
+	e := ScheduleEntry{ID: "1", Cron: "0 9 * * *", Steps: []BatchStep{{Action: "play", Screens: []Screen{Screen3}, URL: video}}}
+	if err := lab.runEntry(context.Background(), e); err != nil {
+		t.Fatalf("runEntry() error = %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if !playerAlive(lab, Screen3) {
+		t.Error("the player died when runEntry returned")
+	}
+}
+
+func TestV1Schedule(t *testing.T) {
+	lab := newTestLab(t)
+	lab.config.Auth = AuthConfig{Clients: []APIClient{
+		{Name: "wall", Token: "wall-token", Scopes: []Scope{ScopeRead, ScopeControl, ScopePlay}, Screens: []Screen{Screen2}},
+	}}
+	s := NewServer(lab)
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer wall-token")
+		return serveRequest(s, r)
+	}
+
+	news := `{"cron": "0 9 * * mon-fri", "until": "09:30", "steps": [{"action": "play", "screen": 2, "url": "https://example.com/news.m3u8"}]}`
+	w := request("POST", "/v1/schedule", news)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/v1/schedule/1" {
+		t.Fatalf("POST /v1/schedule = %d %s", w.Code, w.Body)
+	}
+	if w := request("POST", "/v1/schedule", news); w.Code != http.StatusConflict || decodeEnvelope(t, w)["code"] != "conflict" {
+		t.Errorf("overlapping POST = %d %s, want 409 conflict", w.Code, w.Body)
+	}
+	if w := request("POST", "/v1/schedule", `{"cron": "0 12 * * *", "scene": "standup"}`); w.Code != http.StatusForbidden {
+		t.Errorf("scene entry from a screen-limited client = %d, want 403", w.Code)
+	}
+	if w := request("POST", "/v1/schedule", `{"cron": "0 12 * *", "steps": [{"action": "stop", "screen": 2}]}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid cron = %d, want 400", w.Code)
+	}
+
+	w = request("GET", "/v1/schedule", "")
+	if body := decodeEnvelope(t, w); w.Code != http.StatusOK || body["count"] != 1.0 {
+		t.Errorf("GET /v1/schedule = %d %v", w.Code, body)
+	}
+	if w := request("PUT", "/v1/schedule/1", `{"cron": "0 22 * * *", "steps": [{"action": "stop", "screen": 2}]}`); w.Code != http.StatusOK {
+		t.Errorf("PUT /v1/schedule/1 = %d %s", w.Code, w.Body)
+	}
+	if w := request("DELETE", "/v1/schedule/1", ""); w.Code != http.StatusOK {
+		t.Errorf("DELETE /v1/schedule/1 = %d %s", w.Code, w.Body)
+	}
+	if w := request("GET", "/v1/schedule/1", ""); w.Code != http.StatusNotFound {
+		t.Errorf("GET deleted entry = %d, want 404", w.Code)
+	}
+}
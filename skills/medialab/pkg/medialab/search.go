@@ -0,0 +1,535 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SearchResult is a search hit normalized across providers. Fields a
+// provider cannot supply are left zero.
+type SearchResult struct {
+	Provider        string  `json:"provider"`
+	ID              string  `json:"id"`
+	Title           string  `json:"title"`
+	Channel         string  `json:"channel"`  // uploader, creator or artist
+	Duration        string  `json:"duration"` // formatted "M:SS" ("?" if unknown)
+	DurationSeconds float64 `json:"duration_seconds"`
+	URL             string  `json:"url"` // playable URL or file path
+
+	ViewCount   int64  `json:"view_count,omitempty"`
+	UploadDate  string `json:"upload_date,omitempty"` // YYYYMMDD
+	Thumbnail   string `json:"thumbnail,omitempty"`
+	LiveStatus  string `json:"live_status,omitempty"` // is_live, is_upcoming, was_live, not_live
+	Live        bool   `json:"live"`
+	Description string `json:"description,omitempty"`
+}
+
+// SearchOptions limits, filters and orders search results
+type SearchOptions struct {
+	MaxResults  int
+	MinDuration float64 // seconds, 0 = no minimum
+	MaxDuration float64 // seconds, 0 = no maximum
+	ExcludeLive bool    // drop live and upcoming streams
+	Sort        string  // relevance (default), date, views, duration
+
+	// Full fetches complete metadata per video instead of yt-dlp's
+	// --flat-playlist listing. Flat results usually lack upload_date and
+	// carry only a description snippet, but full mode costs one request per
+	// result and is many times slower.
+	Full bool
+
+	// Remember makes the results the ones LastResults returns and
+	// PlayResult picks from. Searches shown to a user set it; lookups such
+	// as ResolveQuery leave the user's last results alone.
+	Remember bool
+}
+
+// Sort orders for SearchOptions.Sort
+const (
+	SortRelevance = "relevance"
+	SortDate      = "date"
+	SortViews     = "views"
+	SortDuration  = "duration"
+)
+
+// maxSearchFetch caps over-fetching when filters may discard results
+const maxSearchFetch = 50
+
+func (o SearchOptions) filtering() bool {
+	return o.MinDuration > 0 || o.MaxDuration > 0 || o.ExcludeLive
+}
+
+// keep reports whether a result passes the filters. Results with unknown
+// duration are dropped when a duration bound is set.
+func (o SearchOptions) keep(r SearchResult) bool {
+	if o.ExcludeLive && r.Live {
+		return false
+	}
+	if (o.MinDuration > 0 || o.MaxDuration > 0) && r.DurationSeconds <= 0 {
+		return false
+	}
+	if o.MinDuration > 0 && r.DurationSeconds < o.MinDuration {
+		return false
+	}
+	if o.MaxDuration > 0 && r.DurationSeconds > o.MaxDuration {
+		return false
+	}
+	return true
+}
+
+// apply filters, orders and trims provider results
+func (o SearchOptions) apply(results []SearchResult) []SearchResult {
+	kept := results[:0]
+	for _, r := range results {
+		if o.keep(r) {
+			kept = append(kept, r)
+		}
+	}
+
+	switch o.Sort {
+	case SortDate:
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].UploadDate > kept[j].UploadDate })
+	case SortViews:
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].ViewCount > kept[j].ViewCount })
+	case SortDuration:
+		sort.SliceStable(kept, func(i, j int) bool { return kept[i].DurationSeconds < kept[j].DurationSeconds })
+	}
+
+	if len(kept) > o.MaxResults {
+		kept = kept[:o.MaxResults]
+	}
+	return kept
+}
+
+// SearchProvider finds playable media for a free-text query. Providers
+// should return up to opts.MaxResults results; filtering and ordering are
+// applied afterwards by MediaLab.Search.
+type SearchProvider interface {
+	Name() string
+	Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error)
+}
+
+// DefaultSearchProvider is used when a request names no provider
+const DefaultSearchProvider = "youtube"
+
+// ErrUnknownProvider is returned for a provider name that is neither
+// registered nor a yt-dlp search prefix
+var ErrUnknownProvider = errors.New("unknown search provider")
+
+// RegisterSearchProvider adds or replaces a search provider by name
+func (m *MediaLab) RegisterSearchProvider(p SearchProvider) {
+	m.searchMu.Lock()
+	defer m.searchMu.Unlock()
+	m.providers[p.Name()] = p
+}
+
+// SearchProviders returns the names of registered providers
+func (m *MediaLab) SearchProviders() []string {
+	m.searchMu.RLock()
+	defer m.searchMu.RUnlock()
+	names := make([]string, 0, len(m.providers))
+	for name := range m.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Search runs a query against a named provider. Besides registered names,
+// any yt-dlp search prefix (lowercase letters and digits ending in "search",
+// e.g. "bilisearch", "nicosearch") is accepted.
+func (m *MediaLab) Search(ctx context.Context, provider, query string, opts SearchOptions) ([]SearchResult, error) {
+	if provider == "" {
+		provider = DefaultSearchProvider
+	}
+	if opts.MaxResults <= 0 {
+		opts.MaxResults = 10
+	}
+	switch opts.Sort {
+	case "", SortRelevance, SortDate, SortViews, SortDuration:
+	default:
+		return nil, fmt.Errorf("%w: sort order %q", ErrInvalidArgument, opts.Sort)
+	}
+
+	m.searchMu.RLock()
+	p, ok := m.providers[provider]
+	m.searchMu.RUnlock()
+	if !ok {
+		if !ytdlpSearchPrefix.MatchString(provider) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownProvider, provider)
+		}
+		p = &ytdlpSearchProvider{lab: m, name: provider, prefix: provider}
+	}
+
+	// Over-fetch when filters may discard results
+	fetch := opts
+	if opts.filtering() {
+		fetch.MaxResults = opts.MaxResults * 3
+		if fetch.MaxResults > maxSearchFetch {
+			fetch.MaxResults = maxSearchFetch
+		}
+	}
+
+	// The local index is already fast; everything else shells out or hits
+	// the network, so serve repeats from the cache
+	key := ""
+	if provider != "local" {
+		key = searchCacheKey(provider, query, opts)
+		var cached cachedSearch
+		if m.cache.Get(key, &cached) && (cached.Max >= opts.MaxResults || len(cached.Results) < cached.Max) {
+			if len(cached.Results) > opts.MaxResults {
+				cached.Results = cached.Results[:opts.MaxResults]
+			}
+			if opts.Remember {
+				m.rememberResults(cached.Results)
+			}
+			return cached.Results, nil
+		}
+	}
+
+	start := time.Now()
+	results, err := p.Search(ctx, query, fetch)
+	m.metrics.observe("medialab_search_duration_seconds", time.Since(start), "provider", provider)
+	if err != nil {
+		m.metrics.inc("medialab_search_errors_total", "provider", provider)
+		return nil, err
+	}
+	results = opts.apply(results)
+	if key != "" {
+		m.cache.Set(key, cachedSearch{Max: opts.MaxResults, Results: results})
+	}
+	if opts.Remember {
+		m.rememberResults(results)
+	}
+	return results, nil
+}
+
+// cachedSearch is a cached result list; Max is the limit it was fetched
+// with, so smaller requests for the same query reuse it
+type cachedSearch struct {
+	Max     int            `json:"max"`
+	Results []SearchResult `json:"results"`
+}
+
+// searchCacheKey identifies a search by everything but its result limit
+func searchCacheKey(provider, query string, opts SearchOptions) string {
+	query = strings.ToLower(strings.Join(strings.Fields(query), " "))
+	return fmt.Sprintf("search|%s|%s|%g|%g|%t|%s|%t",
+		provider, query, opts.MinDuration, opts.MaxDuration, opts.ExcludeLive, opts.Sort, opts.Full)
+}
+
+// === Last results ===
+
+// youtubeID matches a bare YouTube video ID
+var youtubeID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// rememberResults records the latest search so its results can be played by
+// number. They are also written to Config.LastSearchFile, which lets
+// separate CLI invocations pick from a previous search.
+func (m *MediaLab) rememberResults(results []SearchResult) {
+	results = append([]SearchResult{}, results...)
+	m.lastMu.Lock()
+	defer m.lastMu.Unlock()
+	m.lastResults = results
+
+	if path := m.config.LastSearchFile; path != "" {
+		if data, err := json.Marshal(results); err == nil && os.MkdirAll(filepath.Dir(path), 0755) == nil {
+			os.WriteFile(path, data, 0644)
+		}
+	}
+}
+
+// LastResults returns the results of the most recent search, in order
+func (m *MediaLab) LastResults() []SearchResult {
+	m.lastMu.Lock()
+	defer m.lastMu.Unlock()
+	if m.lastResults == nil && m.config.LastSearchFile != "" {
+		if data, err := os.ReadFile(m.config.LastSearchFile); err == nil {
+			json.Unmarshal(data, &m.lastResults)
+		}
+	}
+	return m.lastResults
+}
+
+// ResultAt returns the nth (1-based) result of the most recent search
+func (m *MediaLab) ResultAt(n int) (*SearchResult, error) {
+	results := m.LastResults()
+	if len(results) == 0 {
+		return nil, fmt.Errorf("%w: no previous search results", ErrInvalidArgument)
+	}
+	if n < 1 || n > len(results) {
+		return nil, fmt.Errorf("%w: result %d out of range (1-%d)", ErrInvalidArgument, n, len(results))
+	}
+	return &results[n-1], nil
+}
+
+// PlayResult plays the nth (1-based) result of the most recent search
+func (m *MediaLab) PlayResult(ctx context.Context, n int, screen Screen) (*PlayerInstance, *SearchResult, error) {
+	result, err := m.ResultAt(n)
+	if err != nil {
+		return nil, nil, err
+	}
+	instance, err := m.Play(ctx, result.URL, screen)
+	return instance, result, err
+}
+
+// PlayVideoID plays a YouTube video by its 11-character ID
+func (m *MediaLab) PlayVideoID(ctx context.Context, id string, screen Screen) (*PlayerInstance, error) {
+	url, err := VideoURL(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.Play(ctx, url, screen)
+}
+
+// VideoURL returns the watch URL for a YouTube video ID
+func VideoURL(id string) (string, error) {
+	if !youtubeID.MatchString(id) {
+		return "", fmt.Errorf("%w: invalid YouTube video ID %q", ErrInvalidArgument, id)
+	}
+	return "https://www.youtube.com/watch?v=" + id, nil
+}
+
+func (m *MediaLab) registerDefaultProviders() {
+	m.RegisterSearchProvider(&ytdlpSearchProvider{lab: m, name: "youtube", prefix: "ytsearch"})
+	m.RegisterSearchProvider(&ytdlpSearchProvider{lab: m, name: "soundcloud", prefix: "scsearch"})
+	m.RegisterSearchProvider(&archiveSearchProvider{client: http.DefaultClient})
+	m.RegisterSearchProvider(&localSearchProvider{library: m.library})
+}
+
+// === yt-dlp search prefixes (ytsearch, scsearch, ...) ===
+
+// ytdlpSearchPrefix matches the search prefixes of yt-dlp extractors. The
+// prefix becomes part of yt-dlp's arguments, so nothing else is accepted.
+var ytdlpSearchPrefix = regexp.MustCompile(`^[a-z0-9]+search$`)
+
+type ytdlpSearchProvider struct {
+	lab    *MediaLab
+	name   string
+	prefix string
+}
+
+func (p *ytdlpSearchProvider) Name() string { return p.name }
+
+func (p *ytdlpSearchProvider) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	prefix := p.prefix
+	if prefix == "ytsearch" && opts.Sort == SortDate {
+		prefix = "ytsearchdate" // newest first, server-side
+	}
+	results, err := p.lab.searchYTDLP(ctx, prefix, query, opts.MaxResults, opts.Full)
+	if err != nil {
+		return nil, err
+	}
+	for i := range results {
+		results[i].Provider = p.name
+	}
+	return results, nil
+}
+
+// ytdlpEntry is the subset of yt-dlp's JSON used for search results
+type ytdlpEntry struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Channel     string  `json:"channel"`
+	Uploader    string  `json:"uploader"`
+	Duration    float64 `json:"duration"`
+	URL         string  `json:"url"`
+	WebpageURL  string  `json:"webpage_url"`
+	ViewCount   int64   `json:"view_count"`
+	UploadDate  string  `json:"upload_date"`
+	Thumbnail   string  `json:"thumbnail"`
+	LiveStatus  string  `json:"live_status"`
+	IsLive      bool    `json:"is_live"`
+	Description string  `json:"description"`
+	Thumbnails  []struct {
+		URL string `json:"url"`
+	} `json:"thumbnails"`
+}
+
+func (e *ytdlpEntry) result() SearchResult {
+	r := SearchResult{
+		ID:              e.ID,
+		Title:           e.Title,
+		Channel:         e.Channel,
+		Duration:        formatDuration(int(e.Duration)),
+		DurationSeconds: e.Duration,
+		URL:             e.WebpageURL,
+		ViewCount:       e.ViewCount,
+		UploadDate:      e.UploadDate,
+		Thumbnail:       e.Thumbnail,
+		LiveStatus:      e.LiveStatus,
+		Live:            e.IsLive || e.LiveStatus == "is_live" || e.LiveStatus == "is_upcoming",
+		Description:     e.Description,
+	}
+	if r.Channel == "" {
+		r.Channel = e.Uploader
+	}
+	if r.URL == "" {
+		r.URL = e.URL
+	}
+	// Flat entries list thumbnails smallest first instead of a single URL
+	if r.Thumbnail == "" && len(e.Thumbnails) > 0 {
+		r.Thumbnail = e.Thumbnails[len(e.Thumbnails)-1].URL
+	}
+	return r
+}
+
+// searchYTDLP runs "<prefix><N>:<query>" through yt-dlp, in flat mode
+// unless full metadata is requested
+func (m *MediaLab) searchYTDLP(ctx context.Context, prefix, query string, maxResults int, full bool) ([]SearchResult, error) {
+	args := []string{"--dump-json", "--no-download"}
+	if full {
+		args = append(args, "--ignore-errors") // skip unavailable videos instead of failing
+	} else {
+		args = append(args, "--flat-playlist")
+	}
+	args = append(args, "--", prefix+strconv.Itoa(maxResults)+":"+query)
+	cmd := exec.CommandContext(ctx, m.config.YTDLPBinary, args...)
+	output, err := cmd.Output()
+	if err != nil && (!full || len(output) == 0) {
+		return nil, fmt.Errorf("yt-dlp search failed: %w", err)
+	}
+	return parseYTDLPEntries(output)
+}
+
+// parseYTDLPEntries parses yt-dlp --dump-json output, one entry per line.
+// Unparseable lines are skipped, but an error is returned if no line parses.
+func parseYTDLPEntries(output []byte) ([]SearchResult, error) {
+	var results []SearchResult
+	var skipped int
+	var lastErr error
+	for _, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var entry ytdlpEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			skipped++
+			lastErr = err
+			continue
+		}
+		results = append(results, entry.result())
+	}
+	if len(results) == 0 && skipped > 0 {
+		return nil, fmt.Errorf("failed to parse %d yt-dlp entries: %w", skipped, lastErr)
+	}
+	return results, nil
+}
+
+// === Internet Archive ===
+
+const archiveSearchURL = "https://archive.org/advancedsearch.php"
+
+type archiveSearchProvider struct {
+	client *http.Client
+}
+
+func (p *archiveSearchProvider) Name() string { return "archive" }
+
+func (p *archiveSearchProvider) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	params := url.Values{}
+	params.Set("q", "("+query+") AND mediatype:(movies OR audio)")
+	params.Add("fl[]", "identifier")
+	params.Add("fl[]", "title")
+	params.Add("fl[]", "creator")
+	params.Set("rows", strconv.Itoa(opts.MaxResults))
+	params.Set("output", "json")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archiveSearchURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("archive.org search failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("archive.org search failed: %s", resp.Status)
+	}
+
+	var body struct {
+		Response struct {
+			Docs []struct {
+				Identifier string `json:"identifier"`
+				Title      any    `json:"title"`
+				Creator    any    `json:"creator"`
+			} `json:"docs"`
+		} `json:"response"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid archive.org response: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(body.Response.Docs))
+	for _, doc := range body.Response.Docs {
+		results = append(results, SearchResult{
+			Provider: "archive",
+			ID:       doc.Identifier,
+			Title:    firstString(doc.Title),
+			Channel:  firstString(doc.Creator),
+			Duration: formatDuration(0),
+			URL:      "https://archive.org/details/" + doc.Identifier,
+		})
+	}
+	return results, nil
+}
+
+// firstString handles archive.org fields that may be a string or a list
+func firstString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case []any:
+		if len(val) > 0 {
+			s, _ := val[0].(string)
+			return s
+		}
+	}
+	return ""
+}
+
+// === Local library ===
+
+type localSearchProvider struct {
+	library *Library
+}
+
+func (p *localSearchProvider) Name() string { return "local" }
+
+// Search queries the library index, scanning once if it was never built
+func (p *localSearchProvider) Search(ctx context.Context, query string, opts SearchOptions) ([]SearchResult, error) {
+	if p.library.Stats().LastScan.IsZero() {
+		if _, err := p.library.Scan(ctx); err != nil {
+			return nil, fmt.Errorf("library scan failed: %w", err)
+		}
+	}
+
+	matches := p.library.Search(query, opts.MaxResults)
+	results := make([]SearchResult, 0, len(matches))
+	for _, match := range matches {
+		results = append(results, SearchResult{
+			Provider:        "local",
+			ID:              match.Path,
+			Title:           match.Title,
+			Channel:         match.Artist,
+			Duration:        formatDuration(int(match.Duration)),
+			DurationSeconds: match.Duration,
+			URL:             match.Path,
+		})
+	}
+	return results, nil
+}
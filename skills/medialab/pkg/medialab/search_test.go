@@ -0,0 +1,225 @@
+package medialab
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSearchProvidersDefault(t *testing.T) {
+	lab := New(nil)
+	got := lab.SearchProviders()
+	want := []string{"archive", "local", "soundcloud", "youtube"}
+
+	if len(got) != len(want) {
+		t.Fatalf("SearchProviders() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("SearchProviders()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSearchUnknownProvider(t *testing.T) {
+	lab := New(nil)
+	_, err := lab.Search(context.Background(), "nosuchprovider", "query", SearchOptions{MaxResults: 5})
+	if !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("Search() error = %v, want ErrUnknownProvider", err)
+	}
+}
+
+func TestSearchAdHocProvider(t *testing.T) {
+	lab := newTestLab(t)
+	argsFile := filepath.Join(t.TempDir(), "args")
+	lab.config.YTDLPBinary = writeScript(t, t.TempDir(), "yt-dlp", `printf '%s\n' "$@" > `+argsFile+`
+echo '{"id": "sm1", "title": "Found", "url": "https://example.com/sm1"}'
+`)
+
+	for _, provider := range []string{"--exec=touch /tmp/pwned;search", "Nicosearch", "search", "nico search"} {
+		if _, err := lab.Search(context.Background(), provider, "q", SearchOptions{}); !errors.Is(err, ErrUnknownProvider) {
+			t.Errorf("Search(%q) error = %v, want ErrUnknownProvider", provider, err)
+		}
+	}
+	if _, err := os.Stat(argsFile); err == nil {
+		t.Fatal("yt-dlp ran for a rejected provider")
+	}
+
+	results, err := lab.Search(context.Background(), "nicosearch", "-v cats", SearchOptions{MaxResults: 3})
+	if err != nil || len(results) != 1 || results[0].Provider != "nicosearch" {
+		t.Fatalf("Search(nicosearch) = %+v, %v", results, err)
+	}
+	data, _ := os.ReadFile(argsFile)
+	args := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if n := len(args); n < 2 || args[n-2] != "--" || args[n-1] != "nicosearch3:-v cats" {
+		t.Errorf("yt-dlp args = %q, want the search target last, after --", args)
+	}
+}
+
+func TestLocalSearchProvider(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{
+		"Jazz/Miles Davis - So What.flac",
+		"Jazz/Coltrane - Naima.mp3",
+		"Jazz/cover.jpg",
+		"Films/So What Happened.mkv",
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, nil, 0644)
+	}
+
+	cfg := DefaultConfig()
+	cfg.LibraryDirs = []string{dir}
+	cfg.LibraryIndex = filepath.Join(t.TempDir(), "library.json")
+	cfg.FFprobeBinary = "/nonexistent/ffprobe"
+	cfg.LastSearchFile = ""
+	lab := New(cfg)
+
+	results, err := lab.Search(context.Background(), "local", "so what jazz", SearchOptions{MaxResults: 10})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1: %+v", len(results), results)
+	}
+	if results[0].Title != "Miles Davis - So What" {
+		t.Errorf("Title = %q, want %q", results[0].Title, "Miles Davis - So What")
+	}
+	if results[0].Provider != "local" {
+		t.Errorf("Provider = %q, want %q", results[0].Provider, "local")
+	}
+
+	results, _ = lab.Search(context.Background(), "local", "cover", SearchOptions{MaxResults: 10})
+	if len(results) != 0 {
+		t.Errorf("non-media file matched: %+v", results)
+	}
+}
+
+func TestFirstString(t *testing.T) {
+	if got := firstString("a"); got != "a" {
+		t.Errorf("firstString(string) = %q", got)
+	}
+	if got := firstString([]any{"b", "c"}); got != "b" {
+		t.Errorf("firstString(list) = %q", got)
+	}
+	if got := firstString(nil); got != "" {
+		t.Errorf("firstString(nil) = %q", got)
+	}
+}
+
+func TestParseYTDLPEntries(t *testing.T) {
+	output := []byte(`{"id":"a","title":"Song","channel":"Band","duration":213.0,"url":"https://www.youtube.com/watch?v=a","view_count":1500,"thumbnails":[{"url":"small.jpg"},{"url":"large.jpg"}]}
+not json
+{"id":"b","title":"Radio","uploader":"Station","duration":null,"url":"https://www.youtube.com/watch?v=b","live_status":"is_live"}
+`)
+
+	results, err := parseYTDLPEntries(output)
+	if err != nil {
+		t.Fatalf("parseYTDLPEntries() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("parseYTDLPEntries() returned %d results, want 2", len(results))
+	}
+
+	song := results[0]
+	if song.DurationSeconds != 213 || song.Duration != "3:33" {
+		t.Errorf("duration = %v (%q), want 213 (3:33)", song.DurationSeconds, song.Duration)
+	}
+	if song.Thumbnail != "large.jpg" {
+		t.Errorf("Thumbnail = %q, want largest flat thumbnail", song.Thumbnail)
+	}
+	if song.ViewCount != 1500 {
+		t.Errorf("ViewCount = %d, want 1500", song.ViewCount)
+	}
+
+	radio := results[1]
+	if !radio.Live {
+		t.Error("live_status is_live not reported as Live")
+	}
+	if radio.Channel != "Station" {
+		t.Errorf("Channel = %q, want uploader fallback %q", radio.Channel, "Station")
+	}
+
+	if _, err := parseYTDLPEntries([]byte("garbage\n")); err == nil {
+		t.Error("parseYTDLPEntries() with no valid entries returned nil error")
+	}
+}
+
+func TestSearchOptionsApply(t *testing.T) {
+	results := []SearchResult{
+		{ID: "loop", DurationSeconds: 36000, ViewCount: 10},
+		{ID: "song", DurationSeconds: 240, ViewCount: 500},
+		{ID: "live", Live: true, ViewCount: 900},
+		{ID: "short", DurationSeconds: 30, ViewCount: 50},
+	}
+
+	opts := SearchOptions{MaxResults: 10, MinDuration: 60, MaxDuration: 600}
+	got := opts.apply(append([]SearchResult(nil), results...))
+	if len(got) != 1 || got[0].ID != "song" {
+		t.Errorf("duration filter = %+v, want only song", got)
+	}
+
+	opts = SearchOptions{MaxResults: 2, ExcludeLive: true, Sort: SortViews}
+	got = opts.apply(append([]SearchResult(nil), results...))
+	if len(got) != 2 || got[0].ID != "song" || got[1].ID != "short" {
+		t.Errorf("views sort without live = %+v, want song, short", got)
+	}
+}
+
+func TestSearchInvalidSort(t *testing.T) {
+	lab := New(nil)
+	_, err := lab.Search(context.Background(), "youtube", "query", SearchOptions{Sort: "random"})
+	if !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("Search() error = %v, want ErrInvalidArgument", err)
+	}
+}
+
+func TestLastResults(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.CacheTTL = 0
+	cfg.LastSearchFile = filepath.Join(t.TempDir(), "last-search.json")
+	lab := New(cfg)
+	lab.RegisterSearchProvider(&countingProvider{})
+
+	if _, err := lab.ResultAt(1); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("ResultAt() before any search: error = %v, want ErrInvalidArgument", err)
+	}
+
+	lab.Search(context.Background(), "counting", "ambient", SearchOptions{MaxResults: 3, Remember: true})
+	if got := len(lab.LastResults()); got != 3 {
+		t.Fatalf("LastResults() = %d results, want 3", got)
+	}
+	if _, err := lab.ResultAt(4); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("ResultAt(4) error = %v, want ErrInvalidArgument", err)
+	}
+
+	// Lookups that only resolve a query keep the user's results
+	lab.Search(context.Background(), "counting", "drone", SearchOptions{MaxResults: 5})
+	lab.config.YTDLPBinary = writeScript(t, t.TempDir(), "yt-dlp", `echo '{"id": "dQw4w9WgXcQ", "title": "internal"}'`+"\n")
+	if _, err := lab.ResolveQuery(context.Background(), "some song"); err != nil {
+		t.Fatalf("ResolveQuery() error = %v", err)
+	}
+	if r, _ := lab.ResultAt(1); len(lab.LastResults()) != 3 || r == nil || r.Title != "ambient" {
+		t.Errorf("LastResults() after internal searches = %+v, want the 3 ambient results", lab.LastResults())
+	}
+
+	// A new MediaLab (e.g. the next CLI invocation) picks up the same results
+	r, err := New(cfg).ResultAt(2)
+	if err != nil || r.Title != "ambient" {
+		t.Errorf("ResultAt(2) after reload = %+v, %v", r, err)
+	}
+}
+
+func TestPlayVideoIDInvalid(t *testing.T) {
+	lab := New(nil)
+	for _, id := range []string{"", "short", "dQw4w9WgXcQ&list=x", "../../etc/passwd"} {
+		if _, err := lab.PlayVideoID(context.Background(), id, Screen1); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("PlayVideoID(%q) error = %v, want ErrInvalidArgument", id, err)
+		}
+	}
+}
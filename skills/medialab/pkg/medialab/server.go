@@ -3,57 +3,186 @@ package medialab
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
+	"net"
 	"net/http"
+	"os"
 	"strconv"
+	"sync"
+	"syscall"
 	"time"
 )
 
 // Server provides HTTP API for media control
 type Server struct {
-	lab    *MediaLab
-	mux    *http.ServeMux
-	server *http.Server
+	lab  *MediaLab
+	mux  *http.ServeMux
+	auth []Authenticator
+
+	mu      sync.Mutex
+	servers []*http.Server // one per Start/StartTLS/StartUnix call
 }
 
 // NewServer creates a new HTTP server for the media lab
 func NewServer(lab *MediaLab) *Server {
 	s := &Server{
-		lab: lab,
-		mux: http.NewServeMux(),
+		lab:  lab,
+		mux:  http.NewServeMux(),
+		auth: authenticatorsFor(lab.config.Auth),
 	}
 	s.registerRoutes()
 	return s
 }
 
 func (s *Server) registerRoutes() {
-	s.mux.HandleFunc("/play", s.handlePlay)
-	s.mux.HandleFunc("/control", s.handleControl)
-	s.mux.HandleFunc("/volume", s.handleVolume)
-	s.mux.HandleFunc("/seek", s.handleSeek)
-	s.mux.HandleFunc("/info", s.handleInfo)
-	s.mux.HandleFunc("/search", s.handleSearch)
-	s.mux.HandleFunc("/list", s.handleList)
-	s.mux.HandleFunc("/health", s.handleHealth)
+	s.registerV1()
+
+	healthScope := ScopeRead
+	if s.lab.config.Auth.PublicHealth {
+		healthScope = ""
+	}
+	metricsScope := ScopeRead
+	if s.lab.config.Auth.PublicMetrics {
+		metricsScope = ""
+	}
+	s.handle("/metrics", metricsScope, s.handleMetrics)
+	s.handle("/ready", healthScope, s.handleReady)
+
+	// Deprecated unversioned routes, kept as aliases of their /v1 successors
+	s.handle("/play", ScopePlay, deprecated("/v1/screens/{id}/play", s.handlePlay))
+	s.handle("/control", ScopeControl, deprecated("/v1/screens/{id}/control", s.handleControl))
+	s.handle("/volume", ScopeControl, deprecated("/v1/screens/{id}", s.handleVolume))
+	s.handle("/seek", ScopeControl, deprecated("/v1/screens/{id}/seek", s.handleSeek))
+	s.handle("/info", ScopeRead, deprecated("/v1/screens/{id}", s.handleInfo))
+	s.handle("/search", ScopeRead, deprecated("/v1/search", s.handleSearch))
+	s.handle("/list", ScopeRead, deprecated("/v1/screens", s.handleList))
+	s.handle("/playlist", ScopeRead, deprecated("/v1/playlist", s.handlePlaylist))
+	s.handle("/formats", ScopeRead, deprecated("/v1/formats", s.handleFormats))
+	s.handle("/stations", ScopeRead, deprecated("/v1/stations", s.handleStations))
+	s.handle("/downloads", ScopeRead, deprecated("/v1/downloads", s.handleDownloads)) // POST also needs ScopeControl
+	s.handle("/downloads/cancel", ScopeControl, deprecated("/v1/downloads/{id}", s.handleDownloadCancel))
+	s.handle("/library", ScopeRead, deprecated("/v1/library", s.handleLibraryStats))
+	s.handle("/library/search", ScopeRead, deprecated("/v1/library/search", s.handleLibrarySearch))
+	s.handle("/library/scan", ScopeControl, deprecated("/v1/library/scan", s.handleLibraryScan))
+	s.handle("/health", healthScope, deprecated("/v1/health", s.handleHealth))
+}
+
+// deprecated marks responses of an unversioned route with its successor
+func deprecated(successor string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		h(w, r)
+	}
 }
 
 // Start starts the HTTP server
 func (s *Server) Start(addr string) error {
-	s.server = &http.Server{
+	return s.newHTTPServer(addr).ListenAndServe()
+}
+
+// StartTLS starts the HTTPS server. With empty certFile and keyFile it
+// uses a self-signed certificate from Config.TLSDir, generated on first use
+// (see SelfSignedCert). Client certificates are verified for MTLSAuth when
+// Config.Auth.ClientCAFile is set.
+func (s *Server) StartTLS(addr, certFile, keyFile string) error {
+	if certFile == "" && keyFile == "" {
+		var err error
+		if certFile, keyFile, err = SelfSignedCert(s.lab.config.TLSDir); err != nil {
+			return err
+		}
+	}
+	tlsConfig, err := s.ClientTLSConfig()
+	if err != nil {
+		return err
+	}
+	srv := s.newHTTPServer(addr)
+	srv.TLSConfig = tlsConfig
+	return srv.ListenAndServeTLS(certFile, keyFile)
+}
+
+// StartUnix serves the API on a Unix domain socket at path with file mode
+// perm (0 = 0600), so local clients need no open port. The socket's
+// permissions are its access control: its requests skip Config.Auth. A
+// stale socket left by a previous run is replaced.
+func (s *Server) StartUnix(path string, perm os.FileMode) error {
+	if perm == 0 {
+		perm = 0o600
+	}
+	if err := removeStaleSocket(path); err != nil {
+		return err
+	}
+
+	// Create the socket without group/other access, then widen it to perm,
+	// so it is never reachable with looser permissions than asked for
+	oldMask := syscall.Umask(0o077)
+	ln, err := net.Listen("unix", path)
+	syscall.Umask(oldMask)
+	if err != nil {
+		return err
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		ln.Close()
+		return err
+	}
+
+	srv := s.newHTTPServer("")
+	srv.ConnContext = func(ctx context.Context, _ net.Conn) context.Context {
+		return context.WithValue(ctx, unixConnKey{}, true)
+	}
+	return srv.Serve(ln)
+}
+
+// removeStaleSocket removes a socket file nothing listens on any more;
+// a live socket or any other file at path is an error
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return fmt.Errorf("%s: another server is listening", path)
+	}
+	return os.Remove(path)
+}
+
+// unixConnKey marks requests that arrived over StartUnix's socket
+type unixConnKey struct{}
+
+func (s *Server) newHTTPServer(addr string) *http.Server {
+	srv := &http.Server{
 		Addr:         addr,
 		Handler:      s.mux,
 		ReadTimeout:  10 * time.Second,
 		WriteTimeout: 30 * time.Second,
 	}
-	return s.server.ListenAndServe()
+	s.mu.Lock()
+	s.servers = append(s.servers, srv)
+	s.mu.Unlock()
+	return srv
 }
 
-// Shutdown gracefully shuts down the server
+// Shutdown gracefully shuts down every listener started on the server
 func (s *Server) Shutdown(ctx context.Context) error {
-	if s.server != nil {
-		return s.server.Shutdown(ctx)
+	s.mu.Lock()
+	servers := s.servers
+	s.servers = nil
+	s.mu.Unlock()
+
+	var errs []error
+	for _, srv := range servers {
+		errs = append(errs, srv.Shutdown(ctx))
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 // Handler returns the HTTP handler for embedding in other servers
@@ -71,6 +200,51 @@ func (s *Server) writeError(w http.ResponseWriter, code int, msg string) {
 	w.WriteHeader(code)
 	json.NewEncoder(w).Encode(map[string]any{
 		"error":   msg,
+		"code":    statusCode(code),
+		"success": false,
+	})
+}
+
+// statusCode is the error envelope code for errors raised by the HTTP
+// layer itself rather than MediaLab, matching errorCode's vocabulary
+func statusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_argument"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusMethodNotAllowed:
+		return "method_not_allowed"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "forbidden"
+	}
+	return "error"
+}
+
+// writeLabError maps MediaLab errors to HTTP status codes
+func (s *Server) writeLabError(w http.ResponseWriter, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNoPlayer), errors.Is(err, ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrIPCTimeout):
+		code = http.StatusGatewayTimeout
+	case errors.Is(err, ErrPropertyUnavailable), errors.Is(err, ErrConflict):
+		code = http.StatusConflict
+	case errors.Is(err, ErrUnknownProvider), errors.Is(err, ErrInvalidArgument):
+		code = http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		code = http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		code = http.StatusForbidden
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]any{
+		"error":   err.Error(),
+		"code":    errorCode(err),
 		"success": false,
 	})
 }
@@ -80,11 +254,27 @@ func (s *Server) parseScreen(r *http.Request) Screen {
 	if screenStr == "" {
 		screenStr = r.FormValue("screen")
 	}
-	n, err := strconv.Atoi(screenStr)
-	if err != nil || n < 1 || n > 4 {
+	screen, err := ParseScreen(screenStr)
+	if err != nil {
 		return Screen1
 	}
-	return Screen(n - 1)
+	return screen
+}
+
+// playRequest is the body of POST /v1/screens/{id}/play (and POST /play,
+// which adds the screen)
+type playRequest struct {
+	URL         string `json:"url"`
+	Query       string `json:"query"`
+	ResultIndex int    `json:"result_index"`
+	VideoID     string `json:"video_id"`
+	Station     string `json:"station"`
+	Playlist    bool   `json:"playlist"`
+	Start       int    `json:"start"`
+	Limit       int    `json:"limit"`
+	AllEntries  bool   `json:"all_entries"`
+	Shuffle     bool   `json:"shuffle"`
+	Quality     string `json:"quality"`
 }
 
 func (s *Server) handlePlay(w http.ResponseWriter, r *http.Request) {
@@ -94,9 +284,8 @@ func (s *Server) handlePlay(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		URL    string `json:"url"`
-		Query  string `json:"query"`
-		Screen int    `json:"screen"`
+		playRequest
+		Screen screenArg `json:"screen"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -104,37 +293,92 @@ func (s *Server) handlePlay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	screen := Screen(req.Screen - 1)
-	if screen < Screen1 || screen > Screen4 {
-		screen = Screen1
+	screen := Screen(req.Screen)
+	if !s.authorizeScreen(w, r, screen) {
+		return
 	}
+	s.play(w, r, screen, req.playRequest)
+}
 
+// play resolves and starts a play request on screen
+func (s *Server) play(w http.ResponseWriter, r *http.Request, screen Screen, req playRequest) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	var instance *PlayerInstance
+	var opts PlayOptions
+	if req.Quality != "" {
+		q, err := ParseQuality(req.Quality)
+		if err != nil {
+			s.writeLabError(w, err)
+			return
+		}
+		opts.Quality = &q
+	}
+
+	url := req.URL
+	var result *SearchResult
 	var err error
 
-	if req.URL != "" {
-		instance, err = s.lab.Play(ctx, req.URL, screen)
-	} else if req.Query != "" {
-		instance, err = s.lab.PlayYouTubeSearch(ctx, req.Query, screen)
-	} else {
-		s.writeError(w, http.StatusBadRequest, "url or query required")
+	switch {
+	case url != "":
+	case req.VideoID != "":
+		url, err = VideoURL(req.VideoID)
+	case req.Station != "":
+		var st Station
+		if st, err = s.lab.Station(req.Station); err == nil {
+			url = st.URL
+		}
+	case req.ResultIndex > 0:
+		if result, err = s.lab.ResultAt(req.ResultIndex); err == nil {
+			url = result.URL
+		}
+	case req.Query != "":
+		url, err = s.lab.ResolveQuery(ctx, req.Query)
+	default:
+		s.writeError(w, http.StatusBadRequest, "url, query, result_index, video_id or station required")
 		return
 	}
 
+	var instance *PlayerInstance
+	var playlist *Playlist
+	if err == nil {
+		if req.Playlist || IsPlaylistURL(url) {
+			plOpts := PlaylistOptions{Start: req.Start, Limit: req.Limit, All: req.AllEntries, Shuffle: req.Shuffle, Quality: opts.Quality}
+			instance, playlist, err = s.lab.PlayPlaylist(ctx, url, plOpts, screen)
+		} else {
+			instance, err = s.lab.PlayWith(ctx, url, screen, opts)
+		}
+	}
+
 	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+		s.writeLabError(w, err)
 		return
 	}
 
-	s.writeJSON(w, map[string]any{
+	resp := map[string]any{
 		"success": true,
-		"screen":  int(screen) + 1,
+		"screen":  screenID(screen),
 		"pid":     instance.PID,
 		"url":     instance.URL,
-	})
+	}
+	if result != nil {
+		resp["title"] = result.Title
+	}
+	if playlist != nil {
+		resp["playlist"] = playlist
+	}
+	s.writeJSON(w, resp)
+}
+
+// controlRequest is the body of POST /v1/screens/{id}/control (and
+// POST /control, which adds the screen)
+type controlRequest struct {
+	Action string  `json:"action"`
+	Count  *int    `json:"count"` // omitted or -1 = infinite, 0 = off
+	A      float64 `json:"a"`
+	B      float64 `json:"b"`
+	Speed  float64 `json:"speed"`
+	Pitch  *bool   `json:"pitch_correction"`
 }
 
 func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
@@ -144,8 +388,8 @@ func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Action string `json:"action"`
-		Screen int    `json:"screen"`
+		controlRequest
+		Screen screenArg `json:"screen"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -153,11 +397,15 @@ func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	screen := Screen(req.Screen - 1)
-	if screen < Screen1 || screen > Screen4 {
-		screen = Screen1
+	screen := Screen(req.Screen)
+	if !s.authorizeScreen(w, r, screen) {
+		return
 	}
+	s.control(w, screen, req.controlRequest)
+}
 
+// control runs a playback control action on screen
+func (s *Server) control(w http.ResponseWriter, screen Screen, req controlRequest) {
 	var err error
 	switch req.Action {
 	case "playpause", "toggle":
@@ -174,20 +422,34 @@ func (s *Server) handleControl(w http.ResponseWriter, r *http.Request) {
 		err = s.lab.Prev(screen)
 	case "fullscreen", "fs":
 		err = s.lab.Fullscreen(screen)
+	case "loop-file", "loop":
+		err = s.lab.SetLoopFile(screen, loopCount(req.Count))
+	case "loop-playlist":
+		err = s.lab.SetLoopPlaylist(screen, loopCount(req.Count))
+	case "ab-loop":
+		err = s.lab.SetABLoop(screen, req.A, req.B)
+	case "loop-off", "unloop":
+		err = s.lab.ClearLoop(screen)
+	case "speed":
+		err = s.lab.SetSpeed(screen, req.Speed, req.Pitch == nil || *req.Pitch)
+	case "frame-step", "step":
+		err = s.lab.FrameStep(screen)
+	case "frame-back-step", "back-step":
+		err = s.lab.FrameBackStep(screen)
 	default:
 		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown action: %s", req.Action))
 		return
 	}
 
 	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+		s.writeLabError(w, err)
 		return
 	}
 
 	s.writeJSON(w, map[string]any{
 		"success": true,
 		"action":  req.Action,
-		"screen":  int(screen) + 1,
+		"screen":  screenID(screen),
 	})
 }
 
@@ -198,8 +460,8 @@ func (s *Server) handleVolume(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Volume int `json:"volume"`
-		Screen int `json:"screen"`
+		Volume int       `json:"volume"`
+		Screen screenArg `json:"screen"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -207,20 +469,20 @@ func (s *Server) handleVolume(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	screen := Screen(req.Screen - 1)
-	if screen < Screen1 || screen > Screen4 {
-		screen = Screen1
+	screen := Screen(req.Screen)
+	if !s.authorizeScreen(w, r, screen) {
+		return
 	}
 
 	if err := s.lab.SetVolume(screen, req.Volume); err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+		s.writeLabError(w, err)
 		return
 	}
 
 	s.writeJSON(w, map[string]any{
 		"success": true,
 		"volume":  req.Volume,
-		"screen":  int(screen) + 1,
+		"screen":  screenID(screen),
 	})
 }
 
@@ -231,9 +493,8 @@ func (s *Server) handleSeek(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Position float64 `json:"position"`
-		Relative bool    `json:"relative"`
-		Screen   int     `json:"screen"`
+		seekRequest
+		Screen screenArg `json:"screen"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -241,13 +502,23 @@ func (s *Server) handleSeek(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	screen := Screen(req.Screen - 1)
-	if screen < Screen1 || screen > Screen4 {
-		screen = Screen1
+	screen := Screen(req.Screen)
+	if !s.authorizeScreen(w, r, screen) {
+		return
 	}
+	s.seek(w, screen, req.seekRequest)
+}
 
+// seekRequest is the body of POST /v1/screens/{id}/seek (and POST /seek,
+// which adds the screen)
+type seekRequest struct {
+	Position float64 `json:"position"`
+	Relative bool    `json:"relative"`
+}
+
+func (s *Server) seek(w http.ResponseWriter, screen Screen, req seekRequest) {
 	if err := s.lab.Seek(screen, req.Position, req.Relative); err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+		s.writeLabError(w, err)
 		return
 	}
 
@@ -255,32 +526,28 @@ func (s *Server) handleSeek(w http.ResponseWriter, r *http.Request) {
 		"success":  true,
 		"position": req.Position,
 		"relative": req.Relative,
-		"screen":   int(screen) + 1,
+		"screen":   screenID(screen),
 	})
 }
 
 func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
 	screen := s.parseScreen(r)
+	if !s.authorizeScreen(w, r, screen) {
+		return
+	}
+	s.info(w, screen)
+}
 
+func (s *Server) info(w http.ResponseWriter, screen Screen) {
 	info, err := s.lab.GetPlaybackInfo(screen)
 	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+		s.writeLabError(w, err)
 		return
 	}
 
-	s.writeJSON(w, map[string]any{
-		"success":     true,
-		"screen":      int(info.Screen) + 1,
-		"playing":     info.Playing,
-		"paused":      info.Paused,
-		"position":    info.Position,
-		"duration":    info.Duration,
-		"volume":      info.Volume,
-		"filename":    info.Filename,
-		"media_title": info.MediaTitle,
-		"fullscreen":  info.Fullscreen,
-		"percent":     info.PercentPos,
-	})
+	out := playbackInfoMap(info)
+	out["success"] = true
+	s.writeJSON(w, out)
 }
 
 func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
@@ -298,27 +565,60 @@ func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
 	defer cancel()
 
-	results, err := s.lab.SearchYouTube(ctx, query, maxResults)
+	provider := r.URL.Query().Get("provider")
+	if provider == "" {
+		provider = DefaultSearchProvider
+	}
+
+	opts := SearchOptions{
+		MaxResults:  maxResults,
+		ExcludeLive: r.URL.Query().Get("exclude_live") == "true",
+		Sort:        r.URL.Query().Get("sort"),
+		Full:        r.URL.Query().Get("full") == "true",
+		Remember:    true,
+	}
+	for _, param := range []struct {
+		name string
+		dst  *float64
+	}{{"min_duration", &opts.MinDuration}, {"max_duration", &opts.MaxDuration}} {
+		value := r.URL.Query().Get(param.name)
+		if value == "" {
+			continue
+		}
+		seconds, err := strconv.ParseFloat(value, 64)
+		if err != nil || seconds < 0 || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			s.writeLabError(w, fmt.Errorf("%w: %s must be a number of seconds: %q", ErrInvalidArgument, param.name, value))
+			return
+		}
+		*param.dst = seconds
+	}
+
+	results, err := s.lab.Search(ctx, provider, query, opts)
 	if err != nil {
-		s.writeError(w, http.StatusInternalServerError, err.Error())
+		s.writeLabError(w, err)
 		return
 	}
 
 	s.writeJSON(w, map[string]any{
-		"success": true,
-		"query":   query,
-		"count":   len(results),
-		"results": results,
+		"success":  true,
+		"query":    query,
+		"provider": provider,
+		"count":    len(results),
+		"results":  results,
 	})
 }
 
 func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
 	players := s.lab.ListPlayers()
 
+	principal, restricted := PrincipalFromContext(r.Context())
 	list := make([]map[string]any, 0, len(players))
 	for _, p := range players {
+		if restricted && !principal.AllowsScreen(p.Screen) {
+			continue
+		}
 		list = append(list, map[string]any{
-			"screen":     int(p.Screen) + 1,
+			"screen":     screenID(p.Screen),
 			"pid":        p.PID,
 			"url":        p.URL,
 			"socket":     p.Socket,
@@ -333,9 +633,213 @@ func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) handleLibraryStats(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, map[string]any{
+		"success": true,
+		"stats":   s.lab.Library().Stats(),
+	})
+}
+
+func (s *Server) handlePlaylist(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	url := q.Get("url")
+	if url == "" {
+		s.writeError(w, http.StatusBadRequest, "query parameter 'url' required")
+		return
+	}
+
+	opts := PlaylistOptions{Shuffle: q.Get("shuffle") == "true", All: q.Get("all_entries") == "true"}
+	opts.Start, _ = strconv.Atoi(q.Get("start"))
+	opts.Limit, _ = strconv.Atoi(q.Get("limit"))
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	playlist, err := s.lab.ExpandPlaylist(ctx, url, opts)
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{
+		"success":  true,
+		"playlist": playlist,
+	})
+}
+
+func (s *Server) handleFormats(w http.ResponseWriter, r *http.Request) {
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		s.writeError(w, http.StatusBadRequest, "query parameter 'url' required")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	formats, err := s.lab.ListFormats(ctx, url)
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{
+		"success": true,
+		"url":     url,
+		"count":   len(formats),
+		"formats": formats,
+	})
+}
+
+func (s *Server) handleStations(w http.ResponseWriter, r *http.Request) {
+	stations := s.lab.Stations()
+	s.writeJSON(w, map[string]any{
+		"success":  true,
+		"count":    len(stations),
+		"stations": stations,
+	})
+}
+
+// handleDownloads lists downloads and the offline cache (GET), reports one
+// download (GET ?id=) or starts a download (POST)
+func (s *Server) handleDownloads(w http.ResponseWriter, r *http.Request) {
+	downloads := s.lab.Downloads()
+
+	switch r.Method {
+	case http.MethodGet:
+		if id := r.URL.Query().Get("id"); id != "" {
+			job, err := downloads.Job(id)
+			if err != nil {
+				s.writeLabError(w, err)
+				return
+			}
+			s.writeJSON(w, map[string]any{"success": true, "download": job})
+			return
+		}
+		s.writeJSON(w, map[string]any{
+			"success": true,
+			"jobs":    downloads.Jobs(),
+			"cached":  downloads.Cached(),
+			"stats":   downloads.Stats(),
+		})
+
+	case http.MethodPost:
+		if !s.requireScope(w, r, ScopeControl) {
+			return
+		}
+		var req struct {
+			URL     string `json:"url"`
+			Quality string `json:"quality"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+			return
+		}
+		var quality *Quality
+		if req.Quality != "" {
+			q, err := ParseQuality(req.Quality)
+			if err != nil {
+				s.writeLabError(w, err)
+				return
+			}
+			quality = &q
+		}
+		job, err := downloads.Start(req.URL, quality)
+		if err != nil {
+			s.writeLabError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]any{"success": true, "download": job})
+
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "GET or POST required")
+	}
+}
+
+func (s *Server) handleDownloadCancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "POST required")
+		return
+	}
+
+	var req struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return
+	}
+	if err := s.lab.Downloads().Cancel(req.ID); err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "id": req.ID})
+}
+
+func (s *Server) handleLibrarySearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		s.writeError(w, http.StatusBadRequest, "query parameter 'q' required")
+		return
+	}
+
+	maxResults := 20
+	if n, err := strconv.Atoi(r.URL.Query().Get("max")); err == nil && n > 0 && n <= 100 {
+		maxResults = n
+	}
+
+	matches := s.lab.Library().Search(query, maxResults)
+	s.writeJSON(w, map[string]any{
+		"success": true,
+		"query":   query,
+		"count":   len(matches),
+		"results": matches,
+	})
+}
+
+func (s *Server) handleLibraryScan(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.writeError(w, http.StatusMethodNotAllowed, "POST required")
+		return
+	}
+
+	// Scans can outlast the write timeout; run in the background and let
+	// clients poll GET /library for last_scan
+	go s.lab.Library().Scan(context.Background())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": true,
+		"status":  "scanning",
+	})
+}
+
+// handleHealth reports every health check. It answers 200 even when
+// checks fail; handleReady is the probe for orchestrators.
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	report := s.lab.CheckHealth(r.Context())
 	s.writeJSON(w, map[string]any{
-		"status": "ok",
-		"time":   time.Now().Format(time.RFC3339),
+		"status": report.Status,
+		"ready":  report.Ready,
+		"time":   report.Time.Format(time.RFC3339),
+		"checks": report.Checks,
+		"cache":  s.lab.Cache().Stats(),
+	})
+}
+
+// handleReady answers 503 while a health check fails
+func (s *Server) handleReady(w http.ResponseWriter, r *http.Request) {
+	report := s.lab.CheckHealth(r.Context())
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]any{
+		"success": report.Ready,
+		"status":  report.Status,
+		"ready":   report.Ready,
+		"time":    report.Time.Format(time.RFC3339),
+		"checks":  report.Checks,
 	})
 }
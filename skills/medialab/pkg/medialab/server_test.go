@@ -0,0 +1,78 @@
+package medialab
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSelfSignedCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, err := SelfSignedCert(dir)
+	if err != nil {
+		t.Fatalf("SelfSignedCert() error = %v", err)
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("generated pair does not load: %v", err)
+	}
+	if info, _ := os.Stat(keyFile); info.Mode().Perm() != 0o600 {
+		t.Errorf("key mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	// A valid certificate is reused, not regenerated
+	SelfSignedCert(dir)
+	again, _ := tls.LoadX509KeyPair(certFile, keyFile)
+	if string(again.Certificate[0]) != string(pair.Certificate[0]) {
+		t.Error("valid certificate was regenerated")
+	}
+}
+
+func TestStartUnix(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Auth = AuthConfig{Clients: []APIClient{{Name: "remote", Token: "secret"}}}
+	s := NewServer(New(cfg))
+	socket := filepath.Join(t.TempDir(), "medialab.sock")
+
+	done := make(chan error, 1)
+	go func() { done <- s.StartUnix(socket, 0) }()
+	t.Cleanup(func() {
+		s.Shutdown(context.Background())
+		<-done
+	})
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	var resp *http.Response
+	var err error
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if resp, err = client.Get("http://medialab/stations"); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		t.Fatalf("GET over unix socket: %v", err)
+	}
+	resp.Body.Close()
+
+	// The socket's permissions stand in for credentials
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200 without a token", resp.StatusCode)
+	}
+	if info, err := os.Stat(socket); err != nil {
+		t.Error(err)
+	} else if info.Mode().Perm() != 0o600 {
+		t.Errorf("socket mode = %v, want 0600", info.Mode().Perm())
+	}
+	if err := s.StartUnix(socket, 0); err == nil {
+		t.Error("second StartUnix on a live socket succeeded")
+	}
+}
@@ -0,0 +1,1000 @@
+package medialab
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiRoute is one operation of the v1 API. The route table drives both
+// the ServeMux registration and the OpenAPI document.
+type apiRoute struct {
+	method  string
+	path    string // ServeMux path, {id} wildcards allowed
+	id      string // OpenAPI operationId
+	scope   Scope  // "" = public
+	summary string
+	query   []apiParam
+	body    string // JSON schema of the request body, "" = none
+	status  int    // success status, 0 = 200
+	handler http.HandlerFunc
+}
+
+// apiParam is a query parameter of an apiRoute
+type apiParam struct {
+	name        string
+	typ         string // JSON schema type
+	description string
+	required    bool
+}
+
+func (s *Server) v1Routes() []apiRoute {
+	healthScope := ScopeRead
+	if s.lab.config.Auth.PublicHealth {
+		healthScope = ""
+	}
+
+	return []apiRoute{
+		{method: "GET", path: "/v1/screens", id: "listScreens", scope: ScopeRead,
+			summary: "List the screens and the speaker with their players", handler: s.v1ListScreens},
+		{method: "GET", path: "/v1/screens/{id}", id: "getScreen", scope: ScopeRead,
+			summary: "Playback state of a screen", handler: s.v1GetScreen},
+		{method: "PATCH", path: "/v1/screens/{id}", id: "updateScreen", scope: ScopeControl,
+			summary: "Change volume, pause or speed; responds with the new state",
+			body:    screenPatchSchema, handler: s.v1PatchScreen},
+		{method: "DELETE", path: "/v1/screens/{id}", id: "stopScreen", scope: ScopeControl,
+			summary: "Stop the screen's player", handler: s.v1StopScreen},
+		{method: "POST", path: "/v1/screens/{id}/play", id: "play", scope: ScopePlay,
+			summary: "Replace what the screen plays", body: playSchema, handler: s.v1Play},
+		{method: "POST", path: "/v1/screens/{id}/queue", id: "enqueue", scope: ScopePlay,
+			summary: "Append to the screen's playlist, starting a player if needed", body: queueSchema, handler: s.v1Queue},
+		{method: "POST", path: "/v1/screens/{id}/seek", id: "seek", scope: ScopeControl,
+			summary: "Seek to an absolute or relative position", body: seekSchema, handler: s.v1Seek},
+		{method: "POST", path: "/v1/screens/{id}/control", id: "control", scope: ScopeControl,
+			summary: "Playback control action (next, prev, loop, speed, frame-step...)", body: controlSchema, handler: s.v1Control},
+
+		{method: "POST", path: "/v1/batch", id: "runBatch", scope: ScopeControl,
+			summary: "Run an ordered list of operations; play and queue steps also need the play scope",
+			body:    batchSchema, handler: s.v1Batch},
+		{method: "GET", path: "/v1/macros", id: "listMacros", scope: ScopeRead,
+			summary: "Named macros from the config", handler: s.v1ListMacros},
+		{method: "POST", path: "/v1/macros/{id}", id: "runMacro", scope: ScopeControl,
+			summary: "Run a named macro as a batch",
+			body:    `{"type": "object", "properties": {"parallel": {"type": "boolean"}, "stop_on_error": {"type": "boolean"}}}`,
+			handler: s.v1RunMacro},
+
+		{method: "GET", path: "/v1/scenes", id: "listScenes", scope: ScopeRead,
+			summary: "Saved scenes", handler: s.v1ListScenes},
+		{method: "POST", path: "/v1/scenes", id: "saveScene", scope: ScopeControl,
+			summary: "Save the state of every screen as a scene",
+			body:    `{"type": "object", "properties": {"name": {"type": "string"}}, "required": ["name"]}`,
+			status:  http.StatusCreated, handler: s.v1SaveScene},
+		{method: "GET", path: "/v1/scenes/{id}", id: "getScene", scope: ScopeRead,
+			summary: "One saved scene", handler: s.v1GetScene},
+		{method: "DELETE", path: "/v1/scenes/{id}", id: "deleteScene", scope: ScopeControl,
+			summary: "Delete a scene", handler: s.v1DeleteScene},
+		{method: "POST", path: "/v1/scenes/{id}/load", id: "loadScene", scope: ScopePlay,
+			summary: "Restore a scene, stopping screens it does not include", handler: s.v1LoadScene},
+
+		{method: "GET", path: "/v1/schedule", id: "listSchedule", scope: ScopeRead,
+			summary: "Schedule entries with their next runs and any conflicts", handler: s.v1ListSchedule},
+		{method: "POST", path: "/v1/schedule", id: "addScheduleEntry", scope: ScopeControl,
+			summary: "Add a schedule entry; overlapping entries are rejected with 409",
+			body:    scheduleEntrySchema, status: http.StatusCreated, handler: s.v1AddScheduleEntry},
+		{method: "GET", path: "/v1/schedule/{id}", id: "getScheduleEntry", scope: ScopeRead,
+			summary: "One schedule entry", handler: s.v1GetScheduleEntry},
+		{method: "PUT", path: "/v1/schedule/{id}", id: "updateScheduleEntry", scope: ScopeControl,
+			summary: "Replace a schedule entry", body: scheduleEntrySchema, handler: s.v1UpdateScheduleEntry},
+		{method: "DELETE", path: "/v1/schedule/{id}", id: "deleteScheduleEntry", scope: ScopeControl,
+			summary: "Delete a schedule entry", handler: s.v1DeleteScheduleEntry},
+
+		{method: "GET", path: "/v1/timers", id: "listTimers", scope: ScopeRead,
+			summary: "Sleep timers and delayed actions, newest first", handler: s.v1ListTimers},
+		{method: "POST", path: "/v1/timers", id: "startTimer", scope: ScopeControl,
+			summary: "Stop or pause a screen after a delay or when its entry ends, optionally fading out",
+			body:    timerSchema, status: http.StatusCreated, handler: s.v1StartTimer},
+		{method: "GET", path: "/v1/timers/{id}", id: "getTimer", scope: ScopeRead,
+			summary: "One timer", handler: s.v1GetTimer},
+		{method: "DELETE", path: "/v1/timers/{id}", id: "cancelTimer", scope: ScopeControl,
+			summary: "Cancel a timer, restoring the volume if it was fading", handler: s.v1CancelTimer},
+
+		{method: "GET", path: "/v1/audit", id: "listAudit", scope: ScopeRead,
+			summary: "Audit log of media actions, oldest first",
+			query: []apiParam{
+				{name: "actor", typ: "string", description: "API client, agent, local user, schedule:ID or timer:ID"},
+				{name: "source", typ: "string", description: "http, skill, cli, scheduler or timer"},
+				{name: "screen", typ: "string", description: "1-4 or speaker"},
+				{name: "action", typ: "string", description: "Action: a v1 operation ID such as play, a skill action or a CLI command"},
+				{name: "since", typ: "string", description: "Entries after this RFC 3339 time"},
+				{name: "limit", typ: "integer", description: "Most recent entries (1-1000, default 100)"},
+			}, handler: s.v1ListAudit},
+
+		{method: "GET", path: "/v1/search", id: "search", scope: ScopeRead, summary: "Search a provider",
+			query: []apiParam{
+				{name: "q", typ: "string", description: "Search query", required: true},
+				{name: "max", typ: "integer", description: "Maximum results (1-20, default 10)"},
+				{name: "provider", typ: "string", description: "youtube, soundcloud, archive, local or a yt-dlp search prefix"},
+				{name: "min_duration", typ: "number", description: "Minimum duration in seconds"},
+				{name: "max_duration", typ: "number", description: "Maximum duration in seconds"},
+				{name: "exclude_live", typ: "boolean", description: "Skip live and upcoming streams"},
+				{name: "sort", typ: "string", description: "relevance, date, views or duration"},
+				{name: "full", typ: "boolean", description: "Fetch full metadata (slower)"},
+			}, handler: s.handleSearch},
+		{method: "GET", path: "/v1/stations", id: "listStations", scope: ScopeRead,
+			summary: "Configured radio stations", handler: s.handleStations},
+		{method: "GET", path: "/v1/playlist", id: "expandPlaylist", scope: ScopeRead,
+			summary: "Expand a playlist or channel without playing it",
+			query: []apiParam{
+				{name: "url", typ: "string", description: "Playlist or channel URL", required: true},
+				{name: "start", typ: "integer", description: "First entry (1-based)"},
+				{name: "limit", typ: "integer", description: "Maximum entries (default 200)"},
+				{name: "all_entries", typ: "boolean", description: "Every entry, ignoring limit"},
+				{name: "shuffle", typ: "boolean", description: "Shuffle entries"},
+			}, handler: s.handlePlaylist},
+		{method: "GET", path: "/v1/formats", id: "listFormats", scope: ScopeRead,
+			summary: "Formats yt-dlp offers for a URL",
+			query:   []apiParam{{name: "url", typ: "string", description: "Media URL", required: true}},
+			handler: s.handleFormats},
+
+		{method: "GET", path: "/v1/downloads", id: "listDownloads", scope: ScopeRead,
+			summary: "Download jobs, cached files and cache usage", handler: s.handleDownloads},
+		{method: "POST", path: "/v1/downloads", id: "startDownload", scope: ScopeControl,
+			summary: "Download media for offline playback", body: downloadSchema, status: http.StatusAccepted,
+			handler: s.handleDownloads},
+		{method: "GET", path: "/v1/downloads/{id}", id: "getDownload", scope: ScopeRead,
+			summary: "One download job", handler: s.v1GetDownload},
+		{method: "DELETE", path: "/v1/downloads/{id}", id: "cancelDownload", scope: ScopeControl,
+			summary: "Cancel a download job", handler: s.v1CancelDownload},
+
+		{method: "GET", path: "/v1/library", id: "libraryStats", scope: ScopeRead,
+			summary: "Local library index stats", handler: s.handleLibraryStats},
+		{method: "GET", path: "/v1/library/search", id: "searchLibrary", scope: ScopeRead,
+			summary: "Fuzzy search the local library",
+			query: []apiParam{
+				{name: "q", typ: "string", description: "Search query", required: true},
+				{name: "max", typ: "integer", description: "Maximum results (1-100, default 20)"},
+			}, handler: s.handleLibrarySearch},
+		{method: "POST", path: "/v1/library/scan", id: "scanLibrary", scope: ScopeControl,
+			summary: "Start a background library rescan", status: http.StatusAccepted, handler: s.handleLibraryScan},
+
+		{method: "GET", path: "/v1/health", id: "health", scope: healthScope,
+			summary: "Health checks of binaries, sockets, display, profiles and audio, with search cache stats", handler: s.handleHealth},
+		{method: "GET", path: "/v1/ready", id: "ready", scope: healthScope,
+			summary: "Readiness probe: the health checks, answering 503 while one fails", handler: s.handleReady},
+		{method: "GET", path: "/v1/openapi.json", id: "openapi",
+			summary: "This OpenAPI document", handler: s.handleOpenAPI},
+	}
+}
+
+// registerV1 registers the v1 routes; methods a path does not support and
+// unknown /v1 paths get error envelopes rather than ServeMux's plain text
+func (s *Server) registerV1() {
+	byPath := make(map[string][]apiRoute)
+	var paths []string
+	for _, rt := range s.v1Routes() {
+		if _, ok := byPath[rt.path]; !ok {
+			paths = append(paths, rt.path)
+		}
+		byPath[rt.path] = append(byPath[rt.path], rt)
+	}
+	for _, path := range paths {
+		s.mux.HandleFunc(path, s.instrument(path, s.dispatch(byPath[path])))
+	}
+	s.mux.HandleFunc("/v1/", s.instrument("/v1/", func(w http.ResponseWriter, r *http.Request) {
+		s.writeError(w, http.StatusNotFound, "no such endpoint: "+r.URL.Path)
+	}))
+}
+
+// dispatch routes a request to the route for its method
+func (s *Server) dispatch(routes []apiRoute) http.HandlerFunc {
+	handlers := make(map[string]http.HandlerFunc, len(routes))
+	allow := make([]string, 0, len(routes))
+	for _, rt := range routes {
+		handlers[rt.method] = s.protect(rt.scope, s.audited(rt.path, rt.id, rt.handler))
+		allow = append(allow, rt.method)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		h, ok := handlers[r.Method]
+		if !ok {
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			s.writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s not allowed (allowed: %s)", r.Method, strings.Join(allow, ", ")))
+			return
+		}
+		h(w, r)
+	}
+}
+
+// screenParam parses the {id} path segment (1-4 or "speaker") and checks
+// the client's screen ACL, writing the error when either fails
+func (s *Server) screenParam(w http.ResponseWriter, r *http.Request) (Screen, bool) {
+	screen, err := ParseScreen(r.PathValue("id"))
+	if err != nil {
+		s.writeLabError(w, fmt.Errorf("%w: screen %q", ErrNotFound, r.PathValue("id")))
+		return 0, false
+	}
+	return screen, s.authorizeScreen(w, r, screen)
+}
+
+// decodeBody decodes a JSON request body into v, writing the error when
+// it is malformed
+func (s *Server) decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid JSON: %v", err))
+		return false
+	}
+	return true
+}
+
+func (s *Server) v1ListScreens(w http.ResponseWriter, r *http.Request) {
+	principal, restricted := PrincipalFromContext(r.Context())
+	screens := make([]map[string]any, 0, 5)
+	for screen := Screen1; screen <= ScreenSpeaker; screen++ {
+		if restricted && !principal.AllowsScreen(screen) {
+			continue
+		}
+		entry := map[string]any{
+			"id":     screenID(screen),
+			"name":   screen.String(),
+			"active": false,
+		}
+		if p, ok := s.lab.GetPlayer(screen); ok {
+			entry["active"] = true
+			entry["pid"] = p.PID
+			entry["url"] = p.URL
+			entry["started_at"] = p.StartedAt.Format(time.RFC3339)
+		}
+		screens = append(screens, entry)
+	}
+	s.writeJSON(w, map[string]any{
+		"success": true,
+		"count":   len(screens),
+		"screens": screens,
+	})
+}
+
+func (s *Server) v1GetScreen(w http.ResponseWriter, r *http.Request) {
+	if screen, ok := s.screenParam(w, r); ok {
+		s.info(w, screen)
+	}
+}
+
+const screenPatchSchema = `{
+	"type": "object",
+	"properties": {
+		"volume": {"type": "integer", "minimum": 0, "maximum": 100},
+		"paused": {"type": "boolean"},
+		"speed": {"type": "number", "minimum": 0.01, "maximum": 100},
+		"pitch_correction": {"type": "boolean", "default": true}
+	},
+	"minProperties": 1
+}`
+
+func (s *Server) v1PatchScreen(w http.ResponseWriter, r *http.Request) {
+	screen, ok := s.screenParam(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		Volume *int     `json:"volume"`
+		Paused *bool    `json:"paused"`
+		Speed  *float64 `json:"speed"`
+		Pitch  *bool    `json:"pitch_correction"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	if req.Volume == nil && req.Paused == nil && req.Speed == nil {
+		s.writeError(w, http.StatusBadRequest, "nothing to change: set volume, paused or speed")
+		return
+	}
+	if req.Volume != nil && (*req.Volume < 0 || *req.Volume > 100) {
+		s.writeError(w, http.StatusBadRequest, fmt.Sprintf("volume out of range (0-100): %d", *req.Volume))
+		return
+	}
+
+	var err error
+	if req.Volume != nil {
+		err = s.lab.SetVolume(screen, *req.Volume)
+	}
+	if err == nil && req.Speed != nil {
+		err = s.lab.SetSpeed(screen, *req.Speed, req.Pitch == nil || *req.Pitch)
+	}
+	if err == nil && req.Paused != nil {
+		if *req.Paused {
+			err = s.lab.Pause(screen)
+		} else {
+			err = s.lab.Resume(screen)
+		}
+	}
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.info(w, screen)
+}
+
+func (s *Server) v1StopScreen(w http.ResponseWriter, r *http.Request) {
+	screen, ok := s.screenParam(w, r)
+	if !ok {
+		return
+	}
+	if err := s.lab.Stop(screen); err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "screen": screenID(screen)})
+}
+
+const playSchema = `{
+	"type": "object",
+	"properties": {
+		"url": {"type": "string", "description": "URL or file path"},
+		"query": {"type": "string", "description": "Station name, local library match, else first YouTube result"},
+		"result_index": {"type": "integer", "minimum": 1, "description": "Nth result of the last search"},
+		"video_id": {"type": "string", "description": "YouTube video ID"},
+		"station": {"type": "string", "description": "Configured radio station name"},
+		"playlist": {"type": "boolean", "description": "Expand url as a playlist"},
+		"start": {"type": "integer", "minimum": 1},
+		"limit": {"type": "integer", "minimum": 0, "description": "Maximum playlist entries (default 200)"},
+		"all_entries": {"type": "boolean", "description": "Every playlist entry, ignoring limit"},
+		"shuffle": {"type": "boolean"},
+		"quality": {"type": "string", "description": "best, audio, 1080p, vp9, 1080p:av1 or a ytdl-format"}
+	}
+}`
+
+func (s *Server) v1Play(w http.ResponseWriter, r *http.Request) {
+	screen, ok := s.screenParam(w, r)
+	if !ok {
+		return
+	}
+	var req playRequest
+	if s.decodeBody(w, r, &req) {
+		s.play(w, r, screen, req)
+	}
+}
+
+const queueSchema = `{
+	"type": "object",
+	"properties": {
+		"url": {"type": "string"},
+		"urls": {"type": "array", "items": {"type": "string"}},
+		"query": {"type": "string", "description": "Resolved like play's query"},
+		"quality": {"type": "string", "description": "Used only when a player has to be started"}
+	}
+}`
+
+func (s *Server) v1Queue(w http.ResponseWriter, r *http.Request) {
+	screen, ok := s.screenParam(w, r)
+	if !ok {
+		return
+	}
+	var req struct {
+		URL     string   `json:"url"`
+		URLs    []string `json:"urls"`
+		Query   string   `json:"query"`
+		Quality string   `json:"quality"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 30*time.Second)
+	defer cancel()
+
+	urls := req.URLs
+	if req.URL != "" {
+		urls = append(urls, req.URL)
+	}
+	if req.Query != "" {
+		url, err := s.lab.ResolveQuery(ctx, req.Query)
+		if err != nil {
+			s.writeLabError(w, err)
+			return
+		}
+		urls = append(urls, url)
+	}
+
+	var opts PlayOptions
+	if req.Quality != "" {
+		q, err := ParseQuality(req.Quality)
+		if err != nil {
+			s.writeLabError(w, err)
+			return
+		}
+		opts.Quality = &q
+	}
+
+	instance, err := s.lab.Enqueue(ctx, screen, urls, opts)
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{
+		"success": true,
+		"screen":  screenID(screen),
+		"pid":     instance.PID,
+		"queued":  urls,
+	})
+}
+
+const seekSchema = `{
+	"type": "object",
+	"properties": {
+		"position": {"type": "number", "description": "Seconds"},
+		"relative": {"type": "boolean", "default": false}
+	},
+	"required": ["position"]
+}`
+
+func (s *Server) v1Seek(w http.ResponseWriter, r *http.Request) {
+	screen, ok := s.screenParam(w, r)
+	if !ok {
+		return
+	}
+	var req seekRequest
+	if s.decodeBody(w, r, &req) {
+		s.seek(w, screen, req)
+	}
+}
+
+const controlSchema = `{
+	"type": "object",
+	"properties": {
+		"action": {"type": "string", "enum": ["playpause", "pause", "play", "stop", "next", "prev", "fullscreen", "loop-file", "loop-playlist", "ab-loop", "loop-off", "speed", "frame-step", "frame-back-step"]},
+		"count": {"type": "integer", "minimum": -1, "description": "Loop count, -1 or omitted = infinite, 0 = off"},
+		"a": {"type": "number"},
+		"b": {"type": "number"},
+		"speed": {"type": "number"},
+		"pitch_correction": {"type": "boolean", "default": true}
+	},
+	"required": ["action"]
+}`
+
+func (s *Server) v1Control(w http.ResponseWriter, r *http.Request) {
+	screen, ok := s.screenParam(w, r)
+	if !ok {
+		return
+	}
+	var req controlRequest
+	if s.decodeBody(w, r, &req) {
+		s.control(w, screen, req)
+	}
+}
+
+const downloadSchema = `{
+	"type": "object",
+	"properties": {
+		"url": {"type": "string"},
+		"quality": {"type": "string"}
+	},
+	"required": ["url"]
+}`
+
+func (s *Server) v1GetDownload(w http.ResponseWriter, r *http.Request) {
+	job, err := s.lab.Downloads().Job(r.PathValue("id"))
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "download": job})
+}
+
+func (s *Server) v1CancelDownload(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := s.lab.Downloads().Cancel(id); err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "id": id})
+}
+
+const batchSchema = `{
+	"type": "object",
+	"properties": {
+		"steps": {"type": "array", "minItems": 1, "items": ` + batchStepSchema + `},
+		"parallel": {"type": "boolean", "default": false},
+		"stop_on_error": {"type": "boolean", "default": false}
+	},
+	"required": ["steps"]
+}`
+
+func (s *Server) v1Batch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Steps       []BatchStep `json:"steps"`
+		Parallel    bool        `json:"parallel"`
+		StopOnError bool        `json:"stop_on_error"`
+	}
+	if !s.decodeBody(w, r, &req) {
+		return
+	}
+	s.runBatch(w, r, req.Steps, BatchOptions{Parallel: req.Parallel, StopOnError: req.StopOnError})
+}
+
+func (s *Server) v1ListMacros(w http.ResponseWriter, r *http.Request) {
+	macros, err := s.lab.Macros()
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "count": len(macros), "macros": macros})
+}
+
+func (s *Server) v1RunMacro(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Parallel    bool `json:"parallel"`
+		StopOnError bool `json:"stop_on_error"`
+	}
+	if r.ContentLength != 0 && !s.decodeBody(w, r, &req) {
+		return
+	}
+	steps, err := s.lab.Macro(r.PathValue("id"))
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.runBatch(w, r, steps, BatchOptions{Parallel: req.Parallel, StopOnError: req.StopOnError})
+}
+
+// runBatch checks the client may run every step, then runs them. The
+// response is 200 whenever the batch ran; per-step outcomes are in steps.
+func (s *Server) runBatch(w http.ResponseWriter, r *http.Request, steps []BatchStep, opts BatchOptions) {
+	if !s.authorizeSteps(w, r, steps) {
+		return
+	}
+	result, err := s.lab.RunBatch(r.Context(), steps, opts)
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, result)
+}
+
+// authorizeSteps checks the client may run every step: play and queue
+// need the play scope, and every screen a step touches must be allowed
+func (s *Server) authorizeSteps(w http.ResponseWriter, r *http.Request, steps []BatchStep) bool {
+	for _, step := range steps {
+		if (step.Action == "play" || step.Action == "queue") && !s.requireScope(w, r, ScopePlay) {
+			return false
+		}
+		if step.AllScreens && !s.requireAllScreens(w, r) {
+			return false
+		}
+		screens := step.Screens
+		if len(screens) == 0 && !step.AllScreens {
+			screens = []Screen{s.lab.config.DefaultScreen}
+		}
+		for _, screen := range screens {
+			if !s.authorizeScreen(w, r, screen) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *Server) v1ListScenes(w http.ResponseWriter, r *http.Request) {
+	scenes, err := s.lab.Scenes()
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "count": len(scenes), "scenes": scenes})
+}
+
+func (s *Server) v1SaveScene(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if !s.decodeBody(w, r, &req) || !s.requireAllScreens(w, r) {
+		return
+	}
+	scene, err := s.lab.SaveScene(req.Name)
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/scenes/"+url.PathEscape(scene.Name))
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "scene": scene})
+}
+
+func (s *Server) v1GetScene(w http.ResponseWriter, r *http.Request) {
+	scene, err := s.lab.GetScene(r.PathValue("id"))
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "scene": scene})
+}
+
+func (s *Server) v1DeleteScene(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("id")
+	if err := s.lab.DeleteScene(name); err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "name": name})
+}
+
+func (s *Server) v1LoadScene(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAllScreens(w, r) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), time.Minute)
+	defer cancel()
+
+	scene, err := s.lab.LoadScene(ctx, r.PathValue("id"))
+	if scene == nil {
+		s.writeLabError(w, err)
+		return
+	}
+	resp := map[string]any{"success": err == nil, "scene": scene}
+	if err != nil {
+		// Partially restored: the screens that failed are in the error
+		resp["error"] = err.Error()
+		resp["code"] = errorCode(err)
+	}
+	s.writeJSON(w, resp)
+}
+
+const scheduleEntrySchema = `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string"},
+		"cron": {"type": "string", "description": "minute hour day month weekday, local time, or @daily etc."},
+		"at": {"type": "string", "format": "date-time", "description": "One-off start instead of cron"},
+		"until": {"type": "string", "pattern": "^\\d{2}:\\d{2}$", "description": "End of the window (HH:MM); the entry's screens are stopped then"},
+		"steps": {"type": "array", "items": ` + batchStepSchema + `},
+		"scene": {"type": "string", "description": "Scene to load instead of steps"},
+		"allow_overlap": {"type": "boolean", "default": false},
+		"disabled": {"type": "boolean", "default": false}
+	}
+}`
+
+func (s *Server) v1ListSchedule(w http.ResponseWriter, r *http.Request) {
+	scheduler := s.lab.Scheduler()
+	entries, err := scheduler.Entries()
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	conflicts, err := scheduler.Conflicts()
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	next, err := scheduler.NextRuns(time.Now())
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{
+		"success":   true,
+		"count":     len(entries),
+		"entries":   entries,
+		"next":      next,
+		"conflicts": conflicts,
+	})
+}
+
+// authorizeScheduleEntry checks the client could run e itself
+func (s *Server) authorizeScheduleEntry(w http.ResponseWriter, r *http.Request, e ScheduleEntry) bool {
+	if e.Scene != "" {
+		return s.requireScope(w, r, ScopePlay) && s.requireAllScreens(w, r)
+	}
+	return s.authorizeSteps(w, r, e.Steps)
+}
+
+func (s *Server) v1AddScheduleEntry(w http.ResponseWriter, r *http.Request) {
+	var e ScheduleEntry
+	if !s.decodeBody(w, r, &e) || !s.authorizeScheduleEntry(w, r, e) {
+		return
+	}
+	e, err := s.lab.Scheduler().Add(e)
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/schedule/"+e.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "entry": e})
+}
+
+func (s *Server) v1GetScheduleEntry(w http.ResponseWriter, r *http.Request) {
+	e, err := s.lab.Scheduler().Get(r.PathValue("id"))
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "entry": e})
+}
+
+func (s *Server) v1UpdateScheduleEntry(w http.ResponseWriter, r *http.Request) {
+	scheduler := s.lab.Scheduler()
+	old, err := scheduler.Get(r.PathValue("id"))
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	var e ScheduleEntry
+	if !s.decodeBody(w, r, &e) || !s.authorizeScheduleEntry(w, r, old) || !s.authorizeScheduleEntry(w, r, e) {
+		return
+	}
+	e, err = scheduler.Update(old.ID, e)
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "entry": e})
+}
+
+func (s *Server) v1DeleteScheduleEntry(w http.ResponseWriter, r *http.Request) {
+	scheduler := s.lab.Scheduler()
+	e, err := scheduler.Get(r.PathValue("id"))
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	if !s.authorizeScheduleEntry(w, r, e) {
+		return
+	}
+	if err := scheduler.Remove(e.ID); err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "id": e.ID})
+}
+
+const timerSchema = `{
+	"type": "object",
+	"properties": {
+		"screen": {"type": ["integer", "string"], "description": "1-4 or speaker (default 1)"},
+		"action": {"type": "string", "enum": ["stop", "pause"]},
+		"after": {"type": "number", "exclusiveMinimum": 0, "description": "Seconds from now"},
+		"at_end": {"type": "boolean", "description": "Act when the current entry ends instead"},
+		"fade": {"type": "number", "minimum": 0, "description": "Fade the volume out over the last seconds"}
+	},
+	"required": ["action"]
+}`
+
+func (s *Server) v1ListTimers(w http.ResponseWriter, r *http.Request) {
+	principal, restricted := PrincipalFromContext(r.Context())
+	timers := make([]Timer, 0)
+	for _, timer := range s.lab.Timers() {
+		if !restricted || principal.AllowsScreen(timer.Screen) {
+			timers = append(timers, timer)
+		}
+	}
+	s.writeJSON(w, map[string]any{"success": true, "count": len(timers), "timers": timers})
+}
+
+func (s *Server) v1StartTimer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Screen screenArg `json:"screen"`
+		Action string    `json:"action"`
+		After  float64   `json:"after"`
+		AtEnd  bool      `json:"at_end"`
+		Fade   float64   `json:"fade"`
+	}
+	if !s.decodeBody(w, r, &req) || !s.authorizeScreen(w, r, Screen(req.Screen)) {
+		return
+	}
+	timer, err := s.lab.StartTimer(TimerOptions{
+		Screen: Screen(req.Screen),
+		Action: req.Action,
+		After:  time.Duration(req.After * float64(time.Second)),
+		AtEnd:  req.AtEnd,
+		Fade:   time.Duration(req.Fade * float64(time.Second)),
+	})
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/v1/timers/"+timer.ID)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"success": true, "timer": timer})
+}
+
+func (s *Server) v1GetTimer(w http.ResponseWriter, r *http.Request) {
+	timer, err := s.lab.GetTimer(r.PathValue("id"))
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	if !s.authorizeScreen(w, r, timer.Screen) {
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "timer": timer})
+}
+
+func (s *Server) v1CancelTimer(w http.ResponseWriter, r *http.Request) {
+	timer, err := s.lab.GetTimer(r.PathValue("id"))
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	if !s.authorizeScreen(w, r, timer.Screen) {
+		return
+	}
+	if timer, err = s.lab.CancelTimer(timer.ID); err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	s.writeJSON(w, map[string]any{"success": true, "timer": timer})
+}
+
+func (s *Server) v1ListAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := AuditFilter{Actor: q.Get("actor"), Source: q.Get("source"), Action: q.Get("action")}
+	if v := q.Get("screen"); v != "" {
+		screen, err := ParseScreen(v)
+		if err != nil {
+			s.writeLabError(w, err)
+			return
+		}
+		filter.Screen = auditScreen(screen)
+	}
+	if v := q.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid since: %v", err))
+			return
+		}
+		filter.Since = since
+	}
+	limit := 100
+	if n, err := strconv.Atoi(q.Get("limit")); err == nil && n > 0 && n <= 1000 {
+		limit = n
+	}
+
+	// Clients limited to some screens only see actions on those
+	p, ok := PrincipalFromContext(r.Context())
+	limited := ok && len(p.Screens) > 0
+	if !limited {
+		filter.Limit = limit
+	}
+	entries, err := s.lab.AuditLog(filter)
+	if err != nil {
+		s.writeLabError(w, err)
+		return
+	}
+	if limited {
+		allowed := entries[:0]
+		for _, e := range entries {
+			if screen, err := ParseScreen(e.Screen); err == nil && p.AllowsScreen(screen) {
+				allowed = append(allowed, e)
+			}
+		}
+		entries = allowed[max(0, len(allowed)-limit):]
+	}
+	s.writeJSON(w, map[string]any{"success": true, "count": len(entries), "entries": entries})
+}
+
+// requireAllScreens rejects clients limited to some screens, for
+// operations such as scenes that touch every screen
+func (s *Server) requireAllScreens(w http.ResponseWriter, r *http.Request) bool {
+	if p, ok := PrincipalFromContext(r.Context()); ok && len(p.Screens) > 0 {
+		s.writeLabError(w, fmt.Errorf("%w: %s may not use every screen", ErrForbidden, p.Name))
+		return false
+	}
+	return true
+}
+
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	s.writeJSON(w, s.openAPISpec())
+}
+
+// openAPISpec generates the OpenAPI 3.1 document of the v1 routes
+func (s *Server) openAPISpec() map[string]any {
+	paths := make(map[string]map[string]any)
+	for _, rt := range s.v1Routes() {
+		var params []map[string]any
+		if strings.Contains(rt.path, "{id}") {
+			desc := "Screen: 1-4 or speaker"
+			switch {
+			case strings.HasPrefix(rt.path, "/v1/downloads/"):
+				desc = "Download job ID"
+			case strings.HasPrefix(rt.path, "/v1/macros/"):
+				desc = "Macro name"
+			case strings.HasPrefix(rt.path, "/v1/scenes/"):
+				desc = "Scene name"
+			case strings.HasPrefix(rt.path, "/v1/schedule/"):
+				desc = "Schedule entry ID"
+			case strings.HasPrefix(rt.path, "/v1/timers/"):
+				desc = "Timer ID"
+			}
+			params = append(params, map[string]any{
+				"name": "id", "in": "path", "required": true, "description": desc,
+				"schema": map[string]any{"type": "string"},
+			})
+		}
+		for _, p := range rt.query {
+			params = append(params, map[string]any{
+				"name": p.name, "in": "query", "required": p.required, "description": p.description,
+				"schema": map[string]any{"type": p.typ},
+			})
+		}
+
+		status := rt.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		op := map[string]any{
+			"operationId": rt.id,
+			"summary":     rt.summary,
+			"responses": map[string]any{
+				fmt.Sprint(status): map[string]any{
+					"description": http.StatusText(status),
+					"content":     jsonContent(`{"$ref": "#/components/schemas/Success"}`),
+				},
+				"default": map[string]any{
+					"description": "Error envelope",
+					"content":     jsonContent(`{"$ref": "#/components/schemas/Error"}`),
+				},
+			},
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if rt.body != "" {
+			op["requestBody"] = map[string]any{"required": true, "content": jsonContent(rt.body)}
+		}
+		if rt.scope == "" {
+			op["security"] = []any{}
+		} else {
+			op["x-scope"] = rt.scope
+		}
+
+		if paths[rt.path] == nil {
+			paths[rt.path] = make(map[string]any)
+		}
+		paths[rt.path][strings.ToLower(rt.method)] = op
+	}
+
+	return map[string]any{
+		"openapi": "3.1.0",
+		"info": map[string]any{
+			"title":       "MediaLab API",
+			"version":     "1.0.0",
+			"description": "Multi-screen media playback. Operations need the scope in x-scope when Config.Auth has clients.",
+		},
+		"paths": paths,
+		"components": map[string]any{
+			"schemas": map[string]any{
+				"Success": json.RawMessage(`{"type": "object", "properties": {"success": {"const": true}}, "required": ["success"]}`),
+				"Error": json.RawMessage(`{
+					"type": "object",
+					"properties": {
+						"success": {"const": false},
+						"error": {"type": "string"},
+						"code": {"type": "string", "enum": ["no_player", "ipc_timeout", "property_unavailable", "unknown_provider", "invalid_argument", "not_found", "unauthorized", "forbidden", "conflict", "method_not_allowed", "error"]}
+					},
+					"required": ["success", "error", "code"]
+				}`),
+			},
+			"securitySchemes": map[string]any{
+				"bearer": map[string]any{"type": "http", "scheme": "bearer"},
+				"hmac": map[string]any{"type": "apiKey", "in": "header", "name": HeaderSignature,
+					"description": "HMAC-SHA256 request signature with " + HeaderKeyID + " and " + HeaderTimestamp + ", see SignRequest"},
+				"mtls": map[string]any{"type": "mutualTLS"},
+			},
+		},
+		"security": []any{
+			map[string]any{"bearer": []string{}},
+			map[string]any{"hmac": []string{}},
+			map[string]any{"mtls": []string{}},
+		},
+	}
+}
+
+func jsonContent(schema string) map[string]any {
+	return map[string]any{"application/json": map[string]any{"schema": json.RawMessage(schema)}}
+}
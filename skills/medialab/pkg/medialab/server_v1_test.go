@@ -0,0 +1,173 @@
+package medialab
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// decodeEnvelope decodes a JSON response body, failing the test on bad JSON
+func decodeEnvelope(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	var body map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("response %q is not JSON: %v", w.Body.String(), err)
+	}
+	return body
+}
+
+func TestV1Screens(t *testing.T) {
+	s := newAuthServer(AuthConfig{})
+
+	w := serveRequest(s, httptest.NewRequest("GET", "/v1/screens", nil))
+	body := decodeEnvelope(t, w)
+	screens, _ := body["screens"].([]any)
+	if w.Code != http.StatusOK || len(screens) != 5 {
+		t.Fatalf("GET /v1/screens = %d %v, want 5 screens", w.Code, body)
+	}
+	if last := screens[4].(map[string]any); last["id"] != "speaker" || last["active"] != false {
+		t.Errorf("speaker entry = %v", last)
+	}
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		code               string
+	}{
+		{"GET", "/v1/screens/7", "", http.StatusNotFound, "not_found"},
+		{"GET", "/v1/screens/tv", "", http.StatusNotFound, "not_found"},
+		{"PUT", "/v1/screens/1", "{}", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"GET", "/v1/nothing", "", http.StatusNotFound, "not_found"},
+		{"PATCH", "/v1/screens/1", "{}", http.StatusBadRequest, "invalid_argument"},
+		{"PATCH", "/v1/screens/1", "{", http.StatusBadRequest, "invalid_argument"},
+		{"PATCH", "/v1/screens/1", `{"volume": 150}`, http.StatusBadRequest, "invalid_argument"},
+		{"PATCH", "/v1/screens/1", `{"volume": -1}`, http.StatusBadRequest, "invalid_argument"},
+		{"PATCH", "/v1/screens/speaker", `{"volume": 50}`, http.StatusNotFound, "no_player"},
+		{"POST", "/v1/screens/2/seek", `{"position": 10}`, http.StatusNotFound, "no_player"},
+	}
+	for _, tt := range tests {
+		w := serveRequest(s, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		body := decodeEnvelope(t, w)
+		if w.Code != tt.status || body["code"] != tt.code || body["success"] != false {
+			t.Errorf("%s %s = %d %v, want %d %s", tt.method, tt.path, w.Code, body, tt.status, tt.code)
+		}
+	}
+
+	w = serveRequest(s, httptest.NewRequest("PUT", "/v1/screens/1", nil))
+	if allow := w.Header().Get("Allow"); allow != "GET, PATCH, DELETE" {
+		t.Errorf("Allow = %q, want GET, PATCH, DELETE", allow)
+	}
+}
+
+func TestV1Auth(t *testing.T) {
+	s := newAuthServer(AuthConfig{
+		Clients: []APIClient{
+			{Name: "dashboard", Token: "read-token", Scopes: []Scope{ScopeRead}, Screens: []Screen{Screen2}},
+		},
+	})
+	request := func(method, path string) int {
+		r := httptest.NewRequest(method, path, strings.NewReader(`{"paused": true}`))
+		r.Header.Set("Authorization", "Bearer read-token")
+		return serveRequest(s, r).Code
+	}
+
+	if code := request("PATCH", "/v1/screens/2"); code != http.StatusForbidden {
+		t.Errorf("PATCH without control scope = %d, want 403", code)
+	}
+	if code := request("GET", "/v1/screens/1"); code != http.StatusForbidden {
+		t.Errorf("GET of a screen outside the ACL = %d, want 403", code)
+	}
+	if code := request("GET", "/v1/screens/2"); code == http.StatusForbidden || code == http.StatusUnauthorized {
+		t.Errorf("GET of an allowed screen = %d", code)
+	}
+	if w := serveRequest(s, httptest.NewRequest("GET", "/v1/screens", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("GET /v1/screens without credentials = %d, want 401", w.Code)
+	}
+	if w := serveRequest(s, httptest.NewRequest("GET", "/v1/openapi.json", nil)); w.Code != http.StatusOK {
+		t.Errorf("GET /v1/openapi.json without credentials = %d, want 200", w.Code)
+	}
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	s := newAuthServer(AuthConfig{})
+	w := serveRequest(s, httptest.NewRequest("GET", "/v1/openapi.json", nil))
+
+	var spec struct {
+		OpenAPI string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("openapi.json: %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("openapi = %q", spec.OpenAPI)
+	}
+
+	// Every route in the table is documented under its method
+	for _, rt := range s.v1Routes() {
+		op, ok := spec.Paths[rt.path][strings.ToLower(rt.method)]
+		if !ok {
+			t.Errorf("%s %s missing from the document", rt.method, rt.path)
+			continue
+		}
+		if op["operationId"] != rt.id {
+			t.Errorf("%s %s operationId = %v, want %s", rt.method, rt.path, op["operationId"], rt.id)
+		}
+		if _, ok := op["requestBody"]; ok != (rt.body != "") {
+			t.Errorf("%s %s requestBody present = %v", rt.method, rt.path, ok)
+		}
+	}
+}
+
+func TestDeprecatedRoutes(t *testing.T) {
+	s := newAuthServer(AuthConfig{})
+	w := serveRequest(s, httptest.NewRequest("GET", "/stations", nil))
+	if w.Header().Get("Deprecation") != "true" {
+		t.Errorf("Deprecation = %q, want true", w.Header().Get("Deprecation"))
+	}
+	if link := w.Header().Get("Link"); link != `</v1/stations>; rel="successor-version"` {
+		t.Errorf("Link = %q", link)
+	}
+	if w := serveRequest(s, httptest.NewRequest("GET", "/v1/stations", nil)); w.Header().Get("Deprecation") != "" {
+		t.Error("/v1/stations marked deprecated")
+	}
+}
+
+func TestV1ControlLoopCount(t *testing.T) {
+	lab := newTestLab(t)
+	player := newFakePlayer(t, lab, Screen2, nil)
+	s := NewServer(lab)
+
+	for _, tt := range []struct {
+		body, prop, want string
+	}{
+		{`{"action": "loop-file"}`, "loop-file", "inf"},
+		{`{"action": "loop-file", "count": 0}`, "loop-file", "no"},
+		{`{"action": "loop-playlist", "count": -1}`, "loop-playlist", "inf"},
+		{`{"action": "loop-playlist", "count": 2}`, "loop-playlist", "2"},
+	} {
+		w := serveRequest(s, httptest.NewRequest("POST", "/v1/screens/2/control", strings.NewReader(tt.body)))
+		if w.Code != http.StatusOK {
+			t.Errorf("POST control %s = %d %s", tt.body, w.Code, w.Body)
+		}
+		if got := player.prop(tt.prop); got != tt.want {
+			t.Errorf("POST control %s: %s = %v, want %s", tt.body, tt.prop, got, tt.want)
+		}
+	}
+}
+
+func TestV1SearchDurationFilters(t *testing.T) {
+	s := NewServer(newTestLab(t))
+	for _, query := range []string{"min_duration=abc", "max_duration=-5", "min_duration=NaN", "max_duration=inf"} {
+		w := serveRequest(s, httptest.NewRequest("GET", "/v1/search?provider=local&q=cats&"+query, nil))
+		if body := decodeEnvelope(t, w); w.Code != http.StatusBadRequest || body["code"] != "invalid_argument" {
+			t.Errorf("GET /v1/search?%s = %d %v, want 400 invalid_argument", query, w.Code, body)
+		}
+	}
+	w := serveRequest(s, httptest.NewRequest("GET", "/v1/search?provider=local&q=cats&min_duration=60&max_duration=600.5", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("GET /v1/search with valid durations = %d %s", w.Code, w.Body)
+	}
+}
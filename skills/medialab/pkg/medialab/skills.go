@@ -3,12 +3,14 @@ package medialab
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/phenomenon0/Agent-GO/core"
 )
 
-// RegisterSkills registers all medialab skills with the tool registry
+// RegisterSkills registers all medialab skills with the tool registry.
+// Calls that act on players are recorded in the audit log.
 func RegisterSkills(registry *core.ToolRegistry, lab *MediaLab) {
 	defaultPolicy := core.ToolPolicy{
 		DefaultTimeout: 30 * time.Second,
@@ -16,13 +18,19 @@ func RegisterSkills(registry *core.ToolRegistry, lab *MediaLab) {
 		MaxRetries:     2,
 	}
 
-	registry.Register(&MediaPlayTool{lab: lab}, defaultPolicy, nil)
-	registry.Register(&MediaControlTool{lab: lab}, defaultPolicy, nil)
-	registry.Register(&MediaVolumeTool{lab: lab}, defaultPolicy, nil)
-	registry.Register(&MediaSeekTool{lab: lab}, defaultPolicy, nil)
-	registry.Register(&MediaInfoTool{lab: lab}, defaultPolicy, nil)
-	registry.Register(&MediaSearchTool{lab: lab}, defaultPolicy, nil)
-	registry.Register(&MediaListTool{lab: lab}, defaultPolicy, nil)
+	registry.Register(auditedTool{&MediaPlayTool{lab: lab}, lab}, defaultPolicy, nil)
+	registry.Register(auditedTool{&MediaControlTool{lab: lab}, lab}, defaultPolicy, nil)
+	registry.Register(auditedTool{&MediaVolumeTool{lab: lab}, lab}, defaultPolicy, nil)
+	registry.Register(auditedTool{&MediaSeekTool{lab: lab}, lab}, defaultPolicy, nil)
+	registry.Register(auditedTool{&MediaInfoTool{lab: lab}, lab}, defaultPolicy, nil)
+	registry.Register(auditedTool{&MediaSearchTool{lab: lab}, lab}, defaultPolicy, nil)
+	registry.Register(auditedTool{&MediaListTool{lab: lab}, lab}, defaultPolicy, nil)
+	registry.Register(auditedTool{&MediaClipTool{lab: lab}, lab}, core.ToolPolicy{DefaultTimeout: 5 * time.Minute}, nil)
+	registry.Register(auditedTool{&MediaDownloadTool{lab: lab}, lab}, core.ToolPolicy{DefaultTimeout: 30 * time.Minute}, nil)
+	registry.Register(auditedTool{&MediaBatchTool{lab: lab}, lab}, core.ToolPolicy{DefaultTimeout: 2 * time.Minute}, nil)
+	registry.Register(auditedTool{&MediaSceneTool{lab: lab}, lab}, core.ToolPolicy{DefaultTimeout: time.Minute}, nil)
+	registry.Register(auditedTool{&MediaScheduleTool{lab: lab}, lab}, defaultPolicy, nil)
+	registry.Register(auditedTool{&MediaTimerTool{lab: lab}, lab}, defaultPolicy, nil)
 }
 
 // === media.play ===
@@ -35,44 +43,90 @@ func (t *MediaPlayTool) Name() string { return "media.play" }
 
 func (t *MediaPlayTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
 	var input struct {
-		URL    string `json:"url"`
-		Query  string `json:"query"`  // YouTube search query (alternative to URL)
-		Screen int    `json:"screen"` // 1, 2, 3, or 4 (default: 1)
+		URL         string    `json:"url"`
+		Query       string    `json:"query"`        // search query: local library match, else first YouTube result
+		ResultIndex int       `json:"result_index"` // 1-based result of the last media.search
+		VideoID     string    `json:"video_id"`     // YouTube video ID
+		Station     string    `json:"station"`      // configured radio station name
+		Screen      screenArg `json:"screen"`       // 1-4 or "speaker" (default: 1)
+		Playlist    bool      `json:"playlist"`     // expand url as a playlist (automatic for YouTube playlists/channels)
+		Start       int       `json:"start"`        // 1-based first playlist entry
+		Limit       int       `json:"limit"`        // max playlist entries, 0 = DefaultPlaylistLimit
+		AllEntries  bool      `json:"all_entries"`  // every playlist entry, ignoring limit
+		Shuffle     bool      `json:"shuffle"`
+		Quality     string    `json:"quality"` // best, audio, 1080p, vp9, 1080p:av1 or a raw ytdl-format
 	}
 
 	if err := extractInput(ctx, &input); err != nil {
 		return failResult(err.Error())
 	}
 
-	screen := Screen(input.Screen - 1)
-	if screen < Screen1 || screen > Screen4 {
-		screen = Screen1
+	screen := Screen(input.Screen)
+
+	var opts PlayOptions
+	if input.Quality != "" {
+		q, err := ParseQuality(input.Quality)
+		if err != nil {
+			return labFailResult("playback failed", err)
+		}
+		opts.Quality = &q
 	}
 
-	var instance *PlayerInstance
+	url := input.URL
+	var result *SearchResult
 	var err error
 
-	if input.URL != "" {
-		instance, err = t.lab.Play(ctx.Ctx, input.URL, screen)
-	} else if input.Query != "" {
-		instance, err = t.lab.PlayYouTubeSearch(ctx.Ctx, input.Query, screen)
-	} else {
-		return failResult("either 'url' or 'query' is required")
+	switch {
+	case url != "":
+	case input.VideoID != "":
+		url, err = VideoURL(input.VideoID)
+	case input.Station != "":
+		var st Station
+		if st, err = t.lab.Station(input.Station); err == nil {
+			url = st.URL
+		}
+	case input.ResultIndex > 0:
+		if result, err = t.lab.ResultAt(input.ResultIndex); err == nil {
+			url = result.URL
+		}
+	case input.Query != "":
+		url, err = t.lab.ResolveQuery(ctx.Ctx, input.Query)
+	default:
+		return failResult("one of 'url', 'query', 'result_index', 'video_id' or 'station' is required")
+	}
+
+	var instance *PlayerInstance
+	var playlist *Playlist
+	if err == nil {
+		if input.Playlist || IsPlaylistURL(url) {
+			plOpts := PlaylistOptions{Start: input.Start, Limit: input.Limit, All: input.AllEntries, Shuffle: input.Shuffle, Quality: opts.Quality}
+			instance, playlist, err = t.lab.PlayPlaylist(ctx.Ctx, url, plOpts, screen)
+		} else {
+			instance, err = t.lab.PlayWith(ctx.Ctx, url, screen, opts)
+		}
 	}
 
 	if err != nil {
-		return failResult(fmt.Sprintf("playback failed: %v", err))
+		return labFailResult("playback failed", err)
 	}
 
+	output := map[string]any{
+		"success": true,
+		"screen":  screenID(screen),
+		"pid":     instance.PID,
+		"url":     instance.URL,
+		"socket":  instance.Socket,
+	}
+	if result != nil {
+		output["title"] = result.Title
+		output["channel"] = result.Channel
+	}
+	if playlist != nil {
+		output["playlist"] = playlist
+	}
 	return &core.ToolExecResult{
 		Status: core.ToolComplete,
-		Output: map[string]any{
-			"success": true,
-			"screen":  int(screen) + 1,
-			"pid":     instance.PID,
-			"url":     instance.URL,
-			"socket":  instance.Socket,
-		},
+		Output: output,
 	}
 }
 
@@ -81,12 +135,24 @@ func (t *MediaPlayTool) InputSchema() []byte {
 		"type": "object",
 		"properties": {
 			"url": {"type": "string", "description": "URL or file path to play (YouTube URLs work directly)"},
-			"query": {"type": "string", "description": "YouTube search query (plays first result)"},
-			"screen": {"type": "integer", "minimum": 1, "maximum": 4, "default": 1, "description": "Target screen (1-4)"}
+			"query": {"type": "string", "description": "Search query (plays the best local library match, else the first YouTube result)"},
+			"result_index": {"type": "integer", "minimum": 1, "description": "Play the Nth result (1-based) of the last media.search"},
+			"video_id": {"type": "string", "description": "YouTube video ID (e.g. dQw4w9WgXcQ)"},
+			"station": {"type": "string", "description": "Name of a configured radio station or live stream (e.g. Groove Salad)"},
+			"screen": {"type": ["integer", "string"], "default": 1, "description": "Target screen: 1-4, or \"speaker\" for audio only"},
+			"playlist": {"type": "boolean", "default": false, "description": "Expand url as a playlist (automatic for YouTube playlist and channel URLs)"},
+			"start": {"type": "integer", "minimum": 1, "default": 1, "description": "First playlist entry to play (1-based)"},
+			"limit": {"type": "integer", "minimum": 0, "default": 200, "description": "Maximum playlist entries to load (default 200)"},
+			"all_entries": {"type": "boolean", "default": false, "description": "Load every playlist entry, however many, ignoring limit"},
+			"shuffle": {"type": "boolean", "default": false, "description": "Shuffle playlist entries"},
+			"quality": {"type": "string", "description": "Format selection: best, audio (audio only), a max height (1080p, 720p), a preferred codec (av1, vp9, h264), both (1080p:vp9) or a raw ytdl-format. Default: the screen's configured quality"}
 		},
 		"oneOf": [
 			{"required": ["url"]},
-			{"required": ["query"]}
+			{"required": ["query"]},
+			{"required": ["result_index"]},
+			{"required": ["video_id"]},
+			{"required": ["station"]}
 		]
 	}`)
 }
@@ -114,18 +180,20 @@ func (t *MediaControlTool) Name() string { return "media.control" }
 
 func (t *MediaControlTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
 	var input struct {
-		Action string `json:"action"` // playpause, pause, play, stop, next, prev, fullscreen
-		Screen int    `json:"screen"`
+		Action string    `json:"action"`           // playpause, pause, play, stop, next, prev, fullscreen, loop-*, speed, frame-step, frame-back-step
+		Count  *int      `json:"count"`            // loop count (omitted or -1 = infinite, 0 = off)
+		A      float64   `json:"a"`                // A-B loop start (seconds)
+		B      float64   `json:"b"`                // A-B loop end (seconds)
+		Speed  float64   `json:"speed"`            // playback speed multiplier
+		Pitch  *bool     `json:"pitch_correction"` // default: true
+		Screen screenArg `json:"screen"`
 	}
 
 	if err := extractInput(ctx, &input); err != nil {
 		return failResult(err.Error())
 	}
 
-	screen := Screen(input.Screen - 1)
-	if screen < Screen1 || screen > Screen4 {
-		screen = Screen1
-	}
+	screen := Screen(input.Screen)
 
 	var err error
 	switch input.Action {
@@ -143,17 +211,31 @@ func (t *MediaControlTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
 		err = t.lab.Prev(screen)
 	case "fullscreen", "fs":
 		err = t.lab.Fullscreen(screen)
+	case "loop-file", "loop":
+		err = t.lab.SetLoopFile(screen, loopCount(input.Count))
+	case "loop-playlist":
+		err = t.lab.SetLoopPlaylist(screen, loopCount(input.Count))
+	case "ab-loop":
+		err = t.lab.SetABLoop(screen, input.A, input.B)
+	case "loop-off", "unloop":
+		err = t.lab.ClearLoop(screen)
+	case "speed":
+		err = t.lab.SetSpeed(screen, input.Speed, input.Pitch == nil || *input.Pitch)
+	case "frame-step", "step":
+		err = t.lab.FrameStep(screen)
+	case "frame-back-step", "back-step":
+		err = t.lab.FrameBackStep(screen)
 	default:
 		return failResult(fmt.Sprintf("unknown action: %s", input.Action))
 	}
 
 	if err != nil {
-		return failResult(fmt.Sprintf("control failed: %v", err))
+		return labFailResult("control failed", err)
 	}
 
 	return &core.ToolExecResult{
 		Status: core.ToolComplete,
-		Output: map[string]any{"success": true, "action": input.Action, "screen": int(screen) + 1},
+		Output: map[string]any{"success": true, "action": input.Action, "screen": screenID(screen)},
 	}
 }
 
@@ -162,8 +244,13 @@ func (t *MediaControlTool) InputSchema() []byte {
 		"type": "object",
 		"required": ["action"],
 		"properties": {
-			"action": {"type": "string", "enum": ["playpause", "pause", "play", "stop", "next", "prev", "fullscreen"], "description": "Control action"},
-			"screen": {"type": "integer", "minimum": 1, "maximum": 4, "default": 1, "description": "Target screen"}
+			"action": {"type": "string", "enum": ["playpause", "pause", "play", "stop", "next", "prev", "fullscreen", "loop-file", "loop-playlist", "ab-loop", "loop-off", "speed", "frame-step", "frame-back-step"], "description": "Control action"},
+			"count": {"type": "integer", "minimum": -1, "default": -1, "description": "Repeat count for loop-file/loop-playlist (-1 = infinite, 0 = off)"},
+			"a": {"type": "number", "minimum": 0, "description": "A-B loop start in seconds (ab-loop)"},
+			"b": {"type": "number", "minimum": 0, "description": "A-B loop end in seconds (ab-loop)"},
+			"speed": {"type": "number", "minimum": 0.01, "maximum": 100, "description": "Playback speed multiplier, e.g. 0.5 or 1.25 (speed)"},
+			"pitch_correction": {"type": "boolean", "default": true, "description": "Keep audio pitch when changing speed (speed)"},
+			"screen": {"type": ["integer", "string"], "default": 1, "description": "Target screen: 1-4, or \"speaker\" for audio only"}
 		}
 	}`)
 }
@@ -174,7 +261,7 @@ func (t *MediaControlTool) Manifest() *core.ToolManifest {
 	return &core.ToolManifest{
 		Name:        "media.control",
 		Version:     "1.0.0",
-		Description: "Control media playback (play/pause/stop/next/prev/fullscreen)",
+		Description: "Control media playback (play/pause/stop/next/prev/fullscreen/loop/speed/frame-step)",
 		Category:    "media",
 		Tags:        []string{"media", "control", "playback"},
 		InputSchema: t.InputSchema(),
@@ -191,26 +278,23 @@ func (t *MediaVolumeTool) Name() string { return "media.volume" }
 
 func (t *MediaVolumeTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
 	var input struct {
-		Volume int `json:"volume"` // 0-100
-		Screen int `json:"screen"`
+		Volume int       `json:"volume"` // 0-100
+		Screen screenArg `json:"screen"`
 	}
 
 	if err := extractInput(ctx, &input); err != nil {
 		return failResult(err.Error())
 	}
 
-	screen := Screen(input.Screen - 1)
-	if screen < Screen1 || screen > Screen4 {
-		screen = Screen1
-	}
+	screen := Screen(input.Screen)
 
 	if err := t.lab.SetVolume(screen, input.Volume); err != nil {
-		return failResult(fmt.Sprintf("volume change failed: %v", err))
+		return labFailResult("volume change failed", err)
 	}
 
 	return &core.ToolExecResult{
 		Status: core.ToolComplete,
-		Output: map[string]any{"success": true, "volume": input.Volume, "screen": int(screen) + 1},
+		Output: map[string]any{"success": true, "volume": input.Volume, "screen": screenID(screen)},
 	}
 }
 
@@ -220,7 +304,7 @@ func (t *MediaVolumeTool) InputSchema() []byte {
 		"required": ["volume"],
 		"properties": {
 			"volume": {"type": "integer", "minimum": 0, "maximum": 100, "description": "Volume level (0-100)"},
-			"screen": {"type": "integer", "minimum": 1, "maximum": 4, "default": 1, "description": "Target screen"}
+			"screen": {"type": ["integer", "string"], "default": 1, "description": "Target screen: 1-4, or \"speaker\" for audio only"}
 		}
 	}`)
 }
@@ -248,27 +332,24 @@ func (t *MediaSeekTool) Name() string { return "media.seek" }
 
 func (t *MediaSeekTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
 	var input struct {
-		Position float64 `json:"position"` // seconds
-		Relative bool    `json:"relative"` // if true, position is offset from current
-		Screen   int     `json:"screen"`
+		Position float64   `json:"position"` // seconds
+		Relative bool      `json:"relative"` // if true, position is offset from current
+		Screen   screenArg `json:"screen"`
 	}
 
 	if err := extractInput(ctx, &input); err != nil {
 		return failResult(err.Error())
 	}
 
-	screen := Screen(input.Screen - 1)
-	if screen < Screen1 || screen > Screen4 {
-		screen = Screen1
-	}
+	screen := Screen(input.Screen)
 
 	if err := t.lab.Seek(screen, input.Position, input.Relative); err != nil {
-		return failResult(fmt.Sprintf("seek failed: %v", err))
+		return labFailResult("seek failed", err)
 	}
 
 	return &core.ToolExecResult{
 		Status: core.ToolComplete,
-		Output: map[string]any{"success": true, "position": input.Position, "relative": input.Relative, "screen": int(screen) + 1},
+		Output: map[string]any{"success": true, "position": input.Position, "relative": input.Relative, "screen": screenID(screen)},
 	}
 }
 
@@ -279,7 +360,7 @@ func (t *MediaSeekTool) InputSchema() []byte {
 		"properties": {
 			"position": {"type": "number", "description": "Position in seconds (absolute or relative offset)"},
 			"relative": {"type": "boolean", "default": false, "description": "If true, seek relative to current position"},
-			"screen": {"type": "integer", "minimum": 1, "maximum": 4, "default": 1, "description": "Target screen"}
+			"screen": {"type": ["integer", "string"], "default": 1, "description": "Target screen: 1-4, or \"speaker\" for audio only"}
 		}
 	}`)
 }
@@ -307,37 +388,23 @@ func (t *MediaInfoTool) Name() string { return "media.info" }
 
 func (t *MediaInfoTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
 	var input struct {
-		Screen int `json:"screen"`
+		Screen screenArg `json:"screen"`
 	}
 
 	if err := extractInput(ctx, &input); err != nil {
 		return failResult(err.Error())
 	}
 
-	screen := Screen(input.Screen - 1)
-	if screen < Screen1 || screen > Screen4 {
-		screen = Screen1
-	}
+	screen := Screen(input.Screen)
 
 	info, err := t.lab.GetPlaybackInfo(screen)
 	if err != nil {
-		return failResult(fmt.Sprintf("failed to get info: %v", err))
+		return labFailResult("failed to get info", err)
 	}
 
 	return &core.ToolExecResult{
 		Status: core.ToolComplete,
-		Output: map[string]any{
-			"screen":      int(info.Screen) + 1,
-			"playing":     info.Playing,
-			"paused":      info.Paused,
-			"position":    info.Position,
-			"duration":    info.Duration,
-			"volume":      info.Volume,
-			"filename":    info.Filename,
-			"media_title": info.MediaTitle,
-			"fullscreen":  info.Fullscreen,
-			"percent":     info.PercentPos,
-		},
+		Output: playbackInfoMap(info),
 	}
 }
 
@@ -345,7 +412,7 @@ func (t *MediaInfoTool) InputSchema() []byte {
 	return []byte(`{
 		"type": "object",
 		"properties": {
-			"screen": {"type": "integer", "minimum": 1, "maximum": 4, "default": 1, "description": "Target screen"}
+			"screen": {"type": ["integer", "string"], "default": 1, "description": "Target screen: 1-4, or \"speaker\" for audio only"}
 		}
 	}`)
 }
@@ -373,8 +440,14 @@ func (t *MediaSearchTool) Name() string { return "media.search" }
 
 func (t *MediaSearchTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
 	var input struct {
-		Query      string `json:"query"`
-		MaxResults int    `json:"max_results"`
+		Query       string  `json:"query"`
+		Provider    string  `json:"provider"` // youtube (default), soundcloud, archive, local or a yt-dlp prefix
+		MaxResults  int     `json:"max_results"`
+		MinDuration float64 `json:"min_duration"` // seconds
+		MaxDuration float64 `json:"max_duration"` // seconds
+		ExcludeLive bool    `json:"exclude_live"`
+		Sort        string  `json:"sort"` // relevance, date, views, duration
+		Full        bool    `json:"full"` // full metadata (slow)
 	}
 
 	if err := extractInput(ctx, &input); err != nil {
@@ -389,17 +462,30 @@ func (t *MediaSearchTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
 		input.MaxResults = 5
 	}
 
-	results, err := t.lab.SearchYouTube(ctx.Ctx, input.Query, input.MaxResults)
+	if input.Provider == "" {
+		input.Provider = DefaultSearchProvider
+	}
+
+	results, err := t.lab.Search(ctx.Ctx, input.Provider, input.Query, SearchOptions{
+		MaxResults:  input.MaxResults,
+		MinDuration: input.MinDuration,
+		MaxDuration: input.MaxDuration,
+		ExcludeLive: input.ExcludeLive,
+		Sort:        input.Sort,
+		Full:        input.Full,
+		Remember:    true,
+	})
 	if err != nil {
-		return failResult(fmt.Sprintf("search failed: %v", err))
+		return labFailResult("search failed", err)
 	}
 
 	return &core.ToolExecResult{
 		Status: core.ToolComplete,
 		Output: map[string]any{
-			"query":   input.Query,
-			"count":   len(results),
-			"results": results,
+			"query":    input.Query,
+			"provider": input.Provider,
+			"count":    len(results),
+			"results":  results,
 		},
 	}
 }
@@ -409,8 +495,14 @@ func (t *MediaSearchTool) InputSchema() []byte {
 		"type": "object",
 		"required": ["query"],
 		"properties": {
-			"query": {"type": "string", "description": "YouTube search query"},
-			"max_results": {"type": "integer", "minimum": 1, "maximum": 20, "default": 5, "description": "Maximum results to return"}
+			"query": {"type": "string", "description": "Search query"},
+			"provider": {"type": "string", "default": "youtube", "description": "youtube, soundcloud, archive (Internet Archive), local (library dirs) or any yt-dlp search prefix such as bilisearch"},
+			"max_results": {"type": "integer", "minimum": 1, "maximum": 20, "default": 5, "description": "Maximum results to return"},
+			"min_duration": {"type": "number", "minimum": 0, "description": "Minimum duration in seconds"},
+			"max_duration": {"type": "number", "minimum": 0, "description": "Maximum duration in seconds (e.g. 600 to avoid 10-hour loops when looking for a single song)"},
+			"exclude_live": {"type": "boolean", "default": false, "description": "Drop live and upcoming streams"},
+			"sort": {"type": "string", "enum": ["relevance", "date", "views", "duration"], "default": "relevance", "description": "Result order"},
+			"full": {"type": "boolean", "default": false, "description": "Fetch full metadata (upload date, full description); much slower"}
 		}
 	}`)
 }
@@ -421,9 +513,9 @@ func (t *MediaSearchTool) Manifest() *core.ToolManifest {
 	return &core.ToolManifest{
 		Name:        "media.search",
 		Version:     "1.0.0",
-		Description: "Search YouTube for videos",
+		Description: "Search YouTube, SoundCloud, Internet Archive, local files or any yt-dlp extractor",
 		Category:    "media",
-		Tags:        []string{"media", "youtube", "search"},
+		Tags:        []string{"media", "youtube", "soundcloud", "search"},
 		InputSchema: t.InputSchema(),
 	}
 }
@@ -442,7 +534,7 @@ func (t *MediaListTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
 	list := make([]map[string]any, 0, len(players))
 	for _, p := range players {
 		list = append(list, map[string]any{
-			"screen":     int(p.Screen) + 1,
+			"screen":     screenID(p.Screen),
 			"pid":        p.PID,
 			"url":        p.URL,
 			"socket":     p.Socket,
@@ -476,6 +568,560 @@ func (t *MediaListTool) Manifest() *core.ToolManifest {
 	}
 }
 
+// === media.clip ===
+
+type MediaClipTool struct {
+	lab *MediaLab
+}
+
+func (t *MediaClipTool) Name() string { return "media.clip" }
+
+func (t *MediaClipTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
+	var input struct {
+		URL    string    `json:"url"`    // source (default: current media on screen)
+		Start  float64   `json:"start"`  // seconds
+		End    float64   `json:"end"`    // seconds
+		Last   float64   `json:"last"`   // export the last N seconds up to the current position
+		Output string    `json:"output"` // output file path
+		Screen screenArg `json:"screen"`
+	}
+
+	if err := extractInput(ctx, &input); err != nil {
+		return failResult(err.Error())
+	}
+
+	screen := Screen(input.Screen)
+
+	result, err := t.lab.ExportClip(ctx.Ctx, ClipOptions{
+		URL:    input.URL,
+		Screen: screen,
+		Start:  input.Start,
+		End:    input.End,
+		Last:   input.Last,
+		Output: input.Output,
+	})
+	if err != nil {
+		return labFailResult("clip export failed", err)
+	}
+
+	return &core.ToolExecResult{
+		Status: core.ToolComplete,
+		Output: map[string]any{
+			"success":  true,
+			"screen":   screenID(screen),
+			"source":   result.Source,
+			"output":   result.Output,
+			"start":    result.Start,
+			"end":      result.End,
+			"duration": result.Duration,
+			"method":   result.Method,
+		},
+	}
+}
+
+func (t *MediaClipTool) InputSchema() []byte {
+	return []byte(`{
+		"type": "object",
+		"properties": {
+			"url": {"type": "string", "description": "Source URL or file (default: media currently loaded on the screen)"},
+			"start": {"type": "number", "minimum": 0, "description": "Range start in seconds"},
+			"end": {"type": "number", "minimum": 0, "description": "Range end in seconds"},
+			"last": {"type": "number", "minimum": 0, "description": "Export the last N seconds up to the current position (overrides start/end)"},
+			"output": {"type": "string", "description": "Output file path (default: ~/Videos/medialab/clip-<time>.mkv)"},
+			"screen": {"type": ["integer", "string"], "default": 1, "description": "Screen to read the current media from (1-4 or \"speaker\")"}
+		}
+	}`)
+}
+
+func (t *MediaClipTool) OutputSchema() []byte { return nil }
+
+func (t *MediaClipTool) Manifest() *core.ToolManifest {
+	return &core.ToolManifest{
+		Name:        "media.clip",
+		Version:     "1.0.0",
+		Description: "Export a time range of the current media (or any URL) to a local file",
+		Category:    "media",
+		Tags:        []string{"media", "clip", "export", "record"},
+		InputSchema: t.InputSchema(),
+	}
+}
+
+// === media.download ===
+
+type MediaDownloadTool struct {
+	lab *MediaLab
+}
+
+func (t *MediaDownloadTool) Name() string { return "media.download" }
+
+func (t *MediaDownloadTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
+	var input struct {
+		Action  string `json:"action"` // start (default), status, list, cancel, remove
+		URL     string `json:"url"`
+		ID      string `json:"id"`
+		Quality string `json:"quality"`
+		Wait    bool   `json:"wait"` // block until the download finishes
+	}
+
+	if err := extractInput(ctx, &input); err != nil {
+		return failResult(err.Error())
+	}
+
+	downloads := t.lab.Downloads()
+	var job DownloadJob
+	var err error
+
+	switch input.Action {
+	case "", "start":
+		var quality *Quality
+		if input.Quality != "" {
+			q, qErr := ParseQuality(input.Quality)
+			if qErr != nil {
+				return labFailResult("download failed", qErr)
+			}
+			quality = &q
+		}
+		job, err = downloads.Start(input.URL, quality)
+		if err == nil && input.Wait {
+			job, err = downloads.Wait(ctx.Ctx, job.ID)
+		}
+	case "status":
+		job, err = downloads.Job(input.ID)
+	case "cancel":
+		if err = downloads.Cancel(input.ID); err == nil {
+			job, err = downloads.Job(input.ID)
+		}
+	case "remove":
+		if err = downloads.Remove(input.URL); err == nil {
+			return &core.ToolExecResult{
+				Status: core.ToolComplete,
+				Output: map[string]any{"success": true, "removed": input.URL},
+			}
+		}
+	case "list":
+		return &core.ToolExecResult{
+			Status: core.ToolComplete,
+			Output: map[string]any{
+				"success": true,
+				"jobs":    downloads.Jobs(),
+				"cached":  downloads.Cached(),
+				"stats":   downloads.Stats(),
+			},
+		}
+	default:
+		return failResult(fmt.Sprintf("unknown action: %s", input.Action))
+	}
+
+	if err != nil {
+		return labFailResult("download failed", err)
+	}
+	if job.State == DownloadFailed {
+		return &core.ToolExecResult{
+			Status: core.ToolFailed,
+			Error:  "download failed: " + job.Error,
+			Output: map[string]any{"success": false, "code": "error", "download": job},
+		}
+	}
+
+	return &core.ToolExecResult{
+		Status: core.ToolComplete,
+		Output: map[string]any{
+			"success":  true,
+			"download": job,
+		},
+	}
+}
+
+func (t *MediaDownloadTool) InputSchema() []byte {
+	return []byte(`{
+		"type": "object",
+		"properties": {
+			"action": {"type": "string", "enum": ["start", "status", "list", "cancel", "remove"], "default": "start"},
+			"url": {"type": "string", "description": "Media URL (start, remove)"},
+			"id": {"type": "string", "description": "Download ID returned by start (status, cancel)"},
+			"quality": {"type": "string", "description": "Format: best, audio, 1080p, vp9, 1080p:av1 or a ytdl-format"},
+			"wait": {"type": "boolean", "default": false, "description": "Wait for the download to finish instead of returning immediately"}
+		}
+	}`)
+}
+
+func (t *MediaDownloadTool) OutputSchema() []byte { return nil }
+
+func (t *MediaDownloadTool) Manifest() *core.ToolManifest {
+	return &core.ToolManifest{
+		Name:        "media.download",
+		Version:     "1.0.0",
+		Description: "Download media for offline playback; media.play uses cached files automatically",
+		Category:    "media",
+		Tags:        []string{"media", "download", "offline", "cache"},
+		InputSchema: t.InputSchema(),
+	}
+}
+
+// === media.batch ===
+
+type MediaBatchTool struct {
+	lab *MediaLab
+}
+
+func (t *MediaBatchTool) Name() string { return "media.batch" }
+
+func (t *MediaBatchTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
+	var input struct {
+		Steps       []BatchStep `json:"steps"`
+		Macro       string      `json:"macro"`
+		Parallel    bool        `json:"parallel"`
+		StopOnError bool        `json:"stop_on_error"`
+	}
+
+	if err := extractInput(ctx, &input); err != nil {
+		return failResult(err.Error())
+	}
+
+	steps := input.Steps
+	if input.Macro != "" {
+		var err error
+		if steps, err = t.lab.Macro(input.Macro); err != nil {
+			return labFailResult("batch failed", err)
+		}
+	}
+
+	result, err := t.lab.RunBatch(ctx.Ctx, steps, BatchOptions{Parallel: input.Parallel, StopOnError: input.StopOnError})
+	if err != nil {
+		return labFailResult("batch failed", err)
+	}
+
+	output := map[string]any{
+		"success": result.Success,
+		"steps":   result.Steps,
+		"failed":  result.Failed,
+		"skipped": result.Skipped,
+	}
+	if !result.Success {
+		return &core.ToolExecResult{
+			Status: core.ToolFailed,
+			Error:  fmt.Sprintf("batch failed: %d of %d steps failed, %d skipped", result.Failed, len(result.Steps), result.Skipped),
+			Output: output,
+		}
+	}
+	return &core.ToolExecResult{Status: core.ToolComplete, Output: output}
+}
+
+func (t *MediaBatchTool) InputSchema() []byte {
+	return []byte(`{
+		"type": "object",
+		"properties": {
+			"steps": {"type": "array", "items": ` + batchStepSchema + `, "description": "Operations in order, e.g. play on screen 1, mute screen 3, fullscreen all"},
+			"macro": {"type": "string", "description": "Run a named macro from the config instead of steps"},
+			"parallel": {"type": "boolean", "default": false, "description": "Run all steps at once"},
+			"stop_on_error": {"type": "boolean", "default": false, "description": "Skip remaining steps after a failure"}
+		},
+		"oneOf": [{"required": ["steps"]}, {"required": ["macro"]}]
+	}`)
+}
+
+func (t *MediaBatchTool) OutputSchema() []byte { return nil }
+
+func (t *MediaBatchTool) Manifest() *core.ToolManifest {
+	return &core.ToolManifest{
+		Name:        "media.batch",
+		Version:     "1.0.0",
+		Description: "Run several playback operations across screens in one call, or a named macro",
+		Category:    "media",
+		Tags:        []string{"media", "batch", "macro", "scene"},
+		InputSchema: t.InputSchema(),
+	}
+}
+
+// === media.scene ===
+
+type MediaSceneTool struct {
+	lab *MediaLab
+}
+
+func (t *MediaSceneTool) Name() string { return "media.scene" }
+
+func (t *MediaSceneTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
+	var input struct {
+		Action string `json:"action"` // save, load, list, show, delete
+		Name   string `json:"name"`
+	}
+
+	if err := extractInput(ctx, &input); err != nil {
+		return failResult(err.Error())
+	}
+
+	var scene *Scene
+	var err error
+	switch input.Action {
+	case "save":
+		scene, err = t.lab.SaveScene(input.Name)
+	case "load":
+		scene, err = t.lab.LoadScene(ctx.Ctx, input.Name)
+	case "show":
+		scene, err = t.lab.GetScene(input.Name)
+	case "delete":
+		if err = t.lab.DeleteScene(input.Name); err == nil {
+			return &core.ToolExecResult{
+				Status: core.ToolComplete,
+				Output: map[string]any{"success": true, "deleted": input.Name},
+			}
+		}
+	case "", "list":
+		scenes, err := t.lab.Scenes()
+		if err != nil {
+			return labFailResult("scene failed", err)
+		}
+		names := make([]string, len(scenes))
+		for i, sc := range scenes {
+			names[i] = sc.Name
+		}
+		return &core.ToolExecResult{
+			Status: core.ToolComplete,
+			Output: map[string]any{"success": true, "count": len(names), "scenes": names},
+		}
+	default:
+		return failResult(fmt.Sprintf("unknown action: %s", input.Action))
+	}
+
+	if err != nil {
+		result := labFailResult("scene "+input.Action+" failed", err)
+		if scene != nil {
+			// Partially loaded: report what the scene holds
+			result.Output = map[string]any{"success": false, "code": errorCode(err), "scene": scene}
+		}
+		return result
+	}
+
+	return &core.ToolExecResult{
+		Status: core.ToolComplete,
+		Output: map[string]any{
+			"success": true,
+			"scene":   scene,
+		},
+	}
+}
+
+func (t *MediaSceneTool) InputSchema() []byte {
+	return []byte(`{
+		"type": "object",
+		"properties": {
+			"action": {"type": "string", "enum": ["save", "load", "list", "show", "delete"], "default": "list"},
+			"name": {"type": "string", "description": "Scene name, e.g. standup (save, load, show, delete)"}
+		}
+	}`)
+}
+
+func (t *MediaSceneTool) OutputSchema() []byte { return nil }
+
+func (t *MediaSceneTool) Manifest() *core.ToolManifest {
+	return &core.ToolManifest{
+		Name:        "media.scene",
+		Version:     "1.0.0",
+		Description: "Save the state of every screen as a named scene, or restore one",
+		Category:    "media",
+		Tags:        []string{"media", "scene", "layout", "snapshot"},
+		InputSchema: t.InputSchema(),
+	}
+}
+
+// === media.schedule ===
+
+type MediaScheduleTool struct {
+	lab *MediaLab
+}
+
+func (t *MediaScheduleTool) Name() string { return "media.schedule" }
+
+func (t *MediaScheduleTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
+	var input struct {
+		Action string        `json:"action"` // list, add, update, remove, enable, disable, conflicts
+		ID     string        `json:"id"`
+		Entry  ScheduleEntry `json:"entry"`
+	}
+
+	if err := extractInput(ctx, &input); err != nil {
+		return failResult(err.Error())
+	}
+
+	scheduler := t.lab.Scheduler()
+	var entry ScheduleEntry
+	var err error
+	switch input.Action {
+	case "", "list":
+		entries, err := scheduler.Entries()
+		if err != nil {
+			return labFailResult("schedule failed", err)
+		}
+		next, err := scheduler.NextRuns(time.Now())
+		if err != nil {
+			return labFailResult("schedule failed", err)
+		}
+		return &core.ToolExecResult{
+			Status: core.ToolComplete,
+			Output: map[string]any{"success": true, "count": len(entries), "entries": entries, "next": next},
+		}
+	case "conflicts":
+		conflicts, err := scheduler.Conflicts()
+		if err != nil {
+			return labFailResult("schedule failed", err)
+		}
+		return &core.ToolExecResult{
+			Status: core.ToolComplete,
+			Output: map[string]any{"success": true, "count": len(conflicts), "conflicts": conflicts},
+		}
+	case "add":
+		entry, err = scheduler.Add(input.Entry)
+	case "update":
+		entry, err = scheduler.Update(input.ID, input.Entry)
+	case "enable", "disable":
+		entry, err = scheduler.SetDisabled(input.ID, input.Action == "disable")
+	case "remove":
+		if err = scheduler.Remove(input.ID); err == nil {
+			return &core.ToolExecResult{
+				Status: core.ToolComplete,
+				Output: map[string]any{"success": true, "removed": input.ID},
+			}
+		}
+	default:
+		return failResult(fmt.Sprintf("unknown action: %s", input.Action))
+	}
+
+	if err != nil {
+		return labFailResult("schedule "+input.Action+" failed", err)
+	}
+
+	return &core.ToolExecResult{
+		Status: core.ToolComplete,
+		Output: map[string]any{
+			"success": true,
+			"entry":   entry,
+		},
+	}
+}
+
+func (t *MediaScheduleTool) InputSchema() []byte {
+	return []byte(`{
+		"type": "object",
+		"properties": {
+			"action": {"type": "string", "enum": ["list", "add", "update", "remove", "enable", "disable", "conflicts"], "default": "list"},
+			"id": {"type": "string", "description": "Entry ID (update, remove, enable, disable)"},
+			"entry": {
+				"type": "object",
+				"description": "Entry for add/update: cron or at, steps or scene",
+				"properties": {
+					"name": {"type": "string"},
+					"cron": {"type": "string", "description": "minute hour day month weekday, e.g. 0 9 * * mon-fri"},
+					"at": {"type": "string", "format": "date-time", "description": "One-off start instead of cron"},
+					"until": {"type": "string", "description": "HH:MM; the entry's screens are stopped then"},
+					"steps": {"type": "array", "items": {"type": "object"}, "description": "Batch steps, as in media.batch"},
+					"scene": {"type": "string", "description": "Scene to load instead of steps"},
+					"allow_overlap": {"type": "boolean"},
+					"disabled": {"type": "boolean"}
+				}
+			}
+		}
+	}`)
+}
+
+func (t *MediaScheduleTool) OutputSchema() []byte { return nil }
+
+func (t *MediaScheduleTool) Manifest() *core.ToolManifest {
+	return &core.ToolManifest{
+		Name:        "media.schedule",
+		Version:     "1.0.0",
+		Description: "Schedule batches or scenes at cron or calendar times, with conflict detection",
+		Category:    "media",
+		Tags:        []string{"media", "schedule", "cron", "timer"},
+		InputSchema: t.InputSchema(),
+	}
+}
+
+// === media.timer ===
+
+type MediaTimerTool struct {
+	lab *MediaLab
+}
+
+func (t *MediaTimerTool) Name() string { return "media.timer" }
+
+func (t *MediaTimerTool) Execute(ctx *core.ToolContext) *core.ToolExecResult {
+	var input struct {
+		Action string    `json:"action"` // stop, pause, list, cancel
+		Screen screenArg `json:"screen"`
+		After  float64   `json:"after"` // seconds
+		AtEnd  bool      `json:"at_end"`
+		Fade   float64   `json:"fade"` // seconds
+		ID     string    `json:"id"`
+	}
+
+	if err := extractInput(ctx, &input); err != nil {
+		return failResult(err.Error())
+	}
+
+	var timer Timer
+	var err error
+	switch input.Action {
+	case "", "list":
+		timers := t.lab.Timers()
+		return &core.ToolExecResult{
+			Status: core.ToolComplete,
+			Output: map[string]any{"success": true, "count": len(timers), "timers": timers},
+		}
+	case "cancel":
+		timer, err = t.lab.CancelTimer(input.ID)
+	case "stop", "pause":
+		timer, err = t.lab.StartTimer(TimerOptions{
+			Screen: Screen(input.Screen),
+			Action: input.Action,
+			After:  time.Duration(input.After * float64(time.Second)),
+			AtEnd:  input.AtEnd,
+			Fade:   time.Duration(input.Fade * float64(time.Second)),
+		})
+	default:
+		return failResult(fmt.Sprintf("unknown action: %s", input.Action))
+	}
+
+	if err != nil {
+		return labFailResult("timer "+input.Action+" failed", err)
+	}
+
+	return &core.ToolExecResult{
+		Status: core.ToolComplete,
+		Output: map[string]any{
+			"success": true,
+			"timer":   timer,
+		},
+	}
+}
+
+func (t *MediaTimerTool) InputSchema() []byte {
+	return []byte(`{
+		"type": "object",
+		"properties": {
+			"action": {"type": "string", "enum": ["stop", "pause", "list", "cancel"], "default": "list", "description": "stop/pause set a timer"},
+			"screen": {"type": ["integer", "string"], "default": 1, "description": "Target screen: 1-4, or \"speaker\" for audio only"},
+			"after": {"type": "number", "description": "Seconds from now, e.g. 2700 for 45 minutes"},
+			"at_end": {"type": "boolean", "description": "Act when the current video or track ends instead"},
+			"fade": {"type": "number", "description": "Fade the volume out over this many seconds first"},
+			"id": {"type": "string", "description": "Timer ID (cancel)"}
+		}
+	}`)
+}
+
+func (t *MediaTimerTool) OutputSchema() []byte { return nil }
+
+func (t *MediaTimerTool) Manifest() *core.ToolManifest {
+	return &core.ToolManifest{
+		Name:        "media.timer",
+		Version:     "1.0.0",
+		Description: "Stop or pause a screen after a delay or when the current media ends, optionally fading out",
+		Category:    "media",
+		Tags:        []string{"media", "timer", "sleep", "fade"},
+		InputSchema: t.InputSchema(),
+	}
+}
+
 // === Helpers ===
 
 func extractInput(ctx *core.ToolContext, v any) error {
@@ -498,6 +1144,82 @@ func extractInput(ctx *core.ToolContext, v any) error {
 	return nil
 }
 
+// screenArg is a tool/HTTP screen parameter: a 1-based number or
+// "speaker", as JSON number or string. Invalid values fall back to screen 1.
+type screenArg Screen
+
+func (a *screenArg) UnmarshalJSON(data []byte) error {
+	screen, err := ParseScreen(strings.Trim(string(data), `"`))
+	if err != nil {
+		screen = Screen1
+	}
+	*a = screenArg(screen)
+	return nil
+}
+
+// screenID is the inverse of screenArg for responses: 1-4 or "speaker"
+func screenID(screen Screen) any {
+	if screen == ScreenSpeaker {
+		return "speaker"
+	}
+	return int(screen) + 1
+}
+
+// playbackInfoMap flattens PlaybackInfo for tool/HTTP responses (1-based screen)
+func playbackInfoMap(info *PlaybackInfo) map[string]any {
+	return map[string]any{
+		"screen":           screenID(info.Screen),
+		"state":            info.State,
+		"playing":          info.Playing,
+		"paused":           info.Paused,
+		"idle":             info.Idle,
+		"position":         info.Position,
+		"duration":         info.Duration,
+		"volume":           info.Volume,
+		"mute":             info.Mute,
+		"filename":         info.Filename,
+		"media_title":      info.MediaTitle,
+		"fullscreen":       info.Fullscreen,
+		"percent":          info.PercentPos,
+		"speed":            info.Speed,
+		"path":             info.Path,
+		"stream_url":       info.StreamURL,
+		"width":            info.Width,
+		"height":           info.Height,
+		"fps":              info.FPS,
+		"video_codec":      info.VideoCodec,
+		"audio_codec":      info.AudioCodec,
+		"hwdec":            info.HWDec,
+		"cache_duration":   info.CacheDuration,
+		"paused_for_cache": info.PausedForCache,
+		"dropped_frames":   info.DroppedFrames,
+		"playlist_pos":     info.PlaylistPos,
+		"playlist_count":   info.PlaylistCount,
+		"chapter":          info.Chapter,
+		"chapter_title":    info.ChapterTitle,
+		"loop_file":        info.LoopFile,
+		"loop_playlist":    info.LoopPlaylist,
+		"ab_loop_a":        info.ABLoopA,
+		"ab_loop_b":        info.ABLoopB,
+		"uploader":         info.Uploader,
+		"upload_date":      info.UploadDate,
+		"thumbnail":        info.Thumbnail,
+		"live":             info.Live,
+		"station":          info.Station,
+		"track":            info.Track,
+		"reconnects":       info.Reconnects,
+	}
+}
+
+// loopCount maps the loop count of a JSON request to a MediaLab count: an
+// omitted count loops forever, like -1, and 0 turns looping off
+func loopCount(count *int) int {
+	if count == nil || *count < 0 {
+		return LoopInfinite
+	}
+	return *count
+}
+
 func failResult(msg string) *core.ToolExecResult {
 	return &core.ToolExecResult{
 		Status: core.ToolFailed,
@@ -505,6 +1227,16 @@ func failResult(msg string) *core.ToolExecResult {
 	}
 }
 
+// labFailResult reports a MediaLab error with its machine-readable code
+// (no_player, ipc_timeout, property_unavailable) so agents can react to it
+func labFailResult(prefix string, err error) *core.ToolExecResult {
+	return &core.ToolExecResult{
+		Status: core.ToolFailed,
+		Error:  fmt.Sprintf("%s: %v", prefix, err),
+		Output: map[string]any{"success": false, "code": errorCode(err)},
+	}
+}
+
 // CreateSkillManifests returns skill manifests for external registration
 func CreateSkillManifests() []*core.SkillManifest {
 	return []*core.SkillManifest{
@@ -522,15 +1254,24 @@ func CreateSkillManifests() []*core.SkillManifest {
 				"type": "object",
 				"properties": {
 					"url": {"type": "string", "description": "URL or file path to play"},
-					"query": {"type": "string", "description": "YouTube search query"},
-					"screen": {"type": "integer", "minimum": 1, "maximum": 4, "default": 1}
+					"query": {"type": "string", "description": "Search query (local library first, then YouTube)"},
+					"result_index": {"type": "integer", "minimum": 1, "description": "Nth result of the last search"},
+					"video_id": {"type": "string", "description": "YouTube video ID"},
+					"station": {"type": "string", "description": "Configured radio station name"},
+					"screen": {"type": ["integer", "string"], "default": 1},
+					"playlist": {"type": "boolean", "description": "Expand url as a playlist"},
+					"start": {"type": "integer", "minimum": 1},
+					"limit": {"type": "integer", "minimum": 0, "default": 200},
+					"all_entries": {"type": "boolean"},
+					"shuffle": {"type": "boolean"},
+					"quality": {"type": "string", "description": "best, audio, 1080p, vp9, 1080p:av1 or a ytdl-format"}
 				}
 			}`),
 		},
 		{
 			Name:        "media.control",
 			Version:     "1.0.0",
-			Description: "Control media playback (play/pause/stop/next/prev/fullscreen)",
+			Description: "Control media playback (play/pause/stop/next/prev/fullscreen/loop/speed/frame-step)",
 			Author:      "Agent-GO",
 			License:     "MIT",
 			Category:    "media",
@@ -541,8 +1282,13 @@ func CreateSkillManifests() []*core.SkillManifest {
 				"type": "object",
 				"required": ["action"],
 				"properties": {
-					"action": {"type": "string", "enum": ["playpause", "pause", "play", "stop", "next", "prev", "fullscreen"]},
-					"screen": {"type": "integer", "minimum": 1, "maximum": 4, "default": 1}
+					"action": {"type": "string", "enum": ["playpause", "pause", "play", "stop", "next", "prev", "fullscreen", "loop-file", "loop-playlist", "ab-loop", "loop-off", "speed", "frame-step", "frame-back-step"]},
+					"count": {"type": "integer", "minimum": -1, "default": -1},
+					"a": {"type": "number", "minimum": 0},
+					"b": {"type": "number", "minimum": 0},
+					"speed": {"type": "number", "minimum": 0.01, "maximum": 100},
+					"pitch_correction": {"type": "boolean", "default": true},
+					"screen": {"type": ["integer", "string"], "default": 1}
 				}
 			}`),
 		},
@@ -561,7 +1307,7 @@ func CreateSkillManifests() []*core.SkillManifest {
 				"required": ["volume"],
 				"properties": {
 					"volume": {"type": "integer", "minimum": 0, "maximum": 100},
-					"screen": {"type": "integer", "minimum": 1, "maximum": 4, "default": 1}
+					"screen": {"type": ["integer", "string"], "default": 1}
 				}
 			}`),
 		},
@@ -581,7 +1327,7 @@ func CreateSkillManifests() []*core.SkillManifest {
 				"properties": {
 					"position": {"type": "number"},
 					"relative": {"type": "boolean", "default": false},
-					"screen": {"type": "integer", "minimum": 1, "maximum": 4, "default": 1}
+					"screen": {"type": ["integer", "string"], "default": 1}
 				}
 			}`),
 		},
@@ -598,18 +1344,18 @@ func CreateSkillManifests() []*core.SkillManifest {
 			InputSchema: json.RawMessage(`{
 				"type": "object",
 				"properties": {
-					"screen": {"type": "integer", "minimum": 1, "maximum": 4, "default": 1}
+					"screen": {"type": ["integer", "string"], "default": 1}
 				}
 			}`),
 		},
 		{
 			Name:        "media.search",
 			Version:     "1.0.0",
-			Description: "Search YouTube for videos",
+			Description: "Search YouTube, SoundCloud, Internet Archive, local files or any yt-dlp extractor",
 			Author:      "Agent-GO",
 			License:     "MIT",
 			Category:    "media",
-			Tags:        []string{"media", "youtube", "search"},
+			Tags:        []string{"media", "youtube", "soundcloud", "search"},
 			Runtime:     "native",
 			TrustLevel:  3,
 			InputSchema: json.RawMessage(`{
@@ -617,7 +1363,13 @@ func CreateSkillManifests() []*core.SkillManifest {
 				"required": ["query"],
 				"properties": {
 					"query": {"type": "string"},
-					"max_results": {"type": "integer", "minimum": 1, "maximum": 20, "default": 5}
+					"provider": {"type": "string", "default": "youtube"},
+					"max_results": {"type": "integer", "minimum": 1, "maximum": 20, "default": 5},
+					"min_duration": {"type": "number", "minimum": 0},
+					"max_duration": {"type": "number", "minimum": 0},
+					"exclude_live": {"type": "boolean", "default": false},
+					"sort": {"type": "string", "enum": ["relevance", "date", "views", "duration"], "default": "relevance"},
+					"full": {"type": "boolean", "default": false}
 				}
 			}`),
 		},
@@ -633,5 +1385,127 @@ func CreateSkillManifests() []*core.SkillManifest {
 			TrustLevel:  3,
 			InputSchema: json.RawMessage(`{"type": "object", "properties": {}}`),
 		},
+		{
+			Name:        "media.clip",
+			Version:     "1.0.0",
+			Description: "Export a time range of the current media (or any URL) to a local file",
+			Author:      "Agent-GO",
+			License:     "MIT",
+			Category:    "media",
+			Tags:        []string{"media", "clip", "export", "record"},
+			Runtime:     "native",
+			TrustLevel:  3,
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"url": {"type": "string"},
+					"start": {"type": "number", "minimum": 0},
+					"end": {"type": "number", "minimum": 0},
+					"last": {"type": "number", "minimum": 0},
+					"output": {"type": "string"},
+					"screen": {"type": ["integer", "string"], "default": 1}
+				}
+			}`),
+		},
+		{
+			Name:        "media.download",
+			Version:     "1.0.0",
+			Description: "Download media for offline playback (start, status, list, cancel, remove)",
+			Author:      "Agent-GO",
+			License:     "MIT",
+			Category:    "media",
+			Tags:        []string{"media", "download", "offline", "cache"},
+			Runtime:     "native",
+			TrustLevel:  3,
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"action": {"type": "string", "enum": ["start", "status", "list", "cancel", "remove"]},
+					"url": {"type": "string"},
+					"id": {"type": "string"},
+					"quality": {"type": "string"},
+					"wait": {"type": "boolean"}
+				}
+			}`),
+		},
+		{
+			Name:        "media.batch",
+			Version:     "1.0.0",
+			Description: "Run an ordered list of playback operations (parallel, stop-on-error) or a named macro",
+			Author:      "Agent-GO",
+			License:     "MIT",
+			Category:    "media",
+			Tags:        []string{"media", "batch", "macro", "scene"},
+			Runtime:     "native",
+			TrustLevel:  3,
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"steps": {"type": "array", "items": {"type": "object"}},
+					"macro": {"type": "string"},
+					"parallel": {"type": "boolean"},
+					"stop_on_error": {"type": "boolean"}
+				}
+			}`),
+		},
+		{
+			Name:        "media.scene",
+			Version:     "1.0.0",
+			Description: "Save or restore the full multi-screen state as a named scene (save, load, list, show, delete)",
+			Author:      "Agent-GO",
+			License:     "MIT",
+			Category:    "media",
+			Tags:        []string{"media", "scene", "layout", "snapshot"},
+			Runtime:     "native",
+			TrustLevel:  3,
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"action": {"type": "string", "enum": ["save", "load", "list", "show", "delete"]},
+					"name": {"type": "string"}
+				}
+			}`),
+		},
+		{
+			Name:        "media.schedule",
+			Version:     "1.0.0",
+			Description: "Run batches or scenes on cron or calendar schedules, with overlap detection per screen",
+			Author:      "Agent-GO",
+			License:     "MIT",
+			Category:    "media",
+			Tags:        []string{"media", "schedule", "cron", "timer"},
+			Runtime:     "native",
+			TrustLevel:  3,
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"action": {"type": "string", "enum": ["list", "add", "update", "remove", "enable", "disable", "conflicts"]},
+					"id": {"type": "string"},
+					"entry": {"type": "object"}
+				}
+			}`),
+		},
+		{
+			Name:        "media.timer",
+			Version:     "1.0.0",
+			Description: "Sleep timers: stop or pause a screen after a delay or at the end of the current media, with an optional fade-out",
+			Author:      "Agent-GO",
+			License:     "MIT",
+			Category:    "media",
+			Tags:        []string{"media", "timer", "sleep", "fade"},
+			Runtime:     "native",
+			TrustLevel:  3,
+			InputSchema: json.RawMessage(`{
+				"type": "object",
+				"properties": {
+					"action": {"type": "string", "enum": ["stop", "pause", "list", "cancel"]},
+					"screen": {"type": ["integer", "string"]},
+					"after": {"type": "number"},
+					"at_end": {"type": "boolean"},
+					"fade": {"type": "number"},
+					"id": {"type": "string"}
+				}
+			}`),
+		},
 	}
 }
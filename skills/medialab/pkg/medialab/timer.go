@@ -0,0 +1,306 @@
+package medialab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TimerState is the lifecycle state of a timer
+type TimerState string
+
+const (
+	TimerPending   TimerState = "pending"
+	TimerFading    TimerState = "fading"
+	TimerDone      TimerState = "done"
+	TimerFailed    TimerState = "failed"
+	TimerCancelled TimerState = "cancelled"
+)
+
+// TimerOptions describes a delayed action on one screen
+type TimerOptions struct {
+	Screen Screen
+	Action string        // "stop" or "pause"
+	After  time.Duration // act this long from now, or
+	AtEnd  bool          // act when the current playlist entry ends
+	Fade   time.Duration // fade the volume out over this long before acting
+}
+
+// Timer is a snapshot of a delayed action
+type Timer struct {
+	ID          string     `json:"id"`
+	Screen      Screen     `json:"screen"`
+	Action      string     `json:"action"`
+	AtEnd       bool       `json:"at_end,omitempty"`
+	Due         time.Time  `json:"due,omitempty"` // for AtEnd, estimated from the remaining playtime
+	FadeSeconds float64    `json:"fade_seconds,omitempty"`
+	State       TimerState `json:"state"`
+	Error       string     `json:"error,omitempty"`
+	Created     time.Time  `json:"created"`
+	Finished    time.Time  `json:"finished,omitempty"`
+}
+
+type timerJob struct {
+	Timer
+	fade   time.Duration
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// timerPoll is how often AtEnd timers and fades check the player
+const timerPoll = 250 * time.Millisecond
+
+// StartTimer schedules a stop or pause on a screen, optionally fading the
+// volume out first. Timers live in this process; a paused player gets its
+// volume back after the fade.
+func (m *MediaLab) StartTimer(opts TimerOptions) (Timer, error) {
+	switch {
+	case !opts.Screen.Valid():
+		return Timer{}, fmt.Errorf("%w: invalid screen %d", ErrInvalidArgument, opts.Screen)
+	case opts.Action != "stop" && opts.Action != "pause":
+		return Timer{}, fmt.Errorf("%w: timer action must be stop or pause: %q", ErrInvalidArgument, opts.Action)
+	case opts.AtEnd == (opts.After > 0):
+		return Timer{}, fmt.Errorf("%w: set a delay or at_end", ErrInvalidArgument)
+	case opts.Fade < 0 || (!opts.AtEnd && opts.Fade > opts.After):
+		return Timer{}, fmt.Errorf("%w: fade must be between 0 and the delay", ErrInvalidArgument)
+	}
+	if opts.AtEnd {
+		// Nothing to wait for without a player
+		if _, err := m.GetProperty(opts.Screen, "playtime-remaining"); err != nil {
+			return Timer{}, err
+		}
+	}
+
+	m.timerMu.Lock()
+	defer m.timerMu.Unlock()
+	m.nextTimer++
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &timerJob{
+		Timer: Timer{
+			ID:          strconv.Itoa(m.nextTimer),
+			Screen:      opts.Screen,
+			Action:      opts.Action,
+			AtEnd:       opts.AtEnd,
+			FadeSeconds: opts.Fade.Seconds(),
+			State:       TimerPending,
+			Created:     time.Now(),
+		},
+		fade:   opts.Fade,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	if !opts.AtEnd {
+		job.Due = job.Created.Add(opts.After)
+	}
+	m.timers[job.ID] = job
+
+	go func() {
+		defer close(job.done)
+		defer cancel()
+		err := m.runTimer(ctx, job)
+		m.timerMu.Lock()
+		job.Finished = time.Now()
+		switch {
+		case errors.Is(err, context.Canceled):
+			job.State = TimerCancelled
+		case err != nil:
+			job.State, job.Error = TimerFailed, err.Error()
+		default:
+			job.State = TimerDone
+		}
+		timer := job.Timer
+		m.timerMu.Unlock()
+
+		if timer.State == TimerCancelled {
+			return
+		}
+		audit := AuditEntry{
+			Actor:  "timer:" + timer.ID,
+			Source: "timer",
+			Screen: auditScreen(timer.Screen),
+			Action: timer.Action,
+			Args:   auditArgs(timer),
+		}
+		if err != nil {
+			audit.Result, audit.Error = errorCode(err), err.Error()
+		}
+		m.Audit(audit)
+	}()
+	return job.Timer, nil
+}
+
+// runTimer waits for job to come due, fading the volume over its last
+// stretch, then acts
+func (m *MediaLab) runTimer(ctx context.Context, job *timerJob) error {
+	if job.AtEnd {
+		// Hold the player at the end of the entry instead of moving on to
+		// the next, so the action cannot land on it
+		if keepOpen, err := m.GetProperty(job.Screen, "keep-open"); err == nil {
+			m.IPCCommand(job.Screen, map[string]any{"command": []any{"set_property", "keep-open", "always"}})
+			defer m.IPCCommand(job.Screen, map[string]any{"command": []any{"set_property", "keep-open", keepOpen}})
+		}
+	}
+
+	startPos := -1.0
+	volume := -1.0 // before the fade; < 0 until it starts
+	for {
+		remaining, ended, err := m.timerRemaining(job, &startPos)
+		if err != nil {
+			return err
+		}
+		if ended || (!job.AtEnd && remaining <= timerPoll/2) {
+			break
+		}
+
+		if remaining <= job.fade {
+			if volume < 0 {
+				if volume, err = m.floatProperty(job.Screen, "volume"); err != nil {
+					return err
+				}
+				m.setTimerState(job, TimerFading)
+			}
+			if err := m.SetVolume(job.Screen, int(volume*float64(remaining)/float64(job.fade))); err != nil {
+				return err
+			}
+		}
+
+		// Sleep through long delays, but keep watching players and fades
+		wait := remaining - job.fade - time.Second
+		if job.AtEnd && wait > time.Second {
+			wait = time.Second
+		}
+		if wait < timerPoll {
+			wait = timerPoll
+		}
+		select {
+		case <-ctx.Done():
+			if volume >= 0 {
+				m.SetVolume(job.Screen, int(volume))
+			}
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	if job.Action == "stop" {
+		m.stopAny(job.Screen)
+		return nil
+	}
+	if err := m.Pause(job.Screen); err != nil {
+		return err
+	}
+	if volume >= 0 {
+		return m.SetVolume(job.Screen, int(volume))
+	}
+	return nil
+}
+
+// timerRemaining returns how long until job is due. AtEnd timers have
+// ended once their player reached the end of the entry (held there by
+// keep-open), moved past the entry it was playing at startPos (recorded on
+// the first call), went idle or quit.
+func (m *MediaLab) timerRemaining(job *timerJob, startPos *float64) (time.Duration, bool, error) {
+	if !job.AtEnd {
+		return time.Until(job.Due), false, nil
+	}
+	vals, err := m.GetProperties(job.Screen, []string{"playtime-remaining", "playlist-pos", "idle-active", "eof-reached"})
+	if errors.Is(err, ErrNoPlayer) {
+		return 0, true, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	pos, _ := vals["playlist-pos"].(float64)
+	if *startPos < 0 {
+		*startPos = pos
+	}
+	seconds, ok := vals["playtime-remaining"].(float64)
+	idle, _ := vals["idle-active"].(bool)
+	eof, _ := vals["eof-reached"].(bool)
+	if idle || eof || pos != *startPos || !ok {
+		return 0, true, nil
+	}
+	remaining := time.Duration(seconds * float64(time.Second))
+	m.timerMu.Lock()
+	job.Due = time.Now().Add(remaining)
+	m.timerMu.Unlock()
+	return remaining, false, nil
+}
+
+func (m *MediaLab) floatProperty(screen Screen, property string) (float64, error) {
+	val, err := m.GetProperty(screen, property)
+	if err != nil {
+		return 0, err
+	}
+	f, ok := val.(float64)
+	if !ok {
+		return 0, fmt.Errorf("%w: %s is not a number", ErrPropertyUnavailable, property)
+	}
+	return f, nil
+}
+
+func (m *MediaLab) setTimerState(job *timerJob, state TimerState) {
+	m.timerMu.Lock()
+	defer m.timerMu.Unlock()
+	job.State = state
+}
+
+// Timers returns snapshots of all timers, newest first
+func (m *MediaLab) Timers() []Timer {
+	m.timerMu.Lock()
+	defer m.timerMu.Unlock()
+	timers := make([]Timer, 0, len(m.timers))
+	for _, job := range m.timers {
+		timers = append(timers, job.Timer)
+	}
+	sort.Slice(timers, func(i, j int) bool {
+		a, _ := strconv.Atoi(timers[i].ID)
+		b, _ := strconv.Atoi(timers[j].ID)
+		return a > b
+	})
+	return timers
+}
+
+// GetTimer returns a snapshot of a timer
+func (m *MediaLab) GetTimer(id string) (Timer, error) {
+	m.timerMu.Lock()
+	defer m.timerMu.Unlock()
+	job, ok := m.timers[id]
+	if !ok {
+		return Timer{}, fmt.Errorf("%w: timer %s", ErrNotFound, id)
+	}
+	return job.Timer, nil
+}
+
+// CancelTimer cancels a pending timer, restoring the volume if it was
+// fading. Cancelling a finished timer does nothing.
+func (m *MediaLab) CancelTimer(id string) (Timer, error) {
+	m.timerMu.Lock()
+	job, ok := m.timers[id]
+	m.timerMu.Unlock()
+	if !ok {
+		return Timer{}, fmt.Errorf("%w: timer %s", ErrNotFound, id)
+	}
+	job.cancel()
+	<-job.done
+	return m.GetTimer(id)
+}
+
+// WaitTimer blocks until a timer finishes or ctx is done
+func (m *MediaLab) WaitTimer(ctx context.Context, id string) (Timer, error) {
+	m.timerMu.Lock()
+	job, ok := m.timers[id]
+	m.timerMu.Unlock()
+	if !ok {
+		return Timer{}, fmt.Errorf("%w: timer %s", ErrNotFound, id)
+	}
+	select {
+	case <-job.done:
+	case <-ctx.Done():
+		return Timer{}, ctx.Err()
+	}
+	return m.GetTimer(id)
+}
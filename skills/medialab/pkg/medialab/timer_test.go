@@ -0,0 +1,222 @@
+package medialab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartTimerValidation(t *testing.T) {
+	lab := New(nil)
+	for _, opts := range []TimerOptions{
+		{Screen: Screen(9), Action: "stop", After: time.Minute},
+		{Screen: Screen1, Action: "mute", After: time.Minute},
+		{Screen: Screen1, Action: "stop"},
+		{Screen: Screen1, Action: "stop", After: time.Minute, AtEnd: true},
+		{Screen: Screen1, Action: "pause", After: time.Minute, Fade: 2 * time.Minute},
+		{Screen: Screen1, Action: "pause", After: time.Minute, Fade: -time.Second},
+	} {
+		if _, err := lab.StartTimer(opts); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("StartTimer(%+v) error = %v, want ErrInvalidArgument", opts, err)
+		}
+	}
+}
+
+func TestTimerCancel(t *testing.T) {
+	lab := New(nil)
+	first, err := lab.StartTimer(TimerOptions{Screen: Screen2, Action: "stop", After: 45 * time.Minute, Fade: 30 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.State != TimerPending || first.FadeSeconds != 30 || time.Until(first.Due) < 44*time.Minute {
+		t.Errorf("StartTimer() = %+v", first)
+	}
+	second, err := lab.StartTimer(TimerOptions{Screen: ScreenSpeaker, Action: "pause", After: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if timers := lab.Timers(); len(timers) != 2 || timers[0].ID != second.ID {
+		t.Errorf("Timers() = %+v, want newest first", timers)
+	}
+
+	cancelled, err := lab.CancelTimer(first.ID)
+	if err != nil || cancelled.State != TimerCancelled || cancelled.Finished.IsZero() {
+		t.Errorf("CancelTimer() = %+v, %v", cancelled, err)
+	}
+	if again, err := lab.CancelTimer(first.ID); err != nil || again.State != TimerCancelled {
+		t.Errorf("CancelTimer(cancelled) = %+v, %v", again, err)
+	}
+	if got, _ := lab.GetTimer(second.ID); got.State != TimerPending {
+		t.Errorf("other timer = %+v, want pending", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := lab.WaitTimer(ctx, second.ID); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitTimer(pending) error = %v", err)
+	}
+	lab.CancelTimer(second.ID)
+
+	if _, err := lab.GetTimer("99"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetTimer(unknown) error = %v, want ErrNotFound", err)
+	}
+	if _, err := lab.CancelTimer("99"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("CancelTimer(unknown) error = %v, want ErrNotFound", err)
+	}
+}
+
+// waitTimer waits for a timer to finish
+func waitTimer(t *testing.T, lab *MediaLab, id string) Timer {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	timer, err := lab.WaitTimer(ctx, id)
+	if err != nil {
+		t.Fatalf("WaitTimer(%s) error = %v", id, err)
+	}
+	return timer
+}
+
+func TestTimerFadeAndPause(t *testing.T) {
+	lab := newTestLab(t)
+	player := newFakePlayer(t, lab, Screen1, map[string]any{"volume": 80.0, "pause": false})
+
+	timer, err := lab.StartTimer(TimerOptions{Screen: Screen1, Action: "pause", After: 1200 * time.Millisecond, Fade: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timer = waitTimer(t, lab, timer.ID); timer.State != TimerDone {
+		t.Fatalf("timer = %+v, want done", timer)
+	}
+	if player.prop("pause") != true {
+		t.Error("timer did not pause the player")
+	}
+
+	// The volume ramps down, then comes back for when playback resumes
+	volumes := player.setValues("volume")
+	if len(volumes) < 3 || volumes[len(volumes)-1] != 80.0 {
+		t.Fatalf("volumes set = %v, want a fade ending in a restore to 80", volumes)
+	}
+	fade := volumes[:len(volumes)-1]
+	for i, v := range fade {
+		if v.(float64) >= 80 || (i > 0 && v.(float64) > fade[i-1].(float64)) {
+			t.Errorf("fade volumes = %v, want falling below 80", fade)
+			break
+		}
+	}
+}
+
+func TestTimerStop(t *testing.T) {
+	lab := newTestLab(t)
+	player := newFakePlayer(t, lab, ScreenSpeaker, map[string]any{"volume": 50.0})
+
+	timer, err := lab.StartTimer(TimerOptions{Screen: ScreenSpeaker, Action: "stop", After: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timer = waitTimer(t, lab, timer.ID); timer.State != TimerDone {
+		t.Fatalf("timer = %+v, want done", timer)
+	}
+	if got := player.ran(); len(got) != 1 || got[0] != "quit" {
+		t.Errorf("commands = %v, want quit", got)
+	}
+	if volumes := player.setValues("volume"); len(volumes) != 0 {
+		t.Errorf("volume set to %v without a fade", volumes)
+	}
+}
+
+func TestTimerAtEnd(t *testing.T) {
+	lab := newTestLab(t)
+	player := newFakePlayer(t, lab, Screen2, map[string]any{
+		"playtime-remaining": 0.1, "playlist-pos": 0.0, "idle-active": false, "eof-reached": false,
+		"keep-open": "no", "pause": false,
+	})
+
+	if _, err := lab.StartTimer(TimerOptions{Screen: Screen3, Action: "pause", AtEnd: true}); !errors.Is(err, ErrNoPlayer) {
+		t.Errorf("AtEnd timer without a player error = %v, want ErrNoPlayer", err)
+	}
+
+	timer, err := lab.StartTimer(TimerOptions{Screen: Screen2, Action: "pause", AtEnd: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Little time remains, but the entry has not ended yet
+	time.Sleep(3 * timerPoll)
+	if got, _ := lab.GetTimer(timer.ID); got.State != TimerPending {
+		t.Fatalf("timer before the end = %+v, want pending", got)
+	}
+	if got := player.prop("keep-open"); got != "always" {
+		t.Errorf("keep-open while waiting = %v, want always", got)
+	}
+
+	// mpv holds the entry's last frame instead of starting the next one
+	player.set("eof-reached", true)
+	player.set("playtime-remaining", 0.0)
+	if timer = waitTimer(t, lab, timer.ID); timer.State != TimerDone {
+		t.Fatalf("timer = %+v, want done", timer)
+	}
+	if player.prop("pause") != true || player.prop("playlist-pos") != 0.0 {
+		t.Errorf("pause = %v at entry %v, want the ended entry paused", player.prop("pause"), player.prop("playlist-pos"))
+	}
+	if got := player.prop("keep-open"); got != "no" {
+		t.Errorf("keep-open after the timer = %v, want it restored to no", got)
+	}
+
+	// Skipping to another entry also ends the wait
+	player.set("eof-reached", false)
+	player.set("playtime-remaining", 120.0)
+	timer, _ = lab.StartTimer(TimerOptions{Screen: Screen2, Action: "stop", AtEnd: true})
+	time.Sleep(timerPoll)
+	player.set("playlist-pos", 1.0)
+	if timer = waitTimer(t, lab, timer.ID); timer.State != TimerDone || !slices.Contains(player.ran(), "quit") {
+		t.Errorf("timer after a skip = %+v, commands %v; want done and quit", timer, player.ran())
+	}
+}
+
+func TestV1Timers(t *testing.T) {
+	s := newAuthServer(AuthConfig{Clients: []APIClient{
+		{Name: "wall", Token: "wall-token", Scopes: []Scope{ScopeRead, ScopeControl}, Screens: []Screen{Screen2}},
+	}})
+	request := func(method, path, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.Header.Set("Authorization", "Bearer wall-token")
+		return serveRequest(s, r)
+	}
+
+	w := request("POST", "/v1/timers", `{"screen": 2, "action": "stop", "after": 2700, "fade": 30}`)
+	if w.Code != http.StatusCreated || w.Header().Get("Location") != "/v1/timers/1" {
+		t.Fatalf("POST /v1/timers = %d %s", w.Code, w.Body)
+	}
+	if w := request("POST", "/v1/timers", `{"screen": 3, "action": "stop", "after": 60}`); w.Code != http.StatusForbidden {
+		t.Errorf("timer on another screen = %d, want 403", w.Code)
+	}
+	if w := request("POST", "/v1/timers", `{"screen": 2, "action": "stop", "after": 10, "fade": 30}`); w.Code != http.StatusBadRequest {
+		t.Errorf("fade longer than delay = %d, want 400", w.Code)
+	}
+
+	// Timers on screens the client may not use are hidden
+	s.lab.StartTimer(TimerOptions{Screen: Screen4, Action: "pause", After: time.Hour})
+	w = request("GET", "/v1/timers", "")
+	if body := decodeEnvelope(t, w); w.Code != http.StatusOK || body["count"] != 1.0 {
+		t.Errorf("GET /v1/timers = %d %v", w.Code, body)
+	}
+
+	w = request("DELETE", "/v1/timers/1", "")
+	timer, _ := decodeEnvelope(t, w)["timer"].(map[string]any)
+	if w.Code != http.StatusOK || timer["state"] != "cancelled" {
+		t.Errorf("DELETE /v1/timers/1 = %d %s", w.Code, w.Body)
+	}
+	if w := request("GET", "/v1/timers/2", ""); w.Code != http.StatusForbidden {
+		t.Errorf("get another screen's timer = %d, want 403", w.Code)
+	}
+	if w := request("DELETE", "/v1/timers/2", ""); w.Code != http.StatusForbidden {
+		t.Errorf("cancel another screen's timer = %d, want 403", w.Code)
+	}
+	s.lab.CancelTimer("2")
+}